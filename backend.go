@@ -0,0 +1,279 @@
+package drivefs
+
+import (
+	"context"
+	"io"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Backend is the set of Google Drive API v3 operations that DriveFS is built on.
+// New uses the Backend returned by NewServiceBackend, which calls the Google Drive API through a drive.Service.
+// Other implementations, such as the in-memory one provided by the drivefsmem package, can be passed to NewWithBackend.
+//
+// Implementations must report failures as *googleapi.Error with the HTTP status code
+// that the Google Drive API would return (e.g., 404 for a file that does not exist),
+// because DriveFS interprets some of them.
+type Backend interface {
+	// GetFile returns the metadata of the file with the given fileID.
+	GetFile(ctx context.Context, fileID string) (*drive.File, error)
+
+	// ListFiles returns a single page of the files matching the request.
+	// The NextPageToken of the returned list is empty on the last page.
+	ListFiles(ctx context.Context, req ListFilesRequest) (*drive.FileList, error)
+
+	// GenerateIDs returns count file IDs that can be set to File.Id of CreateFile and CopyFile requests.
+	// Creating a file with an ID that is already in use fails with 409 Conflict.
+	GenerateIDs(ctx context.Context, count int) ([]string, error)
+
+	// CreateFile creates a file with the given metadata and optional content.
+	CreateFile(ctx context.Context, req CreateFileRequest) (*drive.File, error)
+
+	// UpdateFile updates the metadata, parents and optionally the content of a file.
+	UpdateFile(ctx context.Context, req UpdateFileRequest) (*drive.File, error)
+
+	// CopyFile creates a copy of a file with the given metadata applied.
+	CopyFile(ctx context.Context, req CopyFileRequest) (*drive.File, error)
+
+	// DeleteFile permanently deletes a file, including all descendants if it is a directory.
+	DeleteFile(ctx context.Context, fileID string) error
+
+	// EmptyTrash permanently deletes all trashed files of the user in My Drive if driveID is empty,
+	// or all trashed files in the shared drive with the given driveID otherwise.
+	EmptyTrash(ctx context.Context, driveID string) error
+
+	// DownloadFile returns the content of a file, or the requested byte range of it.
+	DownloadFile(ctx context.Context, req DownloadFileRequest) (io.ReadCloser, error)
+
+	// ExportFile returns the content of a Google Apps file exported to the given MIME type.
+	ExportFile(ctx context.Context, fileID, mimeType string) (io.ReadCloser, error)
+
+	// ListPermissions returns a single page of the permissions of a file.
+	// The NextPageToken of the returned list is empty on the last page.
+	ListPermissions(ctx context.Context, fileID string, pageToken string) (*drive.PermissionList, error)
+
+	// CreatePermission creates a permission on a file.
+	CreatePermission(ctx context.Context, req CreatePermissionRequest) (*drive.Permission, error)
+
+	// UpdatePermission updates the role and options of an existing permission identified by its ID.
+	UpdatePermission(ctx context.Context, req UpdatePermissionRequest) (*drive.Permission, error)
+
+	// DeletePermission deletes a permission from a file.
+	DeletePermission(ctx context.Context, fileID, permissionID string) error
+
+	// ListRevisions returns a single page of the revisions of a file, from the oldest to the latest.
+	// The NextPageToken of the returned list is empty on the last page.
+	ListRevisions(ctx context.Context, fileID string, pageToken string) (*drive.RevisionList, error)
+
+	// DownloadRevision returns the content of a revision of a file.
+	DownloadRevision(ctx context.Context, fileID, revisionID string) (io.ReadCloser, error)
+
+	// UpdateRevision updates the options of an existing revision identified by its ID, such as KeepForever.
+	UpdateRevision(ctx context.Context, req UpdateRevisionRequest) (*drive.Revision, error)
+
+	// DeleteRevision permanently deletes a revision of a file.
+	DeleteRevision(ctx context.Context, fileID, revisionID string) error
+
+	// GetStartPageToken returns the page token from which ListChanges lists the changes made after this call
+	// to My Drive if driveID is empty, or to the shared drive with the given driveID otherwise.
+	GetStartPageToken(ctx context.Context, driveID string) (string, error)
+
+	// ListChanges returns a single page of the changes made since the page token of the request.
+	// The NextPageToken of the returned list is empty on the last page,
+	// which has NewStartPageToken for listing the changes made afterwards instead.
+	ListChanges(ctx context.Context, req ListChangesRequest) (*drive.ChangeList, error)
+
+	// ListDrives returns a single page of the shared drives matching the request.
+	// The NextPageToken of the returned list is empty on the last page.
+	ListDrives(ctx context.Context, req ListDrivesRequest) (*drive.DriveList, error)
+
+	// GetDrive returns the metadata of the shared drive with the given driveID.
+	GetDrive(ctx context.Context, driveID string) (*drive.Drive, error)
+
+	// CreateDrive creates a shared drive.
+	// Creating a shared drive with a request ID that has already been used fails with 409 Conflict.
+	CreateDrive(ctx context.Context, req CreateDriveRequest) (*drive.Drive, error)
+
+	// UpdateDrive updates the name and restrictions of a shared drive.
+	UpdateDrive(ctx context.Context, req UpdateDriveRequest) (*drive.Drive, error)
+
+	// HideDrive hides a shared drive from the default view.
+	HideDrive(ctx context.Context, driveID string) (*drive.Drive, error)
+
+	// UnhideDrive restores a hidden shared drive to the default view.
+	UnhideDrive(ctx context.Context, driveID string) (*drive.Drive, error)
+
+	// DeleteDrive permanently deletes a shared drive, which must not contain any items.
+	DeleteDrive(ctx context.Context, driveID string) error
+}
+
+// ListFilesRequest is the request of Backend.ListFiles.
+type ListFilesRequest struct {
+	// Query is a search query in the Google Drive query syntax. Empty means all files.
+	Query string
+
+	// PageSize is the maximum number of files in a page. Zero means the default of the backend.
+	PageSize int64
+
+	// PageToken is the NextPageToken of the previous page, or empty for the first page.
+	PageToken string
+
+	// OrderBy is a comma-separated list of sort keys such as "folder,name" or "modifiedTime desc".
+	OrderBy string
+
+	// DriveID restricts the search to the shared drive with the given ID. Empty means all drives.
+	DriveID string
+}
+
+// CreateFileRequest is the request of Backend.CreateFile.
+type CreateFileRequest struct {
+	// File is the metadata of the file to be created.
+	File *drive.File
+
+	// Media is the content of the file. Nil means no content.
+	Media io.Reader
+
+	// MediaOptions configures the upload of Media.
+	MediaOptions []googleapi.MediaOption
+
+	// Progress, if not nil, is called with the number of bytes of Media uploaded so far.
+	Progress func(uploaded int64)
+
+	// OCRLanguage is a language hint for OCR when Media is an image or PDF converted into a Google Doc.
+	OCRLanguage string
+}
+
+// UpdateFileRequest is the request of Backend.UpdateFile.
+type UpdateFileRequest struct {
+	// FileID is the ID of the file to be updated.
+	FileID string
+
+	// File is the metadata to be applied. Zero-valued fields are left unchanged
+	// unless they are listed in File.ForceSendFields.
+	File *drive.File
+
+	// AddParents is the list of parent IDs to be added.
+	AddParents []string
+
+	// RemoveParents is the list of parent IDs to be removed.
+	RemoveParents []string
+
+	// Media is the new content of the file. Nil leaves the content unchanged.
+	Media io.Reader
+
+	// MediaOptions configures the upload of Media.
+	MediaOptions []googleapi.MediaOption
+
+	// Progress, if not nil, is called with the number of bytes of Media uploaded so far.
+	Progress func(uploaded int64)
+}
+
+// CopyFileRequest is the request of Backend.CopyFile.
+type CopyFileRequest struct {
+	// FileID is the ID of the file to be copied.
+	FileID string
+
+	// File is the metadata to be applied to the copy, such as its name and parents.
+	File *drive.File
+}
+
+// DownloadFileRequest is the request of Backend.DownloadFile.
+type DownloadFileRequest struct {
+	// FileID is the ID of the file to be downloaded.
+	FileID string
+
+	// Offset is the position of the first byte to be downloaded.
+	Offset int64
+
+	// Length is the number of bytes to be downloaded. Zero means zero bytes, so the content is empty,
+	// and a negative value means up to the end of the file.
+	Length int64
+}
+
+// CreatePermissionRequest is the request of Backend.CreatePermission.
+type CreatePermissionRequest struct {
+	// FileID is the ID of the file on which the permission is created.
+	FileID string
+
+	// Permission is the permission to be created.
+	Permission *drive.Permission
+
+	// SuppressNotificationEmail disables the notification email that is sent to the users and groups
+	// granted the permission by default.
+	SuppressNotificationEmail bool
+
+	// EmailMessage is a plain text message included in the notification email.
+	EmailMessage string
+
+	// TransferOwnership must be true to create a permission with the owner role,
+	// which makes its grantee the owner of the file and the previous owner a writer.
+	TransferOwnership bool
+}
+
+// UpdatePermissionRequest is the request of Backend.UpdatePermission.
+type UpdatePermissionRequest struct {
+	// FileID is the ID of the file that has the permission.
+	FileID string
+
+	// Permission is the permission to be applied. Its Id identifies the permission to be updated.
+	Permission *drive.Permission
+
+	// RemoveExpiration removes the expiration time of the permission.
+	RemoveExpiration bool
+
+	// TransferOwnership must be true to change the role of the permission to owner,
+	// which makes its grantee the owner of the file and the previous owner a writer.
+	TransferOwnership bool
+}
+
+// UpdateRevisionRequest is the request of Backend.UpdateRevision.
+type UpdateRevisionRequest struct {
+	// FileID is the ID of the file that has the revision.
+	FileID string
+
+	// Revision is the revision to be applied. Its Id identifies the revision to be updated,
+	// and zero-valued fields are left unchanged unless they are listed in Revision.ForceSendFields.
+	Revision *drive.Revision
+}
+
+// ListChangesRequest is the request of Backend.ListChanges.
+type ListChangesRequest struct {
+	// DriveID is the ID of the shared drive whose changes are listed. Empty means My Drive.
+	DriveID string
+
+	// PageToken is the token returned by GetStartPageToken, or the NextPageToken or NewStartPageToken of a previous page.
+	PageToken string
+
+	// PageSize is the maximum number of changes in a page. Zero means the default of the backend.
+	PageSize int64
+}
+
+// ListDrivesRequest is the request of Backend.ListDrives.
+type ListDrivesRequest struct {
+	// Query is a search query for shared drives, such as "name contains 'project'". Empty means all shared drives.
+	Query string
+
+	// PageSize is the maximum number of shared drives in a page. Zero means the default of the backend.
+	PageSize int64
+
+	// PageToken is the NextPageToken of the previous page, or empty for the first page.
+	PageToken string
+}
+
+// CreateDriveRequest is the request of Backend.CreateDrive.
+type CreateDriveRequest struct {
+	// RequestID uniquely identifies the request so that a repeated request does not create a duplicate shared drive.
+	RequestID string
+
+	// Drive is the metadata of the shared drive to be created.
+	Drive *drive.Drive
+}
+
+// UpdateDriveRequest is the request of Backend.UpdateDrive.
+type UpdateDriveRequest struct {
+	// Drive is the metadata to be applied. Its Id identifies the shared drive to be updated,
+	// and zero-valued fields are left unchanged unless they are listed in Drive.ForceSendFields
+	// or, for restrictions, in Drive.Restrictions.ForceSendFields.
+	Drive *drive.Drive
+}
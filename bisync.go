@@ -0,0 +1,998 @@
+package drivefs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// DefaultSyncStateFile is the name of the state file that Bisync stores in the local directory
+// unless BisyncOptions.StateFile is specified.
+const DefaultSyncStateFile = ".drivefs-sync.json"
+
+// ConflictResolution is the way Bisync resolves a conflict.
+type ConflictResolution int
+
+const (
+	// KeepBoth keeps the remote version at the conflicting path and the local version as a copy
+	// renamed like "notes (local conflict).txt" on both sides.
+	KeepBoth ConflictResolution = iota
+
+	// PreferLocal overwrites the remote version with the local version.
+	PreferLocal
+
+	// PreferRemote overwrites the local version with the remote version.
+	PreferRemote
+)
+
+// SyncConflict describes a file that has been changed both locally and remotely since the last synchronization.
+type SyncConflict struct {
+	// Path is the conflicting path relative to the synchronized directories.
+	// For a file moved to different paths on both sides, it is the path at the last synchronization.
+	Path Path
+
+	// LocalModTime and RemoteModTime are the modification times of the local and remote versions.
+	LocalModTime  time.Time
+	RemoteModTime time.Time
+
+	// Resolution is the way the conflict has been resolved. It is only set in BisyncResult.Conflicts.
+	Resolution ConflictResolution
+}
+
+// BisyncOptions configures the synchronization performed by Bisync.
+type BisyncOptions struct {
+	// StateFile is the path of the file in which the state of the synchronization is persisted between runs.
+	// Empty means DefaultSyncStateFile in the local directory. The state file itself is never synchronized.
+	StateFile string
+
+	// ResolveConflict decides how each conflict is resolved. Nil means KeepBoth for every conflict.
+	// For a file moved to different paths on both sides, PreferLocal keeps the local path
+	// and the other resolutions keep the remote path.
+	ResolveConflict func(conflict SyncConflict) ConflictResolution
+
+	// DryRun reports the changes that would be made in BisyncResult without making them or updating the state file.
+	DryRun bool
+
+	// MoveToTrash moves the files and directories deleted from Google Drive to trash
+	// instead of permanently deleting them. Local files are always deleted permanently.
+	MoveToTrash bool
+
+	// Include and Exclude select the files and directories to be synchronized as in SyncOptions.
+	Include []string
+	Exclude []string
+}
+
+// BisyncResult summarizes the changes made by Bisync.
+// Paths are relative to the synchronized directories, such as "/docs/notes.txt".
+// Unchanged files are not listed, so the Skipped fields are always empty.
+type BisyncResult struct {
+	// Local lists the changes made to the local directory.
+	Local SyncResult
+
+	// Remote lists the changes made to the Google Drive folder.
+	Remote SyncResult
+
+	// Conflicts lists the files changed on both sides since the last synchronization with their resolutions.
+	Conflicts []SyncConflict
+}
+
+// Bisync synchronizes the local directory localDir and the Google Drive folder with the given remoteFolderID in both directions,
+// creating localDir if it does not exist.
+//
+// The state of every synchronized file, namely its path, FileID, size, MD5 checksum and modification times,
+// is persisted in a state file, so that the changes made on each side since the last synchronization can be detected.
+// Changes made on only one side are applied to the other side, including creations, modifications and deletions;
+// a modification wins over a deletion on the other side.
+// Remote moves and renames are detected by FileID and local ones by content, and they are applied as moves
+// rather than as deletions and creations, so that the FileIDs, permissions and revisions of the remote files are kept.
+// Files changed on both sides are resolved by opts.ResolveConflict.
+// A directory is deleted only if it has become empty.
+//
+// The first synchronization, or one without a usable state file, merges both sides without deleting anything,
+// and files with different contents at the same path are regarded as conflicts.
+// Google Apps files, shortcuts, symbolic links and all but the first of remote siblings with the same name ordered by ID are ignored.
+// Returns a summary of the changes, which is partial if an error occurs; the state file is updated in either case.
+// Returns ErrNotFound if the remote folder does not exist.
+func (s *DriveFS) Bisync(localDir string, remoteFolderID FileID, opts BisyncOptions) (result BisyncResult, err error) {
+	return s.BisyncContext(context.Background(), localDir, remoteFolderID, opts)
+}
+
+// BisyncContext is like Bisync but uses ctx for all Google Drive API calls.
+func (s *DriveFS) BisyncContext(ctx context.Context, localDir string, remoteFolderID FileID, opts BisyncOptions) (result BisyncResult, err error) {
+	if err := validatePatterns(SyncOptions{Include: opts.Include, Exclude: opts.Exclude}); err != nil {
+		return BisyncResult{}, err
+	}
+	root, found, err := findByID(ctx, s.backend, string(remoteFolderID))
+	if err != nil {
+		return BisyncResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found || root.MimeType != mimeTypeGoogleAppFolder {
+		return BisyncResult{}, fmt.Errorf("directory '%s' not found: %w", remoteFolderID, ErrNotFound)
+	}
+	if !opts.DryRun {
+		if err := os.MkdirAll(localDir, 0o755); err != nil {
+			return BisyncResult{}, newIOError("failed to create local directory", err)
+		}
+	}
+	stateFile := opts.StateFile
+	if stateFile == "" {
+		stateFile = filepath.Join(localDir, DefaultSyncStateFile)
+	}
+	state, err := loadSyncState(stateFile, FileID(root.Id))
+	if err != nil {
+		return BisyncResult{}, err
+	}
+
+	b := &bisync{
+		s:        s,
+		opts:     opts,
+		patterns: SyncOptions{Include: opts.Include, Exclude: opts.Exclude},
+		localDir: localDir,
+		rootID:   root.Id,
+		state:    state,
+		local:    map[Path]*bisyncLocal{},
+		remote:   map[Path]*bisyncRemote{},
+		result:   &result,
+	}
+	if rel, err := filepath.Rel(localDir, stateFile); err == nil && filepath.IsLocal(rel) {
+		b.stateRel = Path("/" + filepath.ToSlash(rel))
+	}
+	err = b.run(ctx)
+	if !opts.DryRun {
+		err = errors.Join(err, saveSyncState(stateFile, FileID(root.Id), b.state))
+	}
+	return result, err
+}
+
+// syncState is the content of a state file of Bisync.
+type syncState struct {
+	RemoteFolderID FileID                   `json:"remoteFolderId"`
+	Entries        map[Path]*syncStateEntry `json:"entries"`
+}
+
+// syncStateEntry is the state of a file or directory that was identical on both sides at the last synchronization.
+type syncStateEntry struct {
+	ID            FileID    `json:"id"`
+	Dir           bool      `json:"dir,omitempty"`
+	Size          int64     `json:"size,omitempty"`
+	MD5Checksum   string    `json:"md5Checksum,omitempty"`
+	LocalModTime  time.Time `json:"localModTime,omitzero"`
+	RemoteModTime time.Time `json:"remoteModTime,omitzero"`
+}
+
+// loadSyncState returns the entries of the state file, which are empty if the file does not exist
+// or has been written for another remote folder.
+func loadSyncState(stateFile string, remoteFolderID FileID) (map[Path]*syncStateEntry, error) {
+	data, err := os.ReadFile(stateFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[Path]*syncStateEntry{}, nil
+	}
+	if err != nil {
+		return nil, newIOError("failed to read sync state", err)
+	}
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, newIOError("failed to parse sync state", err)
+	}
+	if state.RemoteFolderID != remoteFolderID || state.Entries == nil {
+		return map[Path]*syncStateEntry{}, nil
+	}
+	return state.Entries, nil
+}
+
+func saveSyncState(stateFile string, remoteFolderID FileID, entries map[Path]*syncStateEntry) error {
+	data, err := json.MarshalIndent(syncState{RemoteFolderID: remoteFolderID, Entries: entries}, "", "  ")
+	if err != nil {
+		return newIOError("failed to encode sync state", err)
+	}
+	return writeLocalFile(stateFile, bytes.NewReader(data), "")
+}
+
+type bisyncLocal struct {
+	dir     bool
+	size    int64
+	modTime time.Time
+	// md5 is the checksum of the content, which is computed on demand.
+	md5 string
+}
+
+type bisyncRemote struct {
+	id       string
+	parentID string
+	dir      bool
+	size     int64
+	md5      string
+	modTime  time.Time
+}
+
+// bisyncDeletion is a deletion of a file or directory that is performed after all other changes.
+type bisyncDeletion struct {
+	path   Path
+	remote bool
+}
+
+type bisync struct {
+	s        *DriveFS
+	opts     BisyncOptions
+	patterns SyncOptions
+	localDir string
+	stateRel Path
+	rootID   string
+	state    map[Path]*syncStateEntry
+	local    map[Path]*bisyncLocal
+	remote   map[Path]*bisyncRemote
+	result   *BisyncResult
+	// index is the index of the current paths of the files, which is only set while applyMoves runs.
+	index *bisyncIndex
+
+	deletions []bisyncDeletion
+}
+
+// bisyncIndex maps the files in the state, the remote files and the local files to their current paths,
+// so that the paths of moved files are found without scanning the maps.
+// An entry may be stale, so the maps of bisync are checked for the path found in the index.
+type bisyncIndex struct {
+	state  map[FileID]Path
+	remote map[string]Path
+	local  map[*bisyncLocal]Path
+}
+
+type bisyncChange int
+
+const (
+	bisyncAbsent bisyncChange = iota
+	bisyncUnchanged
+	bisyncChanged
+	bisyncDeleted
+)
+
+func (b *bisync) run(ctx context.Context) error {
+	if err := b.scanLocal(); err != nil {
+		return err
+	}
+	if err := b.scanRemote(ctx, b.rootID, nil); err != nil {
+		return err
+	}
+	if err := b.applyMoves(ctx); err != nil {
+		return err
+	}
+
+	paths := map[Path]bool{}
+	for p := range b.local {
+		paths[p] = true
+	}
+	for p := range b.remote {
+		paths[p] = true
+	}
+	for p := range b.state {
+		paths[p] = true
+	}
+	queue := slices.Sorted(maps.Keys(paths))
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p := queue[0]
+		queue = queue[1:]
+		added, err := b.merge(ctx, p)
+		if err != nil {
+			return err
+		}
+		queue = append(queue, added...)
+	}
+	return b.applyDeletions(ctx)
+}
+
+func (b *bisync) localPath(p Path) string {
+	return filepath.Join(b.localDir, filepath.FromSlash(string(p)))
+}
+
+func (b *bisync) scanLocal() error {
+	return filepath.WalkDir(b.localDir, func(localPath string, d fs.DirEntry, err error) error {
+		if err != nil && localPath == b.localDir && b.opts.DryRun && errors.Is(err, fs.ErrNotExist) {
+			// The local directory is created only when the synchronization is not a dry run.
+			return nil
+		}
+		if err != nil {
+			return newIOError("failed to read local directory", err)
+		}
+		rel, err := filepath.Rel(b.localDir, localPath)
+		if err != nil {
+			return newIOError("failed to read local directory", err)
+		}
+		if rel == "." {
+			return nil
+		}
+		p := Path("/" + filepath.ToSlash(rel))
+		if p == b.stateRel || (!d.IsDir() && !d.Type().IsRegular()) {
+			return nil
+		}
+		if isExcluded(b.patterns, strings.Split(string(p[1:]), "/"), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return newIOError("failed to stat local file", err)
+		}
+		b.local[p] = &bisyncLocal{dir: d.IsDir(), size: info.Size(), modTime: info.ModTime()}
+		if d.IsDir() {
+			b.local[p].size = 0
+		}
+		return nil
+	})
+}
+
+func (b *bisync) scanRemote(ctx context.Context, folderID string, rel []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	files, err := findAllIn(ctx, b.s.backend, folderID)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	slices.SortStableFunc(files, func(a, b *drive.File) int { return strings.Compare(a.Id, b.Id) })
+	for _, f := range files {
+		dir := f.MimeType == mimeTypeGoogleAppFolder
+		if !dir && strings.HasPrefix(f.MimeType, mimeTypePrefixGoogleApp) {
+			continue
+		}
+		fileRel := append(append([]string{}, rel...), f.Name)
+		p := syncPath(fileRel)
+		if strings.Contains(f.Name, "/") || b.remote[p] != nil || p == b.stateRel || isExcluded(b.patterns, fileRel, dir) {
+			continue
+		}
+		modTime, _ := time.Parse(time.RFC3339, f.ModifiedTime)
+		b.remote[p] = &bisyncRemote{id: f.Id, parentID: folderID, dir: dir, size: f.Size, md5: f.Md5Checksum, modTime: modTime}
+		if dir {
+			if err := b.scanRemote(ctx, f.Id, fileRel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *bisync) localMD5(p Path, l *bisyncLocal) (string, error) {
+	if l.md5 != "" {
+		return l.md5, nil
+	}
+	file, err := os.Open(b.localPath(p))
+	if err != nil {
+		return "", newIOError("failed to open local file", err)
+	}
+	defer file.Close()
+	sum, err := md5Checksum(file)
+	if err != nil {
+		return "", newIOError("failed to read local file", err)
+	}
+	l.md5 = sum
+	return sum, nil
+}
+
+// applyMoves applies the moves detected on each side to the other side.
+func (b *bisync) applyMoves(ctx context.Context) error {
+	localMoves, err := b.detectLocalMoves()
+	if err != nil {
+		return err
+	}
+
+	b.index = &bisyncIndex{
+		state:  make(map[FileID]Path, len(b.state)),
+		remote: make(map[string]Path, len(b.remote)),
+		local:  make(map[*bisyncLocal]Path, len(b.local)),
+	}
+	defer func() { b.index = nil }()
+	for p, st := range b.state {
+		b.index.state[st.ID] = p
+	}
+	for p, r := range b.remote {
+		b.index.remote[r.id] = p
+	}
+	for p, l := range b.local {
+		b.index.local[l] = p
+	}
+
+	// Remote moves are applied first, so that local moves into remotely moved directories are applied to their new paths.
+	// Parents are moved before their children, which may have been moved together with them.
+	ids := make([]FileID, 0, len(b.state))
+	for _, p := range slices.Sorted(maps.Keys(b.state)) {
+		ids = append(ids, b.state[p].ID)
+	}
+	for _, id := range ids {
+		from, ok := b.statePathOf(id)
+		remoteTo, remoteFound := b.remotePathOf(id)
+		if !ok || !remoteFound || remoteTo == from {
+			continue
+		}
+		if localMoves[id] == nil {
+			if b.local[from] == nil {
+				// The local file has been deleted, which is handled at the new path.
+				b.moveState(from, remoteTo)
+			} else if err := b.moveLocalOrForget(from, from, remoteTo); err != nil {
+				return err
+			}
+			continue
+		}
+		localTo := b.localPathOf(localMoves[id])
+		delete(localMoves, id)
+		if localTo == remoteTo {
+			b.moveState(from, remoteTo)
+			continue
+		}
+		conflict := b.resolve(SyncConflict{Path: from, LocalModTime: b.local[localTo].modTime, RemoteModTime: b.remote[remoteTo].modTime})
+		if conflict.Resolution == PreferLocal {
+			err = b.moveRemoteOrForget(ctx, from, remoteTo, localTo)
+		} else {
+			err = b.moveLocalOrForget(from, localTo, remoteTo)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range ids {
+		if localMoves[id] == nil {
+			continue
+		}
+		from, ok := b.statePathOf(id)
+		if !ok || b.remote[from] == nil || b.remote[from].id != string(id) {
+			// The remote file has been deleted, so the moved local file is regarded as a new one.
+			continue
+		}
+		if err := b.moveRemoteOrForget(ctx, from, from, b.localPathOf(localMoves[id])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveLocalOrForget moves the local file or directory at from to to, and the state at statePath to to.
+// If another local file exists at to, the state is forgotten instead, so that both files are regarded as new ones.
+func (b *bisync) moveLocalOrForget(statePath, from, to Path) error {
+	if b.local[to] != nil {
+		delete(b.state, statePath)
+		return nil
+	}
+	if err := b.moveLocal(from, to); err != nil {
+		return err
+	}
+	b.moveState(statePath, to)
+	return nil
+}
+
+// moveRemoteOrForget moves the remote file at from to to, and the state at statePath to to.
+// If another remote file exists at to, the state is forgotten instead, so that both files are regarded as new ones.
+func (b *bisync) moveRemoteOrForget(ctx context.Context, statePath, from, to Path) error {
+	if b.remote[to] != nil {
+		delete(b.state, statePath)
+		return nil
+	}
+	if err := b.moveRemote(ctx, from, to); err != nil {
+		return err
+	}
+	b.moveState(statePath, to)
+	return nil
+}
+
+// detectLocalMoves returns the new local paths of the files that have been moved locally since the last synchronization,
+// which are the new files with the same content as exactly one of the deleted files.
+func (b *bisync) detectLocalMoves() (map[FileID]*bisyncLocal, error) {
+	type key struct {
+		size int64
+		md5  string
+	}
+	deleted := map[key][]Path{}
+	sizes := map[int64]bool{}
+	for p, st := range b.state {
+		if !st.Dir && b.local[p] == nil {
+			k := key{st.Size, st.MD5Checksum}
+			deleted[k] = append(deleted[k], p)
+			sizes[st.Size] = true
+		}
+	}
+	moves := map[FileID]*bisyncLocal{}
+	for _, p := range slices.Sorted(maps.Keys(b.local)) {
+		l := b.local[p]
+		if l.dir || b.state[p] != nil || !sizes[l.size] {
+			continue
+		}
+		sum, err := b.localMD5(p, l)
+		if err != nil {
+			return nil, err
+		}
+		k := key{l.size, sum}
+		if len(deleted[k]) != 1 {
+			continue
+		}
+		moves[b.state[deleted[k][0]].ID] = l
+		delete(deleted, k)
+	}
+	return moves, nil
+}
+
+// statePathOf returns the current path of the state of the file with the given id.
+func (b *bisync) statePathOf(id FileID) (Path, bool) {
+	p, ok := b.index.state[id]
+	if !ok || b.state[p] == nil || b.state[p].ID != id {
+		return "", false
+	}
+	return p, true
+}
+
+// remotePathOf returns the current path of the remote file with the given id.
+func (b *bisync) remotePathOf(id FileID) (Path, bool) {
+	p, ok := b.index.remote[string(id)]
+	if !ok || b.remote[p] == nil || b.remote[p].id != string(id) {
+		return "", false
+	}
+	return p, true
+}
+
+// localPathOf returns the current path of the local file l.
+func (b *bisync) localPathOf(l *bisyncLocal) Path {
+	if p, ok := b.index.local[l]; ok && b.local[p] == l {
+		return p
+	}
+	return ""
+}
+
+// indexState records p as the path of the state st in the index if any.
+func (b *bisync) indexState(p Path, st *syncStateEntry) {
+	if b.index != nil {
+		b.index.state[st.ID] = p
+	}
+}
+
+// indexRemote records p as the path of the remote file r in the index if any.
+func (b *bisync) indexRemote(p Path, r *bisyncRemote) {
+	if b.index != nil && r.id != "" {
+		b.index.remote[r.id] = p
+	}
+}
+
+// indexLocal records p as the path of the local file l in the index if any.
+func (b *bisync) indexLocal(p Path, l *bisyncLocal) {
+	if b.index != nil {
+		b.index.local[l] = p
+	}
+}
+
+// movePrefix moves the entries of m at from and under from to to, and calls onMove with the new path of each entry.
+func movePrefix[T any](m map[Path]T, from, to Path, onMove func(Path, T)) {
+	var moved []Path
+	for p := range m {
+		if p == from || strings.HasPrefix(string(p), string(from)+"/") {
+			moved = append(moved, p)
+		}
+	}
+	entries := make(map[Path]T, len(moved))
+	for _, p := range moved {
+		entries[to+p[len(from):]] = m[p]
+		onMove(to+p[len(from):], m[p])
+		delete(m, p)
+	}
+	maps.Copy(m, entries)
+}
+
+// deletePrefix deletes the entries of m at p and under p.
+func deletePrefix[T any](m map[Path]T, p Path) {
+	maps.DeleteFunc(m, func(q Path, _ T) bool {
+		return q == p || strings.HasPrefix(string(q), string(p)+"/")
+	})
+}
+
+func (b *bisync) moveState(from, to Path) {
+	if from != to {
+		movePrefix(b.state, from, to, b.indexState)
+	}
+}
+
+func (b *bisync) moveLocal(from, to Path) error {
+	b.result.Local.Moved = append(b.result.Local.Moved, SyncMove{From: from, To: to})
+	if !b.opts.DryRun {
+		if err := b.ensureLocalDir(parentPath(to)); err != nil {
+			return err
+		}
+		if err := os.Rename(b.localPath(from), b.localPath(to)); err != nil {
+			return newIOError("failed to move local file", err)
+		}
+	}
+	movePrefix(b.local, from, to, b.indexLocal)
+	return nil
+}
+
+func (b *bisync) moveRemote(ctx context.Context, from, to Path) error {
+	b.result.Remote.Moved = append(b.result.Remote.Moved, SyncMove{From: from, To: to})
+	r := b.remote[from]
+	parentID, err := b.ensureRemoteDir(ctx, parentPath(to))
+	if err != nil {
+		return err
+	}
+	if !b.opts.DryRun {
+		req := UpdateFileRequest{FileID: r.id, File: &drive.File{Name: path.Base(string(to))}}
+		if parentID != r.parentID {
+			req.AddParents, req.RemoveParents = []string{parentID}, []string{r.parentID}
+		}
+		if _, err := b.s.backend.UpdateFile(ctx, req); err != nil {
+			return newDriveError("failed to move file", err)
+		}
+	}
+	r.parentID = parentID
+	movePrefix(b.remote, from, to, b.indexRemote)
+	return nil
+}
+
+func parentPath(p Path) Path {
+	return Path(path.Dir(string(p)))
+}
+
+// ensureLocalDir creates the local directory at p and its parents if they do not exist.
+func (b *bisync) ensureLocalDir(p Path) error {
+	if p == "/" {
+		return nil
+	}
+	if l, ok := b.local[p]; ok && l.dir {
+		return nil
+	}
+	if err := b.ensureLocalDir(parentPath(p)); err != nil {
+		return err
+	}
+	b.result.Local.Created = append(b.result.Local.Created, p)
+	if !b.opts.DryRun {
+		if err := os.Mkdir(b.localPath(p), 0o755); err != nil {
+			return newIOError("failed to create local directory", err)
+		}
+	}
+	b.local[p] = &bisyncLocal{dir: true}
+	b.indexLocal(p, b.local[p])
+	if r, ok := b.remote[p]; ok && r.dir {
+		b.state[p] = &syncStateEntry{ID: FileID(r.id), Dir: true}
+		b.indexState(p, b.state[p])
+	}
+	return nil
+}
+
+// ensureRemoteDir creates the remote directory at p and its parents if they do not exist, and returns its ID.
+// The ID is empty in a dry run if the directory does not exist.
+func (b *bisync) ensureRemoteDir(ctx context.Context, p Path) (string, error) {
+	if p == "/" {
+		return b.rootID, nil
+	}
+	if r, ok := b.remote[p]; ok && r.dir {
+		return r.id, nil
+	}
+	parentID, err := b.ensureRemoteDir(ctx, parentPath(p))
+	if err != nil {
+		return "", err
+	}
+	b.result.Remote.Created = append(b.result.Remote.Created, p)
+	id := ""
+	if !b.opts.DryRun {
+		dir, err := createDirIn(ctx, b.s.backend, parentID, path.Base(string(p)))
+		if err != nil {
+			return "", err
+		}
+		id = dir.Id
+	}
+	b.remote[p] = &bisyncRemote{id: id, parentID: parentID, dir: true}
+	b.indexRemote(p, b.remote[p])
+	if l, ok := b.local[p]; ok && l.dir {
+		b.state[p] = &syncStateEntry{ID: FileID(id), Dir: true}
+		b.indexState(p, b.state[p])
+	}
+	return id, nil
+}
+
+func (b *bisync) resolve(conflict SyncConflict) SyncConflict {
+	conflict.Resolution = KeepBoth
+	if b.opts.ResolveConflict != nil {
+		conflict.Resolution = b.opts.ResolveConflict(conflict)
+	}
+	b.result.Conflicts = append(b.result.Conflicts, conflict)
+	return conflict
+}
+
+func (b *bisync) localChange(p Path, l *bisyncLocal, st *syncStateEntry) (bisyncChange, error) {
+	switch {
+	case l == nil && st == nil:
+		return bisyncAbsent, nil
+	case l == nil:
+		return bisyncDeleted, nil
+	case st == nil || l.dir != st.Dir:
+		return bisyncChanged, nil
+	case l.dir:
+		return bisyncUnchanged, nil
+	case l.size != st.Size:
+		return bisyncChanged, nil
+	case l.modTime.Truncate(time.Millisecond).Equal(st.LocalModTime.Truncate(time.Millisecond)):
+		return bisyncUnchanged, nil
+	}
+	sum, err := b.localMD5(p, l)
+	if err != nil {
+		return 0, err
+	}
+	if sum != st.MD5Checksum {
+		return bisyncChanged, nil
+	}
+	return bisyncUnchanged, nil
+}
+
+func remoteChange(r *bisyncRemote, st *syncStateEntry) bisyncChange {
+	switch {
+	case r == nil && st == nil:
+		return bisyncAbsent
+	case r == nil:
+		return bisyncDeleted
+	case st == nil || r.id != string(st.ID) || r.dir != st.Dir:
+		return bisyncChanged
+	case r.dir:
+		return bisyncUnchanged
+	case r.md5 != "" && st.MD5Checksum != "":
+		if r.md5 != st.MD5Checksum {
+			return bisyncChanged
+		}
+		return bisyncUnchanged
+	case r.size != st.Size || !r.modTime.Equal(st.RemoteModTime):
+		return bisyncChanged
+	}
+	return bisyncUnchanged
+}
+
+// merge synchronizes the path p and returns the paths that have to be synchronized additionally.
+func (b *bisync) merge(ctx context.Context, p Path) (added []Path, err error) {
+	l, r, st := b.local[p], b.remote[p], b.state[p]
+	lc, err := b.localChange(p, l, st)
+	if err != nil {
+		return nil, err
+	}
+	rc := remoteChange(r, st)
+
+	switch {
+	case lc == bisyncUnchanged && rc == bisyncUnchanged:
+		if !st.Dir {
+			st.LocalModTime, st.RemoteModTime = l.modTime, r.modTime
+		}
+		return nil, nil
+	case (lc == bisyncAbsent || lc == bisyncDeleted) && (rc == bisyncAbsent || rc == bisyncDeleted):
+		delete(b.state, p)
+		return nil, nil
+	case lc == bisyncDeleted && rc == bisyncUnchanged:
+		b.deletions = append(b.deletions, bisyncDeletion{path: p, remote: true})
+		return nil, nil
+	case lc == bisyncUnchanged && rc == bisyncDeleted:
+		b.deletions = append(b.deletions, bisyncDeletion{path: p, remote: false})
+		return nil, nil
+	case lc == bisyncChanged && rc != bisyncChanged:
+		return nil, b.upload(ctx, p)
+	case lc != bisyncChanged && rc == bisyncChanged:
+		return nil, b.download(ctx, p)
+	}
+
+	// Both sides have been changed.
+	if l.dir && r.dir {
+		b.state[p] = &syncStateEntry{ID: FileID(r.id), Dir: true}
+		return nil, nil
+	}
+	if !l.dir && !r.dir && r.md5 != "" {
+		sum, err := b.localMD5(p, l)
+		if err != nil {
+			return nil, err
+		}
+		if sum == r.md5 {
+			b.state[p] = &syncStateEntry{ID: FileID(r.id), Size: l.size, MD5Checksum: sum, LocalModTime: l.modTime, RemoteModTime: r.modTime}
+			return nil, nil
+		}
+	}
+	conflict := b.resolve(SyncConflict{Path: p, LocalModTime: l.modTime, RemoteModTime: r.modTime})
+	switch conflict.Resolution {
+	case PreferLocal:
+		return nil, b.upload(ctx, p)
+	case PreferRemote:
+		return nil, b.download(ctx, p)
+	}
+	copyPath := b.conflictPath(p, l.dir)
+	if err := b.moveLocal(p, copyPath); err != nil {
+		return nil, err
+	}
+	for q := range b.local {
+		if q == copyPath || strings.HasPrefix(string(q), string(copyPath)+"/") {
+			added = append(added, q)
+		}
+	}
+	slices.Sort(added)
+	return added, b.download(ctx, p)
+}
+
+// conflictPath returns a path for the local copy of the conflicting file at p that is unused on both sides.
+func (b *bisync) conflictPath(p Path, dir bool) Path {
+	name := path.Base(string(p))
+	ext := path.Ext(name)
+	if dir || ext == name {
+		ext = ""
+	}
+	base := strings.TrimSuffix(string(p), ext)
+	for i := 1; ; i++ {
+		suffix := " (local conflict)"
+		if i > 1 {
+			suffix = fmt.Sprintf(" (local conflict %d)", i)
+		}
+		q := Path(base + suffix + ext)
+		if b.local[q] == nil && b.remote[q] == nil && b.state[q] == nil {
+			return q
+		}
+	}
+}
+
+// upload makes the remote file or directory at p identical to the local one.
+func (b *bisync) upload(ctx context.Context, p Path) error {
+	l, r := b.local[p], b.remote[p]
+	if r != nil && r.dir != l.dir {
+		// The remote file or directory is replaced by one of the other kind.
+		b.result.Remote.Deleted = append(b.result.Remote.Deleted, p)
+		if !b.opts.DryRun {
+			if err := b.s.RemoveAllContext(ctx, FileID(r.id), b.opts.MoveToTrash); err != nil {
+				return err
+			}
+		}
+		deletePrefix(b.remote, p)
+		r = nil
+	}
+	if l.dir {
+		_, err := b.ensureRemoteDir(ctx, p)
+		return err
+	}
+
+	sum, err := b.localMD5(p, l)
+	if err != nil {
+		return err
+	}
+	parentID, err := b.ensureRemoteDir(ctx, parentPath(p))
+	if err != nil {
+		return err
+	}
+	uploaded := &bisyncRemote{parentID: parentID, size: l.size, md5: sum, modTime: l.modTime.UTC().Truncate(time.Millisecond)}
+	if r == nil {
+		b.result.Remote.Created = append(b.result.Remote.Created, p)
+	} else {
+		b.result.Remote.Updated = append(b.result.Remote.Updated, p)
+		uploaded.id = r.id
+	}
+	if !b.opts.DryRun {
+		file, err := os.Open(b.localPath(p))
+		if err != nil {
+			return newIOError("failed to open local file", err)
+		}
+		defer file.Close()
+		meta := &drive.File{ModifiedTime: uploaded.modTime.Format(time.RFC3339Nano)}
+		var f *drive.File
+		if r == nil {
+			meta.Name, meta.Parents = path.Base(string(p)), []string{parentID}
+			f, err = b.s.backend.CreateFile(ctx, CreateFileRequest{File: meta, Media: file})
+		} else {
+			f, err = b.s.backend.UpdateFile(ctx, UpdateFileRequest{FileID: r.id, File: meta, Media: file})
+		}
+		if err != nil {
+			return newDriveError("failed to upload file", err)
+		}
+		uploaded.id = f.Id
+		if modTime, err := time.Parse(time.RFC3339, f.ModifiedTime); err == nil {
+			uploaded.modTime = modTime
+		}
+	}
+	b.remote[p] = uploaded
+	b.state[p] = &syncStateEntry{ID: FileID(uploaded.id), Size: l.size, MD5Checksum: sum, LocalModTime: l.modTime, RemoteModTime: uploaded.modTime}
+	return nil
+}
+
+// download makes the local file or directory at p identical to the remote one.
+func (b *bisync) download(ctx context.Context, p Path) error {
+	l, r := b.local[p], b.remote[p]
+	if l != nil && l.dir != r.dir {
+		// The local file or directory is replaced by one of the other kind.
+		b.result.Local.Deleted = append(b.result.Local.Deleted, p)
+		if !b.opts.DryRun {
+			if err := os.RemoveAll(b.localPath(p)); err != nil {
+				return newIOError("failed to delete local file", err)
+			}
+		}
+		deletePrefix(b.local, p)
+		l = nil
+	}
+	if r.dir {
+		return b.ensureLocalDir(p)
+	}
+
+	if err := b.ensureLocalDir(parentPath(p)); err != nil {
+		return err
+	}
+	if l == nil {
+		b.result.Local.Created = append(b.result.Local.Created, p)
+	} else {
+		b.result.Local.Updated = append(b.result.Local.Updated, p)
+	}
+	if !b.opts.DryRun {
+		body, err := b.s.backend.DownloadFile(ctx, DownloadFileRequest{FileID: r.id, Length: -1})
+		if err != nil {
+			return newDriveError("failed to download file", err)
+		}
+		defer body.Close()
+		if err := writeLocalFile(b.localPath(p), body, r.modTime.Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+	}
+	b.local[p] = &bisyncLocal{size: r.size, modTime: r.modTime, md5: r.md5}
+	b.state[p] = &syncStateEntry{ID: FileID(r.id), Size: r.size, MD5Checksum: r.md5, LocalModTime: r.modTime, RemoteModTime: r.modTime}
+	return nil
+}
+
+// applyDeletions performs the deletions of files before those of their parent directories,
+// and deletes directories only if they have become empty.
+func (b *bisync) applyDeletions(ctx context.Context) error {
+	slices.SortFunc(b.deletions, func(x, y bisyncDeletion) int { return strings.Compare(string(y.path), string(x.path)) })
+	for _, d := range b.deletions {
+		if d.remote {
+			r := b.remote[d.path]
+			if r.dir && hasChildren(b.remote, d.path) {
+				continue
+			}
+			if r.dir && !b.opts.DryRun {
+				nonEmpty, err := existsIn(ctx, b.s.backend, r.id)
+				if err != nil {
+					return err
+				}
+				if nonEmpty {
+					continue
+				}
+			}
+			b.result.Remote.Deleted = append(b.result.Remote.Deleted, d.path)
+			if !b.opts.DryRun {
+				if err := b.s.RemoveAllContext(ctx, FileID(r.id), b.opts.MoveToTrash); err != nil {
+					return err
+				}
+			}
+			delete(b.remote, d.path)
+		} else {
+			l := b.local[d.path]
+			if l.dir && hasChildren(b.local, d.path) {
+				continue
+			}
+			if !b.opts.DryRun {
+				if err := os.Remove(b.localPath(d.path)); err != nil {
+					if l.dir {
+						// The directory contains files that are not synchronized.
+						continue
+					}
+					return newIOError("failed to delete local file", err)
+				}
+			}
+			b.result.Local.Deleted = append(b.result.Local.Deleted, d.path)
+			delete(b.local, d.path)
+		}
+		delete(b.state, d.path)
+	}
+	return nil
+}
+
+func hasChildren[T any](m map[Path]T, p Path) bool {
+	for q := range m {
+		if strings.HasPrefix(string(q), string(p)+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package drivefs_test
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func newBisyncTest(t *testing.T) (*drivefsmem.Drive, *drivefs.DriveFS, string) {
+	t.Helper()
+	mem := drivefsmem.New(drivefsmem.Options{})
+	return mem, drivefs.NewWithBackend(mem), t.TempDir()
+}
+
+func bisync(t *testing.T, s *drivefs.DriveFS, local string, rootID drivefs.FileID, opts drivefs.BisyncOptions) drivefs.BisyncResult {
+	t.Helper()
+	result, err := s.Bisync(local, rootID, opts)
+	if err != nil {
+		t.Fatalf("Bisync() error = %v", err)
+	}
+	return result
+}
+
+func writeRemoteFile(t *testing.T, s *drivefs.DriveFS, parentID drivefs.FileID, name, content string) drivefs.FileInfo {
+	t.Helper()
+	info, err := s.Create(parentID, name)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := s.WriteFile(info.ID, []byte(content)); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return info
+}
+
+func findRemote(t *testing.T, s *drivefs.DriveFS, rootID drivefs.FileID, p drivefs.Path) drivefs.FileInfo {
+	t.Helper()
+	found, err := s.FindByPath(rootID, p)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath(%q) = %v, %v, want a file", p, found, err)
+	}
+	return found[0]
+}
+
+func withoutStateFile(tree map[drivefs.Path]string) map[drivefs.Path]string {
+	delete(tree, "/"+drivefs.DefaultSyncStateFile)
+	return tree
+}
+
+func TestDriveFS_Bisync(t *testing.T) {
+	t.Run("initial merge", func(t *testing.T) {
+		mem, s, local := newBisyncTest(t)
+		writeLocalFiles(t, local, map[string]string{"a.txt": "local", "dir/same.txt": "same"})
+		dir, err := s.MkdirAll(mem.RootID(), "/dir")
+		if err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		writeRemoteFile(t, s, mem.RootID(), "b.txt", "remote")
+		writeRemoteFile(t, s, dir.ID, "same.txt", "same")
+
+		result := bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+		assertPaths(t, "Remote.Created", result.Remote.Created, "/a.txt")
+		assertPaths(t, "Local.Created", result.Local.Created, "/b.txt")
+		if len(result.Conflicts) != 0 {
+			t.Fatalf("Conflicts = %v, want none", result.Conflicts)
+		}
+		want := map[drivefs.Path]string{"/a.txt": "local", "/b.txt": "remote", "/dir/": "", "/dir/same.txt": "same"}
+		if got := withoutStateFile(localTree(t, local)); !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+		if got := remoteTree(t, s, mem.RootID()); !maps.Equal(got, want) {
+			t.Fatalf("remote tree = %v, want %v", got, want)
+		}
+
+		result = bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+		if len(result.Local.Created)+len(result.Local.Updated)+len(result.Remote.Created)+len(result.Remote.Updated) != 0 {
+			t.Fatalf("second Bisync() = %+v, want no changes", result)
+		}
+	})
+
+	t.Run("changes and deletions", func(t *testing.T) {
+		mem, s, local := newBisyncTest(t)
+		writeLocalFiles(t, local, map[string]string{"a.txt": "a", "b.txt": "b", "dir/c.txt": "c"})
+		bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+
+		writeLocalFiles(t, local, map[string]string{"a.txt": "local edit"})
+		if err := os.RemoveAll(filepath.Join(local, "dir")); err != nil {
+			t.Fatalf("RemoveAll() error = %v", err)
+		}
+		b := findRemote(t, s, mem.RootID(), "/b.txt")
+		if err := s.WriteFile(b.ID, []byte("remote edit")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+
+		result := bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+		assertPaths(t, "Remote.Updated", result.Remote.Updated, "/a.txt")
+		assertPaths(t, "Local.Updated", result.Local.Updated, "/b.txt")
+		assertPaths(t, "Remote.Deleted", result.Remote.Deleted, "/dir", "/dir/c.txt")
+		want := map[drivefs.Path]string{"/a.txt": "local edit", "/b.txt": "remote edit"}
+		if got := withoutStateFile(localTree(t, local)); !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+		if got := remoteTree(t, s, mem.RootID()); !maps.Equal(got, want) {
+			t.Fatalf("remote tree = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("moves", func(t *testing.T) {
+		mem, s, local := newBisyncTest(t)
+		writeLocalFiles(t, local, map[string]string{"a.txt": "a", "b.txt": "b", "dir/c.txt": "c"})
+		bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+		a := findRemote(t, s, mem.RootID(), "/a.txt")
+		b := findRemote(t, s, mem.RootID(), "/b.txt")
+		dir := findRemote(t, s, mem.RootID(), "/dir")
+
+		if err := os.Rename(filepath.Join(local, "a.txt"), filepath.Join(local, "dir", "moved.txt")); err != nil {
+			t.Fatalf("Rename() error = %v", err)
+		}
+		if _, err := s.Rename(b.ID, "renamed.txt"); err != nil {
+			t.Fatalf("Rename() error = %v", err)
+		}
+		if _, err := s.Rename(dir.ID, "folder"); err != nil {
+			t.Fatalf("Rename() error = %v", err)
+		}
+
+		result := bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+		if len(result.Remote.Created)+len(result.Remote.Deleted)+len(result.Local.Created)+len(result.Local.Deleted) != 0 {
+			t.Fatalf("Bisync() = %+v, want only moves", result)
+		}
+		want := map[drivefs.Path]string{"/renamed.txt": "b", "/folder/": "", "/folder/c.txt": "c", "/folder/moved.txt": "a"}
+		if got := withoutStateFile(localTree(t, local)); !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+		if got := remoteTree(t, s, mem.RootID()); !maps.Equal(got, want) {
+			t.Fatalf("remote tree = %v, want %v", got, want)
+		}
+		if moved := findRemote(t, s, mem.RootID(), "/folder/moved.txt"); moved.ID != a.ID {
+			t.Fatalf("moved file ID = %s, want %s", moved.ID, a.ID)
+		}
+	})
+
+	conflictCases := []struct {
+		name       string
+		resolution drivefs.ConflictResolution
+		want       map[drivefs.Path]string
+	}{
+		{"keep both", drivefs.KeepBoth, map[drivefs.Path]string{"/a.txt": "remote edit", "/a (local conflict).txt": "local edit"}},
+		{"prefer local", drivefs.PreferLocal, map[drivefs.Path]string{"/a.txt": "local edit"}},
+		{"prefer remote", drivefs.PreferRemote, map[drivefs.Path]string{"/a.txt": "remote edit"}},
+	}
+	for _, c := range conflictCases {
+		t.Run("conflict "+c.name, func(t *testing.T) {
+			mem, s, local := newBisyncTest(t)
+			writeLocalFiles(t, local, map[string]string{"a.txt": "a"})
+			bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{})
+
+			writeLocalFiles(t, local, map[string]string{"a.txt": "local edit"})
+			a := findRemote(t, s, mem.RootID(), "/a.txt")
+			if err := s.WriteFile(a.ID, []byte("remote edit")); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			result := bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{
+				ResolveConflict: func(drivefs.SyncConflict) drivefs.ConflictResolution { return c.resolution },
+			})
+			if len(result.Conflicts) != 1 || result.Conflicts[0].Path != "/a.txt" || result.Conflicts[0].Resolution != c.resolution {
+				t.Fatalf("Conflicts = %+v, want /a.txt resolved by %v", result.Conflicts, c.resolution)
+			}
+			if got := withoutStateFile(localTree(t, local)); !maps.Equal(got, c.want) {
+				t.Fatalf("local tree = %v, want %v", got, c.want)
+			}
+			if got := remoteTree(t, s, mem.RootID()); !maps.Equal(got, c.want) {
+				t.Fatalf("remote tree = %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		mem, s, local := newBisyncTest(t)
+		writeLocalFiles(t, local, map[string]string{"a.txt": "a"})
+		writeRemoteFile(t, s, mem.RootID(), "b.txt", "b")
+
+		result := bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{DryRun: true})
+		assertPaths(t, "Remote.Created", result.Remote.Created, "/a.txt")
+		assertPaths(t, "Local.Created", result.Local.Created, "/b.txt")
+		if got, want := localTree(t, local), map[drivefs.Path]string{"/a.txt": "a"}; !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+		if got, want := remoteTree(t, s, mem.RootID()), map[drivefs.Path]string{"/b.txt": "b"}; !maps.Equal(got, want) {
+			t.Fatalf("remote tree = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("dry run into missing directory", func(t *testing.T) {
+		mem, s, local := newBisyncTest(t)
+		local = filepath.Join(local, "missing")
+		dir, err := s.Mkdir(mem.RootID(), "dir")
+		if err != nil {
+			t.Fatalf("Mkdir() error = %v", err)
+		}
+		writeRemoteFile(t, s, dir.ID, "b.txt", "b")
+
+		result := bisync(t, s, local, mem.RootID(), drivefs.BisyncOptions{DryRun: true})
+		assertPaths(t, "Local.Created", result.Local.Created, "/dir", "/dir/b.txt")
+		if _, err := os.Stat(local); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("Stat() error = %v, want the local directory not created", err)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package drivefs
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// CacheOptions configures the path resolution cache of WithCache.
+type CacheOptions struct {
+	// TTL is how long a cached lookup is used before Google Drive is queried again. Zero means 1 minute.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of cached lookups, beyond which the least recently used ones are evicted.
+	// Zero means 10000.
+	MaxEntries int
+}
+
+// WithCache returns a DriveFS that performs the same operations as s
+// but caches the lookups of files by ID and by name in a parent directory,
+// which are used to resolve paths by FindByPath, MkdirAll and ResolvePath and by the FS opened on it.
+//
+// Changes made through the returned DriveFS, such as Mkdir, Rename, Move and Remove, invalidate the affected lookups,
+// but changes made by others may be seen only after opts.TTL.
+// Use InvalidateCache to invalidate lookups explicitly.
+// The cache is safe for concurrent use.
+func (s *DriveFS) WithCache(opts CacheOptions) *DriveFS {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	c := &pathCache{
+		ttl:        opts.TTL,
+		maxEntries: opts.MaxEntries,
+		lru:        list.New(),
+		entries:    map[cacheKey]*list.Element{},
+	}
+	return &DriveFS{backend: &cacheBackend{Backend: s.backend, cache: c}, cache: c}
+}
+
+// InvalidateCache removes the cached lookups of the files and directories with the given fileIDs,
+// including the lookups of the contents of the directories, or all cached lookups if no fileIDs are given.
+// It does nothing if s has no cache.
+func (s *DriveFS) InvalidateCache(fileIDs ...FileID) {
+	if s.cache == nil {
+		return
+	}
+	if len(fileIDs) == 0 {
+		s.cache.clear()
+		return
+	}
+	ids := make([]string, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = string(id)
+	}
+	s.cache.invalidate(ids...)
+}
+
+// getFile is like findByID but uses the cache of s if any.
+func (s *DriveFS) getFile(ctx context.Context, fileID string) (file *drive.File, found bool, err error) {
+	if s.cache == nil {
+		return findByID(ctx, s.backend, fileID)
+	}
+	key := cacheKey{fileID: fileID}
+	if files, ok := s.cache.get(key); ok {
+		return files[0], true, nil
+	}
+	file, found, err = findByID(ctx, s.backend, fileID)
+	if err == nil && found {
+		s.cache.put(key, []*drive.File{file})
+	}
+	return file, found, err
+}
+
+// findByName is like findAllByNameIn but uses the cache of s if any.
+func (s *DriveFS) findByName(ctx context.Context, parentID, name string) (files []*drive.File, err error) {
+	if s.cache == nil {
+		return findAllByNameIn(ctx, s.backend, parentID, name)
+	}
+	key := cacheKey{parentID: parentID, name: name}
+	if files, ok := s.cache.get(key); ok {
+		return files, nil
+	}
+	files, err = findAllByNameIn(ctx, s.backend, parentID, name)
+	if err == nil {
+		s.cache.put(key, files)
+	}
+	return files, err
+}
+
+// cacheKey identifies a lookup of the file with fileID, or of the files named name in the directory with parentID.
+type cacheKey struct {
+	fileID   string
+	parentID string
+	name     string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	files   []*drive.File
+	expires time.Time
+}
+
+// pathCache is a cache of lookups of files with a TTL and a limited number of entries.
+// The cached files must not be modified.
+type pathCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu sync.Mutex
+	// lru holds the *cacheEntry values from the most recently used to the least recently used.
+	lru     *list.List
+	entries map[cacheKey]*list.Element
+}
+
+func (c *pathCache) get(key cacheKey) (files []*drive.File, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(e)
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return entry.files, true
+}
+
+func (c *pathCache) put(key cacheKey, files []*drive.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, files: files, expires: time.Now().Add(c.ttl)})
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+// invalidate removes the lookups of the files with the given ids and of the contents of the directories with the ids.
+func (c *pathCache) invalidate(ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*cacheEntry)
+		if slices.Contains(ids, entry.key.fileID) || slices.Contains(ids, entry.key.parentID) ||
+			slices.ContainsFunc(entry.files, func(f *drive.File) bool { return slices.Contains(ids, f.Id) }) {
+			c.remove(e)
+		}
+		e = next
+	}
+}
+
+// invalidateName removes the lookups of the files named name in the directories with the given parentIDs.
+func (c *pathCache) invalidateName(name string, parentIDs ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, parentID := range parentIDs {
+		if e, ok := c.entries[cacheKey{parentID: parentID, name: name}]; ok {
+			c.remove(e)
+		}
+	}
+}
+
+func (c *pathCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.Init()
+	clear(c.entries)
+}
+
+func (c *pathCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
+
+// cacheBackend is a Backend that invalidates the lookups in cache affected by the changes made through it.
+type cacheBackend struct {
+	Backend
+	cache *pathCache
+}
+
+func (b *cacheBackend) CreateFile(ctx context.Context, req CreateFileRequest) (*drive.File, error) {
+	file, err := b.Backend.CreateFile(ctx, req)
+	if req.File != nil {
+		b.cache.invalidateName(req.File.Name, req.File.Parents...)
+	}
+	return file, err
+}
+
+func (b *cacheBackend) UpdateFile(ctx context.Context, req UpdateFileRequest) (*drive.File, error) {
+	file, err := b.Backend.UpdateFile(ctx, req)
+	switch {
+	case req.File != nil && (req.File.Trashed || slices.Contains(req.File.ForceSendFields, "Trashed")):
+		// Trashing or restoring a directory changes the lookups of all of its descendants.
+		b.cache.clear()
+	default:
+		b.cache.invalidate(req.FileID)
+		if file != nil {
+			b.cache.invalidateName(file.Name, append(slices.Clone(file.Parents), req.AddParents...)...)
+		}
+	}
+	return file, err
+}
+
+func (b *cacheBackend) CopyFile(ctx context.Context, req CopyFileRequest) (*drive.File, error) {
+	file, err := b.Backend.CopyFile(ctx, req)
+	if file != nil {
+		b.cache.invalidateName(file.Name, file.Parents...)
+	}
+	return file, err
+}
+
+func (b *cacheBackend) DeleteFile(ctx context.Context, fileID string) error {
+	defer b.cache.clear()
+	return b.Backend.DeleteFile(ctx, fileID)
+}
+
+func (b *cacheBackend) EmptyTrash(ctx context.Context, driveID string) error {
+	defer b.cache.clear()
+	return b.Backend.EmptyTrash(ctx, driveID)
+}
+
+func (b *cacheBackend) DeleteDrive(ctx context.Context, driveID string) error {
+	defer b.cache.clear()
+	return b.Backend.DeleteDrive(ctx, driveID)
+}
@@ -0,0 +1,273 @@
+package drivefs_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+)
+
+// lookupCountingBackend counts the requests to get and list files.
+type lookupCountingBackend struct {
+	drivefs.Backend
+
+	mu       sync.Mutex
+	requests int
+}
+
+func (b *lookupCountingBackend) GetFile(ctx context.Context, fileID string) (*drive.File, error) {
+	b.count()
+	return b.Backend.GetFile(ctx, fileID)
+}
+
+func (b *lookupCountingBackend) ListFiles(ctx context.Context, req drivefs.ListFilesRequest) (*drive.FileList, error) {
+	b.count()
+	return b.Backend.ListFiles(ctx, req)
+}
+
+func (b *lookupCountingBackend) count() {
+	b.mu.Lock()
+	b.requests++
+	b.mu.Unlock()
+}
+
+// reset returns the number of requests counted so far and resets it.
+func (b *lookupCountingBackend) reset() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.requests
+	b.requests = 0
+	return n
+}
+
+func newCachedDriveFS(t *testing.T, opts drivefs.CacheOptions) (*drivefs.DriveFS, *lookupCountingBackend, drivefs.FileID) {
+	t.Helper()
+	mem := drivefsmem.New(drivefsmem.Options{})
+	b := &lookupCountingBackend{Backend: mem}
+	s := drivefs.NewWithBackend(b).WithCache(opts)
+	if _, err := s.MkdirAll(mem.RootID(), "/a/b/c"); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	b.reset()
+	return s, b, drivefs.FileID(mem.RootID())
+}
+
+func TestDriveFS_WithCache(t *testing.T) {
+	s, b, rootID := newCachedDriveFS(t, drivefs.CacheOptions{})
+
+	findRemote(t, s, rootID, "/a/b/c")
+	if n := b.reset(); n != 3 {
+		t.Fatalf("FindByPath() after MkdirAll made %d requests, want 3 for the created directories", n)
+	}
+	found, err := s.FindByPath(rootID, "/a/b/c")
+	if err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath() = %+v, %v, want c", found, err)
+	}
+	if n := b.reset(); n != 0 {
+		t.Fatalf("cached FindByPath() made %d requests, want 0", n)
+	}
+	c := found[0]
+	if p, err := s.ResolvePath(c.ID); err != nil || p != "/a/b/c" {
+		t.Fatalf("ResolvePath() = %q, %v, want /a/b/c", p, err)
+	}
+	b.reset()
+	if p, err := s.ResolvePath(c.ID); err != nil || p != "/a/b/c" {
+		t.Fatalf("ResolvePath() = %q, %v, want /a/b/c", p, err)
+	}
+	if info, err := s.MkdirAll(rootID, "/a/b/c"); err != nil || info.ID != c.ID {
+		t.Fatalf("MkdirAll() = %+v, %v, want c", info, err)
+	}
+	if n := b.reset(); n != 0 {
+		t.Fatalf("cached ResolvePath() and MkdirAll() made %d requests, want 0", n)
+	}
+
+	// The lookups of missing names are cached too.
+	if found, err := s.FindByPath(rootID, "/a/missing"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath() = %+v, %v, want nothing", found, err)
+	}
+	b.reset()
+	if found, err := s.FindByPath(rootID, "/a/missing"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath() = %+v, %v, want nothing", found, err)
+	}
+	if n := b.reset(); n != 0 {
+		t.Fatalf("FindByPath() of a cached missing path made %d requests, want 0", n)
+	}
+
+	// The cache is kept by WithRetry.
+	retrying := s.WithRetry(drivefs.RetryPolicy{MaxAttempts: 2})
+	if found, err := retrying.FindByPath(rootID, "/a/b/c"); err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath() = %+v, %v, want c", found, err)
+	}
+	if n := b.reset(); n != 0 {
+		t.Fatalf("FindByPath() through WithRetry made %d requests, want 0", n)
+	}
+}
+
+func TestDriveFS_WithCache_Invalidation(t *testing.T) {
+	s, _, rootID := newCachedDriveFS(t, drivefs.CacheOptions{})
+	a := findRemote(t, s, rootID, "/a")
+	c := findRemote(t, s, rootID, "/a/b/c")
+	// Cache the lookups of the names that appear later.
+	for _, p := range []drivefs.Path{"/a/d", "/a/b/e"} {
+		if _, err := s.FindByPath(rootID, p); err != nil {
+			t.Fatalf("FindByPath() error = %v", err)
+		}
+	}
+
+	assertFound := func(p drivefs.Path, want int) {
+		t.Helper()
+		found, err := s.FindByPath(rootID, p)
+		if err != nil || len(found) != want {
+			t.Fatalf("FindByPath(%q) = %+v, %v, want %d items", p, found, err, want)
+		}
+	}
+	assertPath := func(id drivefs.FileID, want drivefs.Path) {
+		t.Helper()
+		if p, err := s.ResolvePath(id); err != nil || p != want {
+			t.Fatalf("ResolvePath() = %q, %v, want %q", p, err, want)
+		}
+	}
+
+	d, err := s.Mkdir(a.ID, "d")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	assertFound("/a/d", 1)
+
+	if _, err := s.Rename(c.ID, "e"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	assertFound("/a/b/c", 0)
+	assertFound("/a/b/e", 1)
+	assertPath(c.ID, "/a/b/e")
+
+	if err := s.Move(c.ID, d.ID); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	assertFound("/a/b/e", 0)
+	assertFound("/a/d/e", 1)
+	assertPath(c.ID, "/a/d/e")
+
+	if err := s.RemoveAll(d.ID, true); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	assertFound("/a/d", 0)
+	assertFound("/a/d/e", 0)
+
+	b := findRemote(t, s, rootID, "/a/b")
+	if err := s.Remove(b.ID, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	assertFound("/a/b", 0)
+	if _, err := s.ResolvePath(b.ID); err == nil {
+		t.Fatalf("ResolvePath() of a deleted file error = nil")
+	}
+
+	if _, err := s.MkdirAll(rootID, "/a/b/c"); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	assertFound("/a/b/c", 1)
+}
+
+func TestDriveFS_InvalidateCache(t *testing.T) {
+	s, b, rootID := newCachedDriveFS(t, drivefs.CacheOptions{})
+	a := findRemote(t, s, rootID, "/a")
+	findRemote(t, s, rootID, "/a/b/c")
+	b.reset()
+
+	s.InvalidateCache(a.ID)
+	findRemote(t, s, rootID, "/a/b/c")
+	if n := b.reset(); n != 2 {
+		t.Fatalf("FindByPath() after invalidating a made %d requests, want 2", n)
+	}
+
+	s.InvalidateCache()
+	findRemote(t, s, rootID, "/a/b/c")
+	if n := b.reset(); n != 4 {
+		t.Fatalf("FindByPath() after clearing the cache made %d requests, want 4", n)
+	}
+
+	// Changes made by others are seen after invalidating the cache.
+	if _, err := s.FindByPath(rootID, "/a/x"); err != nil {
+		t.Fatalf("FindByPath() error = %v", err)
+	}
+	other := drivefs.NewWithBackend(b)
+	if _, err := other.Mkdir(a.ID, "x"); err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if found, err := s.FindByPath(rootID, "/a/x"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath() = %+v, %v, want the cached lookup", found, err)
+	}
+	s.InvalidateCache(a.ID)
+	findRemote(t, s, rootID, "/a/x")
+
+	// InvalidateCache does nothing without a cache.
+	drivefs.NewWithBackend(b).InvalidateCache(a.ID)
+}
+
+func TestDriveFS_WithCache_Limits(t *testing.T) {
+	t.Run("ttl", func(t *testing.T) {
+		s, b, rootID := newCachedDriveFS(t, drivefs.CacheOptions{TTL: 50 * time.Millisecond})
+		findRemote(t, s, rootID, "/a/b/c")
+		b.reset()
+		findRemote(t, s, rootID, "/a/b/c")
+		if n := b.reset(); n != 0 {
+			t.Fatalf("FindByPath() made %d requests, want 0", n)
+		}
+		time.Sleep(100 * time.Millisecond)
+		findRemote(t, s, rootID, "/a/b/c")
+		if n := b.reset(); n != 4 {
+			t.Fatalf("FindByPath() after the TTL made %d requests, want 4", n)
+		}
+	})
+
+	t.Run("max entries", func(t *testing.T) {
+		s, b, rootID := newCachedDriveFS(t, drivefs.CacheOptions{MaxEntries: 2})
+		findRemote(t, s, rootID, "/a")
+		b.reset()
+		findRemote(t, s, rootID, "/a")
+		if n := b.reset(); n != 0 {
+			t.Fatalf("FindByPath() made %d requests, want 0", n)
+		}
+		// Only the lookups of b and c are kept.
+		findRemote(t, s, rootID, "/a/b/c")
+		b.reset()
+		findRemote(t, s, rootID, "/a")
+		if n := b.reset(); n != 2 {
+			t.Fatalf("FindByPath() after evictions made %d requests, want 2", n)
+		}
+	})
+}
+
+func TestDriveFS_WithCache_Concurrent(t *testing.T) {
+	s, _, rootID := newCachedDriveFS(t, drivefs.CacheOptions{MaxEntries: 8})
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := drivefs.Path(fmt.Sprintf("/a/b/c/d%d", i))
+			for range 10 {
+				info, err := s.MkdirAll(rootID, p)
+				if err != nil {
+					t.Errorf("MkdirAll() error = %v", err)
+					return
+				}
+				if _, err := s.ResolvePath(info.ID); err != nil {
+					t.Errorf("ResolvePath() error = %v", err)
+					return
+				}
+				if _, err := s.FindByPath(rootID, p); err != nil {
+					t.Errorf("FindByPath() error = %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
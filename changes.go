@@ -0,0 +1,399 @@
+package drivefs
+
+import (
+	"bytes"
+	"cmp"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// ChangeKind represents the kind of a change to a file.
+type ChangeKind string
+
+const (
+	// ChangeCreated means that the file has been created.
+	ChangeCreated ChangeKind = "created"
+
+	// ChangeModified means that the content or metadata of the file has changed,
+	// including when the file has been restored from the trash.
+	ChangeModified ChangeKind = "modified"
+
+	// ChangeTrashed means that the file has been moved to the trash, explicitly or together with its parent.
+	ChangeTrashed ChangeKind = "trashed"
+
+	// ChangeRemoved means that the file has been permanently deleted or is no longer accessible.
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeMoved means that the file has been moved to other parents or renamed.
+	ChangeMoved ChangeKind = "moved"
+
+	// ChangePermissionChanged means that the permissions of the file have changed.
+	ChangePermissionChanged ChangeKind = "permissionChanged"
+)
+
+// Change is a change to a file delivered by a ChangeFeed.
+type Change struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind
+
+	// File is the metadata of the file after the change. For ChangeRemoved, only File.ID is set.
+	File FileInfo
+
+	// DriveID is the ID of the shared drive in which the change has been made, or empty for My Drive.
+	DriveID string
+
+	// Time is the time of the change.
+	Time time.Time
+}
+
+// PageTokenStore persists the page tokens of a ChangeFeed, which mark the position in the changes of each drive,
+// so that the ChangeFeed can resume after a restart.
+// The drive is identified by the ID of a shared drive, or by the empty string for My Drive.
+type PageTokenStore interface {
+	// LoadPageToken returns the page token saved for the drive, or an empty string if none has been saved.
+	LoadPageToken(ctx context.Context, driveID string) (string, error)
+
+	// SavePageToken saves the page token for the drive.
+	SavePageToken(ctx context.Context, driveID string, token string) error
+}
+
+// NewFilePageTokenStore returns a PageTokenStore that saves the page tokens in the local JSON file at path.
+// The file is created on the first save, and is replaced atomically on each save.
+func NewFilePageTokenStore(path string) PageTokenStore {
+	return &filePageTokenStore{path: path}
+}
+
+type filePageTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *filePageTokenStore) LoadPageToken(_ context.Context, driveID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return tokens[driveID], nil
+}
+
+func (s *filePageTokenStore) SavePageToken(_ context.Context, driveID string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, err := s.load()
+	if err != nil {
+		return err
+	}
+	tokens[driveID] = token
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return newIOError("failed to encode page tokens", err)
+	}
+	return writeLocalFile(s.path, bytes.NewReader(data), "")
+}
+
+func (s *filePageTokenStore) load() (map[string]string, error) {
+	tokens := map[string]string{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, newIOError("failed to read page tokens", err)
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, newIOError("failed to parse page tokens", err)
+	}
+	return tokens, nil
+}
+
+// ChangeFeedOptions configures a ChangeFeed created by NewChangeFeed.
+type ChangeFeedOptions struct {
+	// DriveIDs is the list of IDs of the shared drives whose changes are delivered in addition to those of My Drive.
+	DriveIDs []string
+
+	// Store persists the page tokens so that the ChangeFeed resumes where it stopped after a restart.
+	// Nil keeps the page tokens in memory only.
+	Store PageTokenStore
+
+	// PollInterval is the interval between polls of Watch. Zero means 30 seconds.
+	PollInterval time.Duration
+
+	// MaxTrackedFiles is the maximum number of files whose last delivered states are kept to classify their next changes,
+	// beyond which the states of the least recently changed files are forgotten. Zero means 100000.
+	MaxTrackedFiles int
+}
+
+// ChangeFeed delivers the changes to the files in My Drive and shared drives using the Changes API of Google Drive.
+//
+// The first poll of a drive without a stored page token obtains a start page token and delivers no change,
+// and each later poll delivers the changes made since the previous one.
+// A file changed several times between polls may be delivered once with its latest state.
+//
+// Google Drive reports only the latest state of a changed file, so the kind of a change is determined by comparing it
+// with the state of the file last delivered by the same ChangeFeed.
+// ChangeMoved and ChangePermissionChanged are therefore reported only for files that have been delivered before,
+// and other changes to a file seen for the first time are reported as ChangeCreated if the file has been created
+// after the ChangeFeed was created, or as ChangeModified otherwise.
+// The states of at most MaxTrackedFiles files are kept, and a file whose state has been forgotten is treated as
+// seen for the first time.
+//
+// A ChangeFeed is not safe for concurrent use.
+type ChangeFeed struct {
+	backend  Backend
+	opts     ChangeFeedOptions
+	started  time.Time
+	tokens   map[string]string
+	previous map[string]*list.Element
+	// lru holds the *changeState values of previous from the most recently changed to the least recently changed.
+	lru *list.List
+}
+
+// changeState is the state of a file that is compared with its next change.
+type changeState struct {
+	id          string
+	name        string
+	parents     []string
+	trashed     bool
+	permissions string
+}
+
+// NewChangeFeed returns a ChangeFeed that delivers the changes visible to driveFS.
+func NewChangeFeed(driveFS *DriveFS, opts ChangeFeedOptions) *ChangeFeed {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if opts.MaxTrackedFiles <= 0 {
+		opts.MaxTrackedFiles = 100000
+	}
+	return &ChangeFeed{
+		backend:  driveFS.backend,
+		opts:     opts,
+		started:  time.Now(),
+		tokens:   map[string]string{},
+		previous: map[string]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// Poll returns the changes made since the previous poll, and saves the new page tokens to the store.
+// The page token of each page of changes is saved as soon as the page has been collected,
+// so if polling fails, the changes collected so far are returned together with the error and are not delivered again.
+func (f *ChangeFeed) Poll() (changes []Change, err error) {
+	return f.PollContext(context.Background())
+}
+
+// PollContext is like Poll but uses ctx for all Google Drive API calls.
+func (f *ChangeFeed) PollContext(ctx context.Context) (changes []Change, err error) {
+	for _, driveID := range f.driveIDs() {
+		err := f.poll(ctx, driveID, func(c Change) bool {
+			changes = append(changes, c)
+			return true
+		})
+		if err != nil {
+			return changes, err
+		}
+	}
+	return changes, nil
+}
+
+// Watch returns an iterator that polls the changes every PollInterval and yields them until the loop is stopped.
+// The page token of a page of changes is saved after all of its changes have been yielded,
+// so changes may be yielded again after a restart but are never skipped.
+// The iteration ends after yielding an error with a zero Change.
+func (f *ChangeFeed) Watch() iter.Seq2[Change, error] {
+	return f.WatchContext(context.Background())
+}
+
+// WatchContext is like Watch but uses ctx for all Google Drive API calls.
+// The iteration also ends, yielding the error of ctx, when ctx is done.
+func (f *ChangeFeed) WatchContext(ctx context.Context) iter.Seq2[Change, error] {
+	return func(yield func(Change, error) bool) {
+		for {
+			for _, driveID := range f.driveIDs() {
+				stopped := false
+				err := f.poll(ctx, driveID, func(c Change) bool {
+					stopped = !yield(c, nil)
+					return !stopped
+				})
+				if stopped {
+					return
+				}
+				if err != nil {
+					yield(Change{}, err)
+					return
+				}
+			}
+			timer := time.NewTimer(f.opts.PollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				yield(Change{}, ctx.Err())
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func (f *ChangeFeed) driveIDs() []string {
+	return append([]string{""}, f.opts.DriveIDs...)
+}
+
+// poll passes the changes of the drive made since its page token to deliver page by page,
+// and saves the page token of each page after all of its changes have been delivered.
+// It stops without saving the page token if deliver returns false.
+func (f *ChangeFeed) poll(ctx context.Context, driveID string, deliver func(Change) bool) error {
+	token, err := f.loadPageToken(ctx, driveID)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		token, err = f.backend.GetStartPageToken(ctx, driveID)
+		if err != nil {
+			return newDriveError("failed to get start page token", err)
+		}
+		return f.savePageToken(ctx, driveID, token)
+	}
+	for {
+		res, err := f.backend.ListChanges(ctx, ListChangesRequest{DriveID: driveID, PageToken: token})
+		if err != nil {
+			return newDriveError("failed to list changes", err)
+		}
+		for _, c := range res.Changes {
+			change, state, ok, err := f.classify(driveID, c)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if !deliver(change) {
+				return nil
+			}
+			f.commit(change, state)
+		}
+		if res.NextPageToken == "" {
+			return f.savePageToken(ctx, driveID, cmp.Or(res.NewStartPageToken, token))
+		}
+		token = res.NextPageToken
+		if err := f.savePageToken(ctx, driveID, token); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *ChangeFeed) loadPageToken(ctx context.Context, driveID string) (string, error) {
+	if token, ok := f.tokens[driveID]; ok || f.opts.Store == nil {
+		return token, nil
+	}
+	token, err := f.opts.Store.LoadPageToken(ctx, driveID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load page token: %w", err)
+	}
+	f.tokens[driveID] = token
+	return token, nil
+}
+
+func (f *ChangeFeed) savePageToken(ctx context.Context, driveID, token string) error {
+	if f.opts.Store != nil {
+		if err := f.opts.Store.SavePageToken(ctx, driveID, token); err != nil {
+			return fmt.Errorf("failed to save page token: %w", err)
+		}
+	}
+	f.tokens[driveID] = token
+	return nil
+}
+
+// classify converts c into a Change by comparing it with the previous state of the file,
+// and returns the new state of the file, which is nil for ChangeRemoved, to be committed once the change is delivered.
+// It returns false for changes that are not changes to files, such as changes to shared drives themselves.
+func (f *ChangeFeed) classify(driveID string, c *drive.Change) (change Change, state *changeState, ok bool, err error) {
+	if c.ChangeType != "" && c.ChangeType != "file" {
+		return Change{}, nil, false, nil
+	}
+	changeTime, _ := time.Parse(time.RFC3339, c.Time)
+	change = Change{DriveID: driveID, Time: changeTime}
+	if c.Removed || c.File == nil {
+		change.Kind = ChangeRemoved
+		change.File = FileInfo{ID: FileID(c.FileId)}
+		return change, nil, true, nil
+	}
+	change.File, err = newFileInfo(c.File)
+	if err != nil {
+		return Change{}, nil, false, fmt.Errorf("failed to create FileInfo: %w", err)
+	}
+
+	state = &changeState{
+		id:          c.File.Id,
+		name:        c.File.Name,
+		parents:     slices.Sorted(slices.Values(c.File.Parents)),
+		trashed:     c.File.Trashed,
+		permissions: permissionsFingerprint(c.File.Permissions),
+	}
+	var prev changeState
+	e, seen := f.previous[state.id]
+	if seen {
+		prev = *e.Value.(*changeState)
+	}
+	switch {
+	case state.trashed && (!seen || !prev.trashed):
+		change.Kind = ChangeTrashed
+	case !seen:
+		change.Kind = ChangeModified
+		if created, err := time.Parse(time.RFC3339, c.File.CreatedTime); err == nil && !created.Before(f.started) {
+			change.Kind = ChangeCreated
+		}
+	case prev.name != state.name || !slices.Equal(prev.parents, state.parents):
+		change.Kind = ChangeMoved
+	case prev.permissions != state.permissions:
+		change.Kind = ChangePermissionChanged
+	default:
+		change.Kind = ChangeModified
+	}
+	return change, state, true, nil
+}
+
+// commit records state as the last delivered state of the file of change, or forgets the file if state is nil.
+func (f *ChangeFeed) commit(change Change, state *changeState) {
+	if state == nil {
+		if e, ok := f.previous[string(change.File.ID)]; ok {
+			f.lru.Remove(e)
+			delete(f.previous, string(change.File.ID))
+		}
+		return
+	}
+	if e, ok := f.previous[state.id]; ok {
+		e.Value = state
+		f.lru.MoveToFront(e)
+		return
+	}
+	f.previous[state.id] = f.lru.PushFront(state)
+	for f.lru.Len() > f.opts.MaxTrackedFiles {
+		e := f.lru.Back()
+		f.lru.Remove(e)
+		delete(f.previous, e.Value.(*changeState).id)
+	}
+}
+
+func permissionsFingerprint(perms []*drive.Permission) string {
+	var entries []string
+	for _, perm := range perms {
+		entries = append(entries, fmt.Sprintf("%s:%s:%t", perm.Id, perm.Role, perm.AllowFileDiscovery))
+	}
+	slices.Sort(entries)
+	return strings.Join(entries, ",")
+}
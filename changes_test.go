@@ -0,0 +1,235 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func poll(t *testing.T, feed *drivefs.ChangeFeed) []drivefs.Change {
+	t.Helper()
+	changes, err := feed.Poll()
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	return changes
+}
+
+func assertChanges(t *testing.T, got []drivefs.Change, want map[drivefs.FileID]drivefs.ChangeKind) {
+	t.Helper()
+	kinds := map[drivefs.FileID]drivefs.ChangeKind{}
+	for _, c := range got {
+		kinds[c.File.ID] = c.Kind
+	}
+	if len(got) != len(want) || len(kinds) != len(want) {
+		t.Fatalf("changes = %+v, want %v", got, want)
+	}
+	for id, kind := range want {
+		if kinds[id] != kind {
+			t.Fatalf("kind of change to %s = %q, want %q", id, kinds[id], kind)
+		}
+	}
+}
+
+func TestChangeFeed_Poll(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	existing := writeRemoteFile(t, s, mem.RootID(), "existing.txt", "existing")
+	feed := drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{})
+	if changes := poll(t, feed); len(changes) != 0 {
+		t.Fatalf("first Poll() = %+v, want no changes", changes)
+	}
+
+	dir, err := s.Mkdir(mem.RootID(), "dir")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	file := writeRemoteFile(t, s, mem.RootID(), "file.txt", "v1")
+	if err := s.WriteFile(existing.ID, []byte("modified")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	assertChanges(t, poll(t, feed), map[drivefs.FileID]drivefs.ChangeKind{
+		dir.ID:      drivefs.ChangeCreated,
+		file.ID:     drivefs.ChangeCreated,
+		existing.ID: drivefs.ChangeModified,
+	})
+	if changes := poll(t, feed); len(changes) != 0 {
+		t.Fatalf("Poll() without changes = %+v, want no changes", changes)
+	}
+
+	if err := s.Move(file.ID, dir.ID); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if _, err := s.PermSet(existing.ID, drivefs.UserPermission("alice@example.com", drivefs.RoleReader)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	assertChanges(t, poll(t, feed), map[drivefs.FileID]drivefs.ChangeKind{
+		file.ID:     drivefs.ChangeMoved,
+		existing.ID: drivefs.ChangePermissionChanged,
+	})
+
+	if err := s.RemoveAll(dir.ID, true); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if err := s.Remove(existing.ID, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	changes := poll(t, feed)
+	assertChanges(t, changes, map[drivefs.FileID]drivefs.ChangeKind{
+		dir.ID:      drivefs.ChangeTrashed,
+		file.ID:     drivefs.ChangeTrashed,
+		existing.ID: drivefs.ChangeRemoved,
+	})
+	for _, c := range changes {
+		if c.Time.IsZero() || c.DriveID != "" {
+			t.Fatalf("change = %+v, want a time in My Drive", c)
+		}
+	}
+}
+
+func TestChangeFeed_Resume(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	store := drivefs.NewFilePageTokenStore(filepath.Join(t.TempDir(), "tokens.json"))
+	poll(t, drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{Store: store}))
+
+	file := writeRemoteFile(t, s, mem.RootID(), "file.txt", "content")
+	assertChanges(t, poll(t, drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{Store: store})), map[drivefs.FileID]drivefs.ChangeKind{
+		file.ID: drivefs.ChangeModified,
+	})
+	if changes := poll(t, drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{Store: store})); len(changes) != 0 {
+		t.Fatalf("Poll() after resuming = %+v, want no changes", changes)
+	}
+}
+
+func TestChangeFeed_Watch(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	feed := drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{PollInterval: time.Millisecond})
+	poll(t, feed)
+
+	want := []drivefs.FileID{writeRemoteFile(t, s, mem.RootID(), "file.txt", "content").ID}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var got []drivefs.FileID
+	for c, err := range feed.WatchContext(ctx) {
+		if len(got) == 3 {
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("WatchContext() error = %v, want context.Canceled", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("WatchContext() error = %v", err)
+		}
+		got = append(got, c.File.ID)
+		if len(got) < 3 {
+			want = append(want, writeRemoteFile(t, s, mem.RootID(), "file.txt", "content").ID)
+		} else {
+			cancel()
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("WatchContext() yielded changes to %v, want %v", got, want)
+	}
+}
+
+func TestChangeFeed_WatchStopped(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	feed := drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{PollInterval: time.Millisecond})
+	poll(t, feed)
+	file := writeRemoteFile(t, s, mem.RootID(), "file.txt", "content")
+	poll(t, feed)
+
+	if _, err := s.Rename(file.ID, "renamed.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	for c, err := range feed.Watch() {
+		if err != nil {
+			t.Fatalf("Watch() error = %v", err)
+		}
+		if c.File.ID != file.ID || c.Kind != drivefs.ChangeMoved {
+			t.Fatalf("Watch() yielded %+v, want the move of file.txt", c)
+		}
+		break
+	}
+	// The change at which the consumer stopped is delivered again with the same kind.
+	assertChanges(t, poll(t, feed), map[drivefs.FileID]drivefs.ChangeKind{file.ID: drivefs.ChangeMoved})
+}
+
+// failingChangesBackend fails to list the changes of the drive with failDriveID.
+type failingChangesBackend struct {
+	drivefs.Backend
+	failDriveID *string
+}
+
+func (b *failingChangesBackend) ListChanges(ctx context.Context, req drivefs.ListChangesRequest) (*drive.ChangeList, error) {
+	if b.failDriveID != nil && req.DriveID == *b.failDriveID {
+		return nil, &googleapi.Error{Code: 500, Message: "list changes failed"}
+	}
+	return b.Backend.ListChanges(ctx, req)
+}
+
+func TestChangeFeed_PollPartialFailure(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	b := &failingChangesBackend{Backend: mem}
+	s := drivefs.NewWithBackend(b)
+	team, err := s.CreateSharedDrive("Team", "")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	feed := drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{DriveIDs: []string{team.ID}})
+	poll(t, feed)
+
+	file := writeRemoteFile(t, s, mem.RootID(), "file.txt", "content")
+	b.failDriveID = &team.ID
+	changes, err := feed.Poll()
+	if !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("Poll() error = %v, want ErrDriveError", err)
+	}
+	assertChanges(t, changes, map[drivefs.FileID]drivefs.ChangeKind{file.ID: drivefs.ChangeCreated})
+
+	b.failDriveID = nil
+	if changes := poll(t, feed); len(changes) != 0 {
+		t.Fatalf("Poll() after the failure = %+v, want the returned changes not delivered again", changes)
+	}
+}
+
+func TestChangeFeed_MaxTrackedFiles(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	feed := drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{MaxTrackedFiles: 1})
+	poll(t, feed)
+
+	a := writeRemoteFile(t, s, mem.RootID(), "a.txt", "a")
+	b := writeRemoteFile(t, s, mem.RootID(), "b.txt", "b")
+	poll(t, feed)
+
+	if _, err := s.Rename(b.ID, "b2.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	assertChanges(t, poll(t, feed), map[drivefs.FileID]drivefs.ChangeKind{b.ID: drivefs.ChangeMoved})
+
+	// The state of a.txt has been forgotten when the state of b.txt was kept.
+	if _, err := s.Rename(a.ID, "a2.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	assertChanges(t, poll(t, feed), map[drivefs.FileID]drivefs.ChangeKind{a.ID: drivefs.ChangeCreated})
+}
+
+func TestChangeFeed_SharedDriveNotFound(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	feed := drivefs.NewChangeFeed(drivefs.NewWithBackend(mem), drivefs.ChangeFeedOptions{DriveIDs: []string{"unknown"}})
+	if _, err := feed.Poll(); !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("Poll() error = %v, want ErrDriveError", err)
+	}
+}
@@ -0,0 +1,333 @@
+package drivefs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// CopyOptions configures CopyAll.
+type CopyOptions struct {
+	// Concurrency is the maximum number of files copied at the same time. Zero means 4.
+	Concurrency int
+
+	// CopyPermissions copies the permissions set on each item, except those of the owner, to its copy.
+	// Inherited permissions are not copied, since the copies inherit those of the destination.
+	// No notification emails are sent to the grantees of the copied permissions.
+	CopyPermissions bool
+
+	// CopyShortcuts copies the shortcuts in the tree. A shortcut that points to an item in the copied tree
+	// is re-targeted to the copy of the item, and other shortcuts point to the same target as the original.
+	// Otherwise shortcuts are skipped.
+	CopyShortcuts bool
+}
+
+// CopyResult is the result of CopyAll.
+type CopyResult struct {
+	// Root is the FileInfo of the copy of the source.
+	Root FileInfo
+
+	// Mapping maps the ID of each source item that has been copied to the ID of its copy.
+	Mapping map[FileID]FileID
+
+	// Errors lists the items that could not be copied, ordered by path.
+	// The contents of a directory that could not be copied are not listed.
+	Errors []CopyError
+}
+
+// CopyError is a failure to copy an item of the tree copied by CopyAll.
+type CopyError struct {
+	// SourceID is the ID of the source item.
+	SourceID FileID
+
+	// Path is the path of the source item relative to the source, such as "/dir/file.txt".
+	Path Path
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// CopyAll copies the file or directory with the given srcID, including all of its contents,
+// into the directory with the given dstParentID under newName, or under the name of the source if newName is empty.
+// Directories are recreated, since Google Drive can only copy files, and files are copied concurrently.
+// Trashed items are not copied.
+//
+// A failure to copy an item inside the tree does not stop the copy but is reported in CopyResult.Errors,
+// and the items that have been copied are listed in CopyResult.Mapping.
+// Returns an error if the source cannot be copied itself, or if ctx is done.
+// Returns ErrNotFound if the source does not exist,
+// and ErrInvalidDestination if the source is a directory and dstParentID is the source or one of its descendants.
+func (s *DriveFS) CopyAll(srcID, dstParentID FileID, newName string, opts CopyOptions) (result CopyResult, err error) {
+	return s.CopyAllContext(context.Background(), srcID, dstParentID, newName, opts)
+}
+
+// CopyAllContext is like CopyAll but uses ctx for all Google Drive API calls.
+func (s *DriveFS) CopyAllContext(ctx context.Context, srcID, dstParentID FileID, newName string, opts CopyOptions) (result CopyResult, err error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	src, found, err := findByID(ctx, s.backend, string(srcID))
+	if err != nil {
+		return CopyResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return CopyResult{}, fmt.Errorf("file not found: %s: %w", srcID, ErrNotFound)
+	}
+	if newName == "" {
+		newName = src.Name
+	}
+	if src.MimeType == mimeTypeGoogleAppFolder {
+		inside, err := isInTree(ctx, s.backend, string(dstParentID), src.Id)
+		if err != nil {
+			return CopyResult{}, fmt.Errorf("failed to check destination: %w", err)
+		}
+		if inside {
+			return CopyResult{}, fmt.Errorf("cannot copy directory '%s' into itself: %w", srcID, ErrInvalidDestination)
+		}
+	}
+
+	c := &copier{s: s, mapping: map[string]string{}, copies: map[string]bool{}, items: map[string]copyItem{}}
+	root := copyItem{src: src, parentID: string(dstParentID), name: newName}
+	var dst *drive.File
+	if src.MimeType == mimeTypeGoogleAppFolder {
+		dst, err = c.copyDir(ctx, root)
+	} else {
+		dst, err = c.copyFile(ctx, root, nil)
+	}
+	if err != nil {
+		return CopyResult{}, err
+	}
+	result.Root, err = newFileInfo(dst)
+	if err != nil {
+		return CopyResult{}, fmt.Errorf("failed to create FileInfo: %w", err)
+	}
+
+	runConcurrently(opts.Concurrency, c.files, func(item copyItem) {
+		if _, err := c.copyFile(ctx, item, nil); err != nil {
+			c.fail(item, err)
+		}
+	})
+	if opts.CopyShortcuts {
+		targets := maps.Clone(c.mapping)
+		runConcurrently(opts.Concurrency, c.shortcuts, func(item copyItem) {
+			if _, err := c.copyFile(ctx, item, targets); err != nil {
+				c.fail(item, err)
+			}
+		})
+	}
+	if opts.CopyPermissions {
+		var pairs [][2]string
+		for srcID, dstID := range c.mapping {
+			pairs = append(pairs, [2]string{srcID, dstID})
+		}
+		runConcurrently(opts.Concurrency, pairs, func(pair [2]string) {
+			if err := copyPermissions(ctx, s.backend, pair[0], pair[1]); err != nil {
+				c.fail(c.items[pair[0]], err)
+			}
+		})
+	}
+
+	result.Mapping = map[FileID]FileID{}
+	for srcID, dstID := range c.mapping {
+		result.Mapping[FileID(srcID)] = FileID(dstID)
+	}
+	result.Errors = c.errors
+	slices.SortStableFunc(result.Errors, func(a, b CopyError) int { return cmp.Compare(a.Path, b.Path) })
+	return result, ctx.Err()
+}
+
+// copyItem is an item of the source tree to be copied into the directory with the ID parentID under name.
+// rel is the path of the item relative to the source.
+type copyItem struct {
+	src      *drive.File
+	parentID string
+	name     string
+	rel      []string
+}
+
+type copier struct {
+	s *DriveFS
+
+	// files and shortcuts are the items to be copied after the directories have been created.
+	files     []copyItem
+	shortcuts []copyItem
+
+	mu sync.Mutex
+	// mapping maps the IDs of the copied source items to the IDs of their copies.
+	mapping map[string]string
+	// copies holds the IDs of the copies.
+	copies map[string]bool
+	// items maps the IDs of the copied source items to the items, to report failures to copy their permissions.
+	items  map[string]copyItem
+	errors []CopyError
+}
+
+// copyDir creates the copy of the directory of item and the copies of its subdirectories,
+// and collects the files and shortcuts in them.
+// A failure to create the copy of the directory is returned, and other failures are recorded.
+func (c *copier) copyDir(ctx context.Context, item copyItem) (*drive.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dst, err := createDirIn(ctx, c.s.backend, item.parentID, item.name)
+	if err != nil {
+		return nil, err
+	}
+	c.done(item, dst)
+
+	children, err := findAllIn(ctx, c.s.backend, item.src.Id)
+	if err != nil {
+		c.fail(item, fmt.Errorf("failed to list files: %w", err))
+		return dst, nil
+	}
+	for _, child := range children {
+		if c.isCopy(child.Id) {
+			// The copy of an item of the tree created inside the tree is not copied again.
+			continue
+		}
+		childItem := copyItem{src: child, parentID: dst.Id, name: child.Name, rel: append(slices.Clone(item.rel), child.Name)}
+		switch child.MimeType {
+		case mimeTypeGoogleAppFolder:
+			if _, err := c.copyDir(ctx, childItem); err != nil {
+				c.fail(childItem, err)
+			}
+		case mimeTypeGoogleAppShortcut:
+			c.shortcuts = append(c.shortcuts, childItem)
+		default:
+			c.files = append(c.files, childItem)
+		}
+	}
+	return dst, nil
+}
+
+// copyFile copies the file or shortcut of item.
+// Shortcuts are recreated with the targets re-targeted according to mapping.
+func (c *copier) copyFile(ctx context.Context, item copyItem, mapping map[string]string) (dst *drive.File, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if item.src.MimeType == mimeTypeGoogleAppShortcut && item.src.ShortcutDetails != nil {
+		targetID := item.src.ShortcutDetails.TargetId
+		if mapped, ok := mapping[targetID]; ok {
+			targetID = mapped
+		}
+		dst, err = createShortcutIn(ctx, c.s.backend, item.parentID, item.name, targetID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		dst, err = c.s.backend.CopyFile(ctx, CopyFileRequest{
+			FileID: item.src.Id,
+			File:   &drive.File{Name: item.name, Parents: []string{item.parentID}},
+		})
+		if err != nil {
+			return nil, newDriveError("failed to copy file", err)
+		}
+	}
+	c.done(item, dst)
+	return dst, nil
+}
+
+func (c *copier) done(item copyItem, dst *drive.File) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mapping[item.src.Id] = dst.Id
+	c.copies[dst.Id] = true
+	c.items[item.src.Id] = item
+}
+
+// isCopy reports whether the file with the given fileID is the copy of a source item.
+func (c *copier) isCopy(fileID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.copies[fileID]
+}
+
+func (c *copier) fail(item copyItem, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors = append(c.errors, CopyError{SourceID: FileID(item.src.Id), Path: syncPath(item.rel), Err: err})
+}
+
+// copyPermissions creates the permissions of the file with the given srcID, except those of the owner
+// and those that are only inherited, on the file with the given dstID.
+func copyPermissions(ctx context.Context, b Backend, srcID, dstID string) error {
+	perms, err := listPermissions(ctx, b, srcID)
+	if err != nil {
+		return fmt.Errorf("failed to copy permissions: %w", err)
+	}
+	for _, perm := range perms {
+		if perm.Role == string(RoleOwner) || inheritedOnly(perm) {
+			continue
+		}
+		_, err := b.CreatePermission(ctx, CreatePermissionRequest{
+			FileID: dstID,
+			Permission: &drive.Permission{
+				Type:               perm.Type,
+				Role:               perm.Role,
+				EmailAddress:       perm.EmailAddress,
+				Domain:             perm.Domain,
+				AllowFileDiscovery: perm.AllowFileDiscovery,
+				ExpirationTime:     perm.ExpirationTime,
+			},
+			SuppressNotificationEmail: true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy permissions: %w", newDriveError("failed to set permission", err))
+		}
+	}
+	return nil
+}
+
+// isInTree reports whether the file with the given fileID is the file with the given rootID or one of its descendants,
+// following all parents of the file and its ancestors.
+func isInTree(ctx context.Context, b Backend, fileID, rootID string) (bool, error) {
+	queue := []string{fileID}
+	visited := map[string]bool{fileID: true}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == rootID {
+			return true, nil
+		}
+		f, found, err := findByID(ctx, b, id)
+		if err != nil {
+			return false, err
+		}
+		if !found {
+			continue
+		}
+		if f.Id == rootID {
+			return true, nil
+		}
+		for _, parentID := range f.Parents {
+			if !visited[parentID] {
+				visited[parentID] = true
+				queue = append(queue, parentID)
+			}
+		}
+	}
+	return false, nil
+}
+
+// runConcurrently calls f with each of items, running at most n calls at the same time,
+// and returns after all calls have returned.
+func runConcurrently[T any](n int, items []T, f func(T)) {
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(item)
+		}()
+	}
+	wg.Wait()
+}
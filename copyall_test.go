@@ -0,0 +1,199 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// failingCopyBackend fails to copy the files with the given IDs.
+type failingCopyBackend struct {
+	drivefs.Backend
+	fail map[string]bool
+}
+
+func (b *failingCopyBackend) CopyFile(ctx context.Context, req drivefs.CopyFileRequest) (*drive.File, error) {
+	if b.fail[req.FileID] {
+		return nil, &googleapi.Error{Code: 500, Message: "copy failed"}
+	}
+	return b.Backend.CopyFile(ctx, req)
+}
+
+// permRequestBackend records the requests to create permissions.
+type permRequestBackend struct {
+	drivefs.Backend
+
+	mu       sync.Mutex
+	requests []drivefs.CreatePermissionRequest
+}
+
+func (b *permRequestBackend) CreatePermission(ctx context.Context, req drivefs.CreatePermissionRequest) (*drive.Permission, error) {
+	b.mu.Lock()
+	b.requests = append(b.requests, req)
+	b.mu.Unlock()
+	return b.Backend.CreatePermission(ctx, req)
+}
+
+func TestDriveFS_CopyAll(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	src, err := s.MkdirAll(mem.RootID(), "/src/sub/deep")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	srcDir := findRemote(t, s, mem.RootID(), "/src")
+	sub := findRemote(t, s, mem.RootID(), "/src/sub")
+	a := writeRemoteFile(t, s, srcDir.ID, "a.txt", "a")
+	b := writeRemoteFile(t, s, sub.ID, "b.txt", "b")
+	writeRemoteFile(t, s, src.ID, "c.txt", "c")
+	outside := writeRemoteFile(t, s, mem.RootID(), "outside.txt", "outside")
+	trashed := writeRemoteFile(t, s, srcDir.ID, "trashed.txt", "trashed")
+	if err := s.Remove(trashed.ID, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := s.Shortcut(sub.ID, "to-a", a.ID); err != nil {
+		t.Fatalf("Shortcut() error = %v", err)
+	}
+	if _, err := s.Shortcut(srcDir.ID, "to-outside", outside.ID); err != nil {
+		t.Fatalf("Shortcut() error = %v", err)
+	}
+	if _, err := s.PermSet(b.ID, drivefs.UserPermission("alice@example.com", drivefs.RoleReader)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	dst, err := s.Mkdir(mem.RootID(), "dst")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	permRequests := &permRequestBackend{Backend: mem}
+	result, err := drivefs.NewWithBackend(permRequests).CopyAll(srcDir.ID, dst.ID, "copy", drivefs.CopyOptions{Concurrency: 2, CopyPermissions: true, CopyShortcuts: true})
+	if err != nil {
+		t.Fatalf("CopyAll() error = %v", err)
+	}
+	if len(permRequests.requests) != 1 || !permRequests.requests[0].SuppressNotificationEmail {
+		t.Fatalf("CopyAll() permission requests = %+v, want one without notification email", permRequests.requests)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("CopyAll() errors = %+v, want none", result.Errors)
+	}
+	if result.Root.Name != "copy" || !result.Root.IsFolder() || result.Root.ID != findRemote(t, s, dst.ID, "/copy").ID {
+		t.Fatalf("CopyAll() root = %+v, want /dst/copy", result.Root)
+	}
+	if len(result.Mapping) != 8 {
+		t.Fatalf("CopyAll() mapping = %v, want 8 items", result.Mapping)
+	}
+	for p, want := range map[drivefs.Path]string{"/copy/a.txt": "a", "/copy/sub/b.txt": "b", "/copy/sub/deep/c.txt": "c"} {
+		f := findRemote(t, s, dst.ID, p)
+		data, err := s.ReadFile(f.ID)
+		if err != nil || string(data) != want {
+			t.Fatalf("ReadFile(%q) = %q, %v, want %q", p, data, err, want)
+		}
+	}
+	if found, err := s.FindByPath(dst.ID, "/copy/trashed.txt"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath(trashed copy) = %v, %v, want nothing", found, err)
+	}
+	copiedA := findRemote(t, s, dst.ID, "/copy/a.txt")
+	if result.Mapping[a.ID] != copiedA.ID {
+		t.Fatalf("mapping of a.txt = %s, want %s", result.Mapping[a.ID], copiedA.ID)
+	}
+	if target := findRemote(t, s, dst.ID, "/copy/sub/to-a").ShortcutTarget; target != copiedA.ID {
+		t.Fatalf("shortcut inside the tree targets %s, want %s", target, copiedA.ID)
+	}
+	if target := findRemote(t, s, dst.ID, "/copy/to-outside").ShortcutTarget; target != outside.ID {
+		t.Fatalf("shortcut outside the tree targets %s, want %s", target, outside.ID)
+	}
+	perms, err := s.PermList(findRemote(t, s, dst.ID, "/copy/sub/b.txt").ID)
+	if err != nil {
+		t.Fatalf("PermList() error = %v", err)
+	}
+	var shared bool
+	for _, perm := range perms {
+		if user, ok := perm.Grantee().(drivefs.GranteeUser); ok && user.Email == "alice@example.com" && perm.Role() == drivefs.RoleReader {
+			shared = true
+		}
+	}
+	if !shared {
+		t.Fatalf("permissions of copied b.txt = %+v, want alice as reader", perms)
+	}
+
+	t.Run("without shortcuts", func(t *testing.T) {
+		result, err := s.CopyAll(srcDir.ID, dst.ID, "", drivefs.CopyOptions{})
+		if err != nil {
+			t.Fatalf("CopyAll() error = %v", err)
+		}
+		if result.Root.Name != "src" || len(result.Mapping) != 6 {
+			t.Fatalf("CopyAll() = %+v, want src with 6 items", result)
+		}
+		if found, err := s.FindByPath(dst.ID, "/src/to-outside"); err != nil || len(found) != 0 {
+			t.Fatalf("FindByPath(shortcut copy) = %v, %v, want nothing", found, err)
+		}
+	})
+
+	t.Run("file", func(t *testing.T) {
+		result, err := s.CopyAll(a.ID, dst.ID, "a-copy.txt", drivefs.CopyOptions{})
+		if err != nil {
+			t.Fatalf("CopyAll() error = %v", err)
+		}
+		if result.Root.Name != "a-copy.txt" || result.Mapping[a.ID] != result.Root.ID {
+			t.Fatalf("CopyAll() = %+v, want a copy of a.txt", result)
+		}
+	})
+
+	t.Run("into itself", func(t *testing.T) {
+		for _, dstID := range []drivefs.FileID{srcDir.ID, src.ID} {
+			if _, err := s.CopyAll(srcDir.ID, dstID, "copy", drivefs.CopyOptions{}); !errors.Is(err, drivefs.ErrInvalidDestination) {
+				t.Fatalf("CopyAll() into %s error = %v, want ErrInvalidDestination", dstID, err)
+			}
+		}
+		if found, err := s.FindByPath(srcDir.ID, "/copy"); err != nil || len(found) != 0 {
+			t.Fatalf("FindByPath() = %v, %v, want nothing copied", found, err)
+		}
+		// A file may be copied into its own directory.
+		if _, err := s.CopyAll(a.ID, srcDir.ID, "a2.txt", drivefs.CopyOptions{}); err != nil {
+			t.Fatalf("CopyAll() of a file into its directory error = %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := s.CopyAll("unknown", dst.ID, "", drivefs.CopyOptions{}); !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("CopyAll() error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDriveFS_CopyAll_PartialFailure(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	src, err := s.Mkdir(mem.RootID(), "src")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	ok := writeRemoteFile(t, s, src.ID, "ok.txt", "ok")
+	broken := writeRemoteFile(t, s, src.ID, "broken.txt", "broken")
+
+	s = drivefs.NewWithBackend(&failingCopyBackend{Backend: mem, fail: map[string]bool{string(broken.ID): true}})
+	result, err := s.CopyAll(src.ID, mem.RootID(), "dst", drivefs.CopyOptions{})
+	if err != nil {
+		t.Fatalf("CopyAll() error = %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].SourceID != broken.ID || result.Errors[0].Path != "/broken.txt" ||
+		!errors.Is(result.Errors[0].Err, drivefs.ErrDriveError) {
+		t.Fatalf("CopyAll() errors = %+v, want a drive error for /broken.txt", result.Errors)
+	}
+	if _, copied := result.Mapping[broken.ID]; copied {
+		t.Fatalf("CopyAll() mapping = %v, want broken.txt missing", result.Mapping)
+	}
+	if result.Mapping[ok.ID] != findRemote(t, s, mem.RootID(), "/dst/ok.txt").ID {
+		t.Fatalf("CopyAll() mapping = %v, want ok.txt copied", result.Mapping)
+	}
+
+	if _, err := s.CopyAll(broken.ID, mem.RootID(), "", drivefs.CopyOptions{}); !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("CopyAll() of the failing file error = %v, want ErrDriveError", err)
+	}
+}
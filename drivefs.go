@@ -20,21 +20,33 @@ import (
 )
 
 // DriveFS provides file system-like operations for Google Drive.
-// It wraps a drive.Service and provides high-level methods for managing files and directories.
+// It wraps a Backend, usually a drive.Service, and provides high-level methods for managing files and directories.
 type DriveFS struct {
-	service *drive.Service
+	backend Backend
+	// cache caches the lookups to resolve paths if not nil.
+	cache *pathCache
 }
 
 // New creates a new DriveFS instance with the given drive.Service.
 // The service should be properly authenticated before being passed to this function.
 func New(service *drive.Service) *DriveFS {
-	return &DriveFS{service: service}
+	return NewWithBackend(NewServiceBackend(service))
+}
+
+// NewWithBackend creates a new DriveFS instance that performs all operations through the given Backend.
+func NewWithBackend(backend Backend) *DriveFS {
+	return &DriveFS{backend: backend}
 }
 
 // PermList lists all permissions for the file or directory with the given fileID.
 // Returns a slice of Permission objects representing the access permissions.
 func (s *DriveFS) PermList(fileID FileID) (permissions []Permission, err error) {
-	perms, err := listPermissions(s.service, string(fileID))
+	return s.PermListContext(context.Background(), fileID)
+}
+
+// PermListContext is like PermList but uses ctx for all Google Drive API calls.
+func (s *DriveFS) PermListContext(ctx context.Context, fileID FileID) (permissions []Permission, err error) {
+	perms, err := listPermissions(ctx, s.backend, string(fileID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to set permissions: %w", err)
 	}
@@ -42,77 +54,67 @@ func (s *DriveFS) PermList(fileID FileID) (permissions []Permission, err error)
 }
 
 // PermSet sets a permission for the file or directory with the given fileID.
-// If a permission for the same grantee already exists, it will be updated.
-// Otherwise, a new permission will be created.
+// If a permission for the same grantee already exists on the file or directory, it will be updated,
+// including its expiration time, which is removed unless the permission has been created by WithExpiration.
+// Otherwise, a new permission will be created, also if the grantee only inherits a permission.
 // Returns all permissions after the operation.
 func (s *DriveFS) PermSet(fileID FileID, permission Permission) (permissions []Permission, err error) {
-	perms, err := listPermissions(s.service, string(fileID))
+	return s.PermSetContext(context.Background(), fileID, permission)
+}
+
+// PermSetContext is like PermSet but uses ctx for all Google Drive API calls.
+func (s *DriveFS) PermSetContext(ctx context.Context, fileID FileID, permission Permission) (permissions []Permission, err error) {
+	return s.PermSetWithOptionsContext(ctx, fileID, permission, PermSetOptions{})
+}
+
+// PermSetOptions configures how PermSetWithOptions notifies the grantee of a new permission.
+type PermSetOptions struct {
+	// SuppressNotificationEmail prevents Google Drive from sending a notification email
+	// to the user or group granted a new permission, which it does by default.
+	SuppressNotificationEmail bool
+
+	// EmailMessage, if not empty, is a plain text message included in the notification email.
+	EmailMessage string
+}
+
+// PermSetWithOptions is like PermSet but notifies the grantee of a new permission as configured by opts.
+// Notification emails are only sent when a permission is created for a user or group.
+func (s *DriveFS) PermSetWithOptions(fileID FileID, permission Permission, opts PermSetOptions) (permissions []Permission, err error) {
+	return s.PermSetWithOptionsContext(context.Background(), fileID, permission, opts)
+}
+
+// PermSetWithOptionsContext is like PermSetWithOptions but uses ctx for all Google Drive API calls.
+func (s *DriveFS) PermSetWithOptionsContext(ctx context.Context, fileID FileID, permission Permission, opts PermSetOptions) (permissions []Permission, err error) {
+	perms, err := listPermissions(ctx, s.backend, string(fileID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to set permissions: %w", err)
 	}
 
-	var updated bool
-	for _, perm := range perms {
-		if granteeMatch(perm, permission.Grantee()) {
-			updated = true
-			perm.AllowFileDiscovery = permission.AllowFileDiscovery()
-			perm.Role = string(permission.Role())
-			err := updatePermissions(s.service, string(fileID), perm)
-			if err != nil {
-				return nil, newDriveError("failed to set permission", err)
-			}
-		}
-	}
-
-	if !updated {
-		var email, domain, granteeType string
-		switch grantee := permission.Grantee().(type) {
-		case GranteeUser:
-			email, granteeType = grantee.Email, granteeTypeUser
-		case GranteeGroup:
-			email, granteeType = grantee.Email, granteeTypeGroup
-		case GranteeDomain:
-			domain, granteeType = grantee.Domain, granteeTypeDomain
-		case GranteeAnyone:
-			granteeType = granteeTypeAnyone
-		}
-		perm, err := createPermissions(s.service, string(fileID), &drive.Permission{
-			AllowFileDiscovery: permission.AllowFileDiscovery(),
-			EmailAddress:       email,
-			Domain:             domain,
-			Id:                 string(permission.ID()),
-			Role:               string(permission.Role()),
-			Type:               granteeType,
-		})
-		if err != nil {
-			return nil, newDriveError("failed to set permission", err)
-		}
-		perms = append(perms, perm)
+	perms, err = setPermissionIn(ctx, s.backend, string(fileID), perms, permission, opts)
+	if err != nil {
+		return nil, err
 	}
-
 	return newPermissions(perms), nil
 }
 
 // PermDel deletes all permissions matching the given grantee for the file or directory with the given fileID.
+// Permissions that are only inherited cannot be deleted from the file or directory and are kept.
 // Returns all remaining permissions after the operation.
 func (s *DriveFS) PermDel(fileID FileID, grantee Grantee) (permissions []Permission, err error) {
-	perms, err := listPermissions(s.service, string(fileID))
+	return s.PermDelContext(context.Background(), fileID, grantee)
+}
+
+// PermDelContext is like PermDel but uses ctx for all Google Drive API calls.
+func (s *DriveFS) PermDelContext(ctx context.Context, fileID FileID, grantee Grantee) (permissions []Permission, err error) {
+	perms, err := listPermissions(ctx, s.backend, string(fileID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to delete permissions: %w", err)
 	}
 
-	remainedPermissions := []*drive.Permission{}
-	for _, perm := range perms {
-		if granteeMatch(perm, grantee) {
-			err := deletePermissions(s.service, string(fileID), perm.Id)
-			if err != nil {
-				return nil, newDriveError("failed to delete permission", err)
-			}
-		} else {
-			remainedPermissions = append(remainedPermissions, perm)
-		}
+	perms, err = deletePermissionsIn(ctx, s.backend, string(fileID), perms, grantee)
+	if err != nil {
+		return nil, err
 	}
-
 	return newPermissions(perms), nil
 }
 
@@ -121,12 +123,17 @@ func (s *DriveFS) PermDel(fileID FileID, grantee Grantee) (permissions []Permiss
 // Returns the FileInfo of the final directory in the path.
 // If two or more directories with the same name exist at any level, returns ErrAlreadyExists.
 func (s *DriveFS) MkdirAll(rootID FileID, path Path) (info FileInfo, err error) {
+	return s.MkdirAllContext(context.Background(), rootID, path)
+}
+
+// MkdirAllContext is like MkdirAll but uses ctx for all Google Drive API calls.
+func (s *DriveFS) MkdirAllContext(ctx context.Context, rootID FileID, path Path) (info FileInfo, err error) {
 	parts, err := validateAndSplitPath(string(path))
 	if err != nil {
 		return FileInfo{}, fmt.Errorf("path validation failed: %w", err)
 	}
 	currentID := string(rootID)
-	file, found, err := findByID(s.service, currentID)
+	file, found, err := s.getFile(ctx, currentID)
 	if err != nil {
 		return FileInfo{}, err
 	}
@@ -134,7 +141,7 @@ func (s *DriveFS) MkdirAll(rootID FileID, path Path) (info FileInfo, err error)
 		return FileInfo{}, fmt.Errorf("root not found: %s: %w", currentID, ErrNotFound)
 	}
 	for _, p := range parts {
-		files, err := findAllByNameIn(s.service, currentID, p)
+		files, err := s.findByName(ctx, currentID, p)
 		if err != nil {
 			return FileInfo{}, fmt.Errorf("failed to find directory '%s' in '%s': %w", p, currentID, err)
 		}
@@ -146,7 +153,7 @@ func (s *DriveFS) MkdirAll(rootID FileID, path Path) (info FileInfo, err error)
 			currentID = file.Id
 			continue
 		}
-		file, err = createDirIn(s.service, currentID, p)
+		file, err = createDirIn(ctx, s.backend, currentID, p)
 		if err != nil {
 			return FileInfo{}, fmt.Errorf("failed to create directory '%s' in '%s': %w", p, currentID, err)
 		}
@@ -158,7 +165,12 @@ func (s *DriveFS) MkdirAll(rootID FileID, path Path) (info FileInfo, err error)
 // Mkdir creates a single directory with the given name in the specified parent directory.
 // Returns the FileInfo of the created directory.
 func (s *DriveFS) Mkdir(parentID FileID, name string) (info FileInfo, err error) {
-	f, err := createDirIn(s.service, string(parentID), name)
+	return s.MkdirContext(context.Background(), parentID, name)
+}
+
+// MkdirContext is like Mkdir but uses ctx for all Google Drive API calls.
+func (s *DriveFS) MkdirContext(ctx context.Context, parentID FileID, name string) (info FileInfo, err error) {
+	f, err := createDirIn(ctx, s.backend, string(parentID), name)
 	if err != nil {
 		return FileInfo{}, fmt.Errorf("failed to create directory: %w", err)
 	}
@@ -167,16 +179,26 @@ func (s *DriveFS) Mkdir(parentID FileID, name string) (info FileInfo, err error)
 
 // ReadFile reads the entire contents of the file with the given fileID.
 // Returns the file data as a byte slice.
-// Returns ErrNotReadable for Google Apps files (Docs, Sheets, etc.) that cannot be directly downloaded.
+// Returns ErrNotReadable for Google Apps files (Docs, Sheets, etc.) that cannot be directly downloaded; use Export for them.
 func (s *DriveFS) ReadFile(fileID FileID) (data []byte, err error) {
-	return downloadFile(s.service, string(fileID))
+	return s.ReadFileContext(context.Background(), fileID)
+}
+
+// ReadFileContext is like ReadFile but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ReadFileContext(ctx context.Context, fileID FileID) (data []byte, err error) {
+	return downloadFile(ctx, s.backend, string(fileID))
 }
 
 // Remove deletes the file or directory with the given fileID.
 // For directories, only empty directories can be removed; otherwise returns ErrNotRemovable.
 // If moveToTrash is true, the file is moved to trash; otherwise it is permanently deleted.
 func (s *DriveFS) Remove(fileID FileID, moveToTrash bool) (err error) {
-	file, found, err := findByID(s.service, string(fileID))
+	return s.RemoveContext(context.Background(), fileID, moveToTrash)
+}
+
+// RemoveContext is like Remove but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RemoveContext(ctx context.Context, fileID FileID, moveToTrash bool) (err error) {
+	file, found, err := findByID(ctx, s.backend, string(fileID))
 	if err != nil {
 		return fmt.Errorf("failed to find file: %w", err)
 	}
@@ -184,7 +206,7 @@ func (s *DriveFS) Remove(fileID FileID, moveToTrash bool) (err error) {
 		return nil
 	}
 	if file.MimeType == mimeTypeGoogleAppFolder {
-		exists, err := existsIn(s.service, string(fileID))
+		exists, err := existsIn(ctx, s.backend, string(fileID))
 		if err != nil {
 			return fmt.Errorf("failed to check if directory is empty: %w", err)
 		}
@@ -193,24 +215,28 @@ func (s *DriveFS) Remove(fileID FileID, moveToTrash bool) (err error) {
 		}
 	}
 
-	return s.RemoveAll(fileID, moveToTrash)
+	return s.RemoveAllContext(ctx, fileID, moveToTrash)
 }
 
 // RemoveAll deletes the file or directory with the given fileID, including all children if it's a directory.
 // If moveToTrash is true, the file is moved to trash; otherwise it is permanently deleted.
 func (s *DriveFS) RemoveAll(fileID FileID, moveToTrash bool) (err error) {
+	return s.RemoveAllContext(context.Background(), fileID, moveToTrash)
+}
+
+// RemoveAllContext is like RemoveAll but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RemoveAllContext(ctx context.Context, fileID FileID, moveToTrash bool) (err error) {
 	if moveToTrash {
-		_, err := s.service.Files.Update(string(fileID), &drive.File{Trashed: true}).
-			SupportsAllDrives(true).
-			Do()
+		_, err := s.backend.UpdateFile(ctx, UpdateFileRequest{
+			FileID: string(fileID),
+			File:   &drive.File{Trashed: true},
+		})
 		if err != nil {
 			return newDriveError("failed to move file to trash", err)
 		}
 		return nil
 	} else {
-		err := s.service.Files.Delete(string(fileID)).
-			SupportsAllDrives(true).
-			Do()
+		err := s.backend.DeleteFile(ctx, string(fileID))
 		if err != nil {
 			return newDriveError("failed to delete file", err)
 		}
@@ -221,18 +247,24 @@ func (s *DriveFS) RemoveAll(fileID FileID, moveToTrash bool) (err error) {
 // Move moves the file or directory with the given fileID to a new parent directory.
 // Returns ErrNotFound if the file does not exist.
 func (s *DriveFS) Move(fileID, newParentID FileID) (err error) {
-	f, found, err := findByID(s.service, string(fileID))
+	return s.MoveContext(context.Background(), fileID, newParentID)
+}
+
+// MoveContext is like Move but uses ctx for all Google Drive API calls.
+func (s *DriveFS) MoveContext(ctx context.Context, fileID, newParentID FileID) (err error) {
+	f, found, err := findByID(ctx, s.backend, string(fileID))
 	if err != nil {
 		return fmt.Errorf("failed to find file: %w", err)
 	}
 	if !found {
 		return fmt.Errorf("file '%s' not found: %w", fileID, ErrNotFound)
 	}
-	_, err = s.service.Files.Update(string(fileID), &drive.File{}).
-		SupportsAllDrives(true).
-		RemoveParents(strings.Join(f.Parents, ",")).
-		AddParents(string(newParentID)).
-		Do()
+	_, err = s.backend.UpdateFile(ctx, UpdateFileRequest{
+		FileID:        string(fileID),
+		File:          &drive.File{},
+		AddParents:    []string{string(newParentID)},
+		RemoveParents: f.Parents,
+	})
 	if err != nil {
 		return newDriveError("failed to move file", err)
 	}
@@ -241,13 +273,43 @@ func (s *DriveFS) Move(fileID, newParentID FileID) (err error) {
 
 // WriteFile writes data to the file with the given fileID, overwriting any existing content.
 func (s *DriveFS) WriteFile(fileID FileID, data []byte) (err error) {
-	return uploadFile(s.service, string(fileID), data)
+	return s.WriteFileContext(context.Background(), fileID, data)
+}
+
+// WriteFileContext is like WriteFile but uses ctx for all Google Drive API calls.
+func (s *DriveFS) WriteFileContext(ctx context.Context, fileID FileID, data []byte) (err error) {
+	return uploadFile(ctx, s.backend, string(fileID), data)
 }
 
 // ReadDir reads the directory with the given fileID and returns a slice of FileInfo
 // for all files and subdirectories within it. Does not include trashed items.
 func (s *DriveFS) ReadDir(fileID FileID) (children []FileInfo, err error) {
-	l, err := findAllIn(s.service, string(fileID))
+	return s.ReadDirContext(context.Background(), fileID)
+}
+
+// ReadDirContext is like ReadDir but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ReadDirContext(ctx context.Context, fileID FileID) (children []FileInfo, err error) {
+	return s.ReadDirWithOptionsContext(ctx, fileID, ListOptions{})
+}
+
+// ListOptions configures which files are listed by ReadDirWithOptions and WalkWithOptions.
+type ListOptions struct {
+	// IncludeTrashed includes trashed files and directories, which can be told apart by FileInfo.Trashed.
+	IncludeTrashed bool
+
+	// Query, if not zero, restricts the listed files to those matching it.
+	// WalkWithOptions does not descend into the directories that do not match it.
+	Query Query
+}
+
+// ReadDirWithOptions is like ReadDir but lists the files selected by opts.
+func (s *DriveFS) ReadDirWithOptions(fileID FileID, opts ListOptions) (children []FileInfo, err error) {
+	return s.ReadDirWithOptionsContext(context.Background(), fileID, opts)
+}
+
+// ReadDirWithOptionsContext is like ReadDirWithOptions but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ReadDirWithOptionsContext(ctx context.Context, fileID FileID, opts ListOptions) (children []FileInfo, err error) {
+	l, err := listChildren(ctx, s.backend, string(fileID), opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list directory contents: %w", err)
 	}
@@ -264,7 +326,12 @@ func (s *DriveFS) ReadDir(fileID FileID) (children []FileInfo, err error) {
 // Create creates a new empty file with the given name in the specified parent directory.
 // Returns the FileInfo of the created file.
 func (s *DriveFS) Create(parentID FileID, name string) (info FileInfo, err error) {
-	f, err := createFileIn(s.service, string(parentID), name)
+	return s.CreateContext(context.Background(), parentID, name)
+}
+
+// CreateContext is like Create but uses ctx for all Google Drive API calls.
+func (s *DriveFS) CreateContext(ctx context.Context, parentID FileID, name string) (info FileInfo, err error) {
+	f, err := createFileIn(ctx, s.backend, string(parentID), name)
 	if err != nil {
 		return FileInfo{}, fmt.Errorf("failed to create file: %w", err)
 	}
@@ -275,7 +342,12 @@ func (s *DriveFS) Create(parentID FileID, name string) (info FileInfo, err error
 // The shortcut is created in the specified parent directory.
 // Returns the FileInfo of the created shortcut.
 func (s *DriveFS) Shortcut(parentID FileID, name string, targetID FileID) (info FileInfo, err error) {
-	f, err := createShortcutIn(s.service, string(parentID), name, string(targetID))
+	return s.ShortcutContext(context.Background(), parentID, name, targetID)
+}
+
+// ShortcutContext is like Shortcut but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ShortcutContext(ctx context.Context, parentID FileID, name string, targetID FileID) (info FileInfo, err error) {
+	f, err := createShortcutIn(ctx, s.backend, string(parentID), name, string(targetID))
 	if err != nil {
 		return FileInfo{}, fmt.Errorf("failed to create shortcut: %w", err)
 	}
@@ -285,7 +357,12 @@ func (s *DriveFS) Shortcut(parentID FileID, name string, targetID FileID) (info
 // Info retrieves metadata for the file or directory with the given fileID.
 // Returns ErrNotFound if the file does not exist.
 func (s *DriveFS) Info(fileID FileID) (info FileInfo, err error) {
-	f, found, err := findByID(s.service, string(fileID))
+	return s.InfoContext(context.Background(), fileID)
+}
+
+// InfoContext is like Info but uses ctx for all Google Drive API calls.
+func (s *DriveFS) InfoContext(ctx context.Context, fileID FileID) (info FileInfo, err error) {
+	f, found, err := findByID(ctx, s.backend, string(fileID))
 	if err != nil {
 		return FileInfo{}, fmt.Errorf("failed to get file info '%s': %w", fileID, err)
 	}
@@ -299,12 +376,18 @@ func (s *DriveFS) Info(fileID FileID) (info FileInfo, err error) {
 // The copy is placed in the specified parent directory with the given name.
 // Returns the FileInfo of the copied file.
 func (s *DriveFS) Copy(fileID, newParentID FileID, newName string) (info FileInfo, err error) {
-	f, err := s.service.Files.Copy(string(fileID), &drive.File{
-		Name:    newName,
-		Parents: []string{string(newParentID)},
-	}).
-		SupportsAllDrives(true).
-		Do()
+	return s.CopyContext(context.Background(), fileID, newParentID, newName)
+}
+
+// CopyContext is like Copy but uses ctx for all Google Drive API calls.
+func (s *DriveFS) CopyContext(ctx context.Context, fileID, newParentID FileID, newName string) (info FileInfo, err error) {
+	f, err := s.backend.CopyFile(ctx, CopyFileRequest{
+		FileID: string(fileID),
+		File: &drive.File{
+			Name:    newName,
+			Parents: []string{string(newParentID)},
+		},
+	})
 	if err != nil {
 		return FileInfo{}, newDriveError("failed to copy file", err)
 	}
@@ -314,9 +397,15 @@ func (s *DriveFS) Copy(fileID, newParentID FileID, newName string) (info FileInf
 // Rename changes the name of the file or directory with the given fileID.
 // Returns the updated FileInfo.
 func (s *DriveFS) Rename(fileID FileID, newName string) (info FileInfo, err error) {
-	f, err := s.service.Files.Update(string(fileID), &drive.File{Name: newName}).
-		SupportsAllDrives(true).
-		Do()
+	return s.RenameContext(context.Background(), fileID, newName)
+}
+
+// RenameContext is like Rename but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RenameContext(ctx context.Context, fileID FileID, newName string) (info FileInfo, err error) {
+	f, err := s.backend.UpdateFile(ctx, UpdateFileRequest{
+		FileID: string(fileID),
+		File:   &drive.File{Name: newName},
+	})
 	if err != nil {
 		return FileInfo{}, newDriveError("failed to copy file", err)
 	}
@@ -327,7 +416,12 @@ func (s *DriveFS) Rename(fileID FileID, newName string) (info FileInfo, err erro
 // The query uses Google Drive's query syntax.
 // See https://developers.google.com/drive/api/guides/search-files for query syntax.
 func (s *DriveFS) Query(query string) (results []FileInfo, err error) {
-	files, err := queryFileInfo(s.service, query)
+	return s.QueryContext(context.Background(), query)
+}
+
+// QueryContext is like Query but uses ctx for all Google Drive API calls.
+func (s *DriveFS) QueryContext(ctx context.Context, query string) (results []FileInfo, err error) {
+	files, err := queryFileInfo(ctx, s.backend, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query files: %w", err)
 	}
@@ -345,18 +439,23 @@ func (s *DriveFS) Query(query string) (results []FileInfo, err error) {
 // Returns all files matching the path (multiple results if duplicates exist at any level).
 // The path must be absolute (starting with '/').
 func (s *DriveFS) FindByPath(rootID FileID, path Path) (info []FileInfo, err error) {
+	return s.FindByPathContext(context.Background(), rootID, path)
+}
+
+// FindByPathContext is like FindByPath but uses ctx for all Google Drive API calls.
+func (s *DriveFS) FindByPathContext(ctx context.Context, rootID FileID, path Path) (info []FileInfo, err error) {
 	parts, err := validateAndSplitPath(string(path))
 	if err != nil {
 		return nil, fmt.Errorf("path validation failed: %w", err)
 	}
-	file, found, err := findByID(s.service, string(rootID))
+	file, found, err := s.getFile(ctx, string(rootID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to find root directory: %w", err)
 	}
 	if !found {
 		return nil, nil
 	}
-	err = dfsFindByPath(s.service, file, 0, parts, func(i FileInfo) error {
+	err = dfsFindByPath(ctx, s, file, 0, parts, func(i FileInfo) error {
 		info = append(info, i)
 		return nil
 	})
@@ -370,7 +469,15 @@ func (s *DriveFS) FindByPath(rootID FileID, path Path) (info []FileInfo, err err
 // The returned path is a slash-separated string (e.g., "/folder/subfolder/file").
 // Returns ErrMultiParentsNotSupported if the file has multiple parents.
 func (s *DriveFS) ResolvePath(fileID FileID) (path Path, err error) {
-	parts, err := resolvePathParts(s, fileID)
+	return s.ResolvePathContext(context.Background(), fileID)
+}
+
+// ResolvePathContext is like ResolvePath but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ResolvePathContext(ctx context.Context, fileID FileID) (path Path, err error) {
+	parts, err := resolvePathParts(ctx, s, fileID)
+	if err != nil {
+		return "", err
+	}
 	return Path("/" + strings.Join(parts, "/")), nil
 }
 
@@ -378,20 +485,38 @@ func (s *DriveFS) ResolvePath(fileID FileID) (path Path, err error) {
 // For each file or directory (including the root), it calls the provided function with
 // the relative path and FileInfo. If the function returns an error, walking stops.
 func (s *DriveFS) Walk(rootID FileID, f func(Path, FileInfo) error) (err error) {
-	file, found, err := findByID(s.service, string(rootID))
+	return s.WalkContext(context.Background(), rootID, f)
+}
+
+// WalkContext is like Walk but uses ctx for all Google Drive API calls.
+// Walking stops with the context's error once ctx is done.
+func (s *DriveFS) WalkContext(ctx context.Context, rootID FileID, f func(Path, FileInfo) error) (err error) {
+	return s.WalkWithOptionsContext(ctx, rootID, ListOptions{}, f)
+}
+
+// WalkWithOptions is like Walk but visits the files selected by opts.
+// If trashed items are included, the descendants of trashed directories are also visited.
+func (s *DriveFS) WalkWithOptions(rootID FileID, opts ListOptions, f func(Path, FileInfo) error) (err error) {
+	return s.WalkWithOptionsContext(context.Background(), rootID, opts, f)
+}
+
+// WalkWithOptionsContext is like WalkWithOptions but uses ctx for all Google Drive API calls.
+// Walking stops with the context's error once ctx is done.
+func (s *DriveFS) WalkWithOptionsContext(ctx context.Context, rootID FileID, opts ListOptions, f func(Path, FileInfo) error) (err error) {
+	file, found, err := findByID(ctx, s.backend, string(rootID))
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 	if !found {
 		return fmt.Errorf("file not found: %s: %w", rootID, ErrNotFound)
 	}
-	return walk(s, []string{}, file, f)
+	return walk(ctx, s, []string{}, file, opts, f)
 }
 
-func resolvePathParts(s *DriveFS, fileID FileID) (parts []string, err error) {
+func resolvePathParts(ctx context.Context, s *DriveFS, fileID FileID) (parts []string, err error) {
 	currentID := string(fileID)
 	for {
-		f, found, err := findByID(s.service, currentID)
+		f, found, err := s.getFile(ctx, currentID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get file info: %w", err)
 		}
@@ -411,23 +536,22 @@ func resolvePathParts(s *DriveFS, fileID FileID) (parts []string, err error) {
 	return parts, nil
 }
 
-func queryFileInfo(s *drive.Service, query string) (results []*drive.File, err error) {
-	err = s.Files.List().
-		SupportsAllDrives(true).
-		IncludeItemsFromAllDrives(true).
-		Q(query).
-		Fields(driveFilesFields).
-		Pages(context.Background(), func(list *drive.FileList) error {
-			results = append(results, list.Files...)
-			return nil
-		})
-	if err != nil {
-		return nil, newDriveError("failed to query files", err)
+func queryFileInfo(ctx context.Context, b Backend, query string) (results []*drive.File, err error) {
+	req := ListFilesRequest{Query: query}
+	for {
+		list, err := b.ListFiles(ctx, req)
+		if err != nil {
+			return nil, newDriveError("failed to query files", err)
+		}
+		results = append(results, list.Files...)
+		if list.NextPageToken == "" {
+			return results, nil
+		}
+		req.PageToken = list.NextPageToken
 	}
-	return results, nil
 }
 
-func dfsFindByPath(s *drive.Service, file *drive.File, partIndex int, parts []string, onPathMatch func(FileInfo) error) (err error) {
+func dfsFindByPath(ctx context.Context, s *DriveFS, file *drive.File, partIndex int, parts []string, onPathMatch func(FileInfo) error) (err error) {
 	info, err := newFileInfo(file)
 	if err != nil {
 		return fmt.Errorf("failed to create FileInfo: %w", err)
@@ -438,19 +562,22 @@ func dfsFindByPath(s *drive.Service, file *drive.File, partIndex int, parts []st
 	if file.MimeType != mimeTypeGoogleAppFolder {
 		return nil
 	}
-	files, err := findAllByNameIn(s, file.Id, parts[partIndex])
+	files, err := s.findByName(ctx, file.Id, parts[partIndex])
 	if err != nil {
 		return fmt.Errorf("failed to list files: %w", err)
 	}
 	for _, file := range files {
-		if err := dfsFindByPath(s, file, partIndex+1, parts, onPathMatch); err != nil {
+		if err := dfsFindByPath(ctx, s, file, partIndex+1, parts, onPathMatch); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func walk(s *DriveFS, path []string, file *drive.File, f func(Path, FileInfo) error) (err error) {
+func walk(ctx context.Context, s *DriveFS, path []string, file *drive.File, opts ListOptions, f func(Path, FileInfo) error) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	info, err := newFileInfo(file)
 	if err != nil {
 		return fmt.Errorf("failed to create FileInfo: %w", err)
@@ -461,12 +588,12 @@ func walk(s *DriveFS, path []string, file *drive.File, f func(Path, FileInfo) er
 	if file.MimeType != mimeTypeGoogleAppFolder {
 		return nil
 	}
-	files, err := findAllIn(s.service, file.Id)
+	files, err := listChildren(ctx, s.backend, file.Id, opts)
 	if err != nil {
 		return fmt.Errorf("failed to list files: %w", err)
 	}
 	for _, file := range files {
-		if err := walk(s, append(append([]string{}, path...), file.Name), file, f); err != nil {
+		if err := walk(ctx, s, append(append([]string{}, path...), file.Name), file, opts, f); err != nil {
 			return err
 		}
 	}
@@ -495,16 +622,23 @@ func validateAndSplitPath(path string) (parts []string, err error) {
 }
 
 func escapeQuery(s string) string {
-	s = strings.ReplaceAll(s, "'", `\'`)
 	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "'", `\'`)
 	return s
 }
 
 const (
-	driveFileFields        = "parents,id,name,mimeType,size,modifiedTime,shortcutDetails,webViewLink"
-	driveFilesFields       = "nextPageToken,files(parents,id,name,mimeType,size,modifiedTime,shortcutDetails,webViewLink)"
-	drivePermissionFields  = "id,type,emailAddress,domain,role,allowFileDiscovery"
-	drivePermissionsFields = "nextPageToken,permissions(id,type,emailAddress,domain,role,allowFileDiscovery)"
+	driveFileFields        = "parents,id,driveId,name,mimeType,size,md5Checksum,modifiedTime,trashed,explicitlyTrashed,shortcutDetails,webViewLink,exportLinks"
+	driveFilesFields       = "nextPageToken,files(parents,id,driveId,name,mimeType,size,md5Checksum,modifiedTime,trashed,explicitlyTrashed,shortcutDetails,webViewLink,exportLinks)"
+	drivePermissionFields  = "id,type,emailAddress,domain,role,allowFileDiscovery,expirationTime,pendingOwner,permissionDetails(permissionType,role,inherited,inheritedFrom)"
+	drivePermissionsFields = "nextPageToken,permissions(id,type,emailAddress,domain,role,allowFileDiscovery,expirationTime,pendingOwner,permissionDetails(permissionType,role,inherited,inheritedFrom))"
+	driveRevisionFields    = "id,mimeType,modifiedTime,size,md5Checksum,keepForever,lastModifyingUser(displayName,emailAddress)"
+	driveRevisionsFields   = "nextPageToken,revisions(id,mimeType,modifiedTime,size,md5Checksum,keepForever,lastModifyingUser(displayName,emailAddress))"
+	driveChangesFields     = "nextPageToken,newStartPageToken,changes(changeType,fileId,removed,time,driveId," +
+		"file(parents,id,name,mimeType,size,md5Checksum,createdTime,modifiedTime,trashed,shortcutDetails,webViewLink,exportLinks," +
+		"permissions(id,type,emailAddress,domain,role,allowFileDiscovery)))"
+	driveDriveFields  = "id,name,hidden,createdTime,restrictions(domainUsersOnly,copyRequiresWriterPermission,driveMembersOnly)"
+	driveDrivesFields = "nextPageToken,drives(id,name,hidden,createdTime,restrictions(domainUsersOnly,copyRequiresWriterPermission,driveMembersOnly))"
 )
 
 func newFileInfo(f *drive.File) (FileInfo, error) {
@@ -519,36 +653,30 @@ func newFileInfo(f *drive.File) (FileInfo, error) {
 		Size:           f.Size,
 		Mime:           f.MimeType,
 		ModTime:        modTime,
+		MD5Checksum:    f.Md5Checksum,
+		Trashed:        f.Trashed,
 		ShortcutTarget: shortcutTarget,
 		WebViewLink:    f.WebViewLink,
+		ExportFormats:  exportFormatsOf(f.ExportLinks, f.MimeType),
 	}, nil
 }
 
-func findAllByNameIn(s *drive.Service, parentID string, name string) (files []*drive.File, err error) {
-	q := fmt.Sprintf("name = '%s' and '%s' in parents and trashed = false", escapeQuery(name), parentID)
-	return queryFileInfo(s, q)
+func findAllByNameIn(ctx context.Context, b Backend, parentID string, name string) (files []*drive.File, err error) {
+	q := And(NameEquals(name), InParents(FileID(parentID)), Trashed(false))
+	return queryFileInfo(ctx, b, q.String())
 }
 
-func existsIn(s *drive.Service, parentID string) (found bool, err error) {
-	q := fmt.Sprintf("'%s' in parents and trashed = false", parentID)
-	res, err := s.Files.List().
-		SupportsAllDrives(true).
-		IncludeItemsFromAllDrives(true).
-		Q(q).
-		Fields(driveFileFields).
-		PageSize(1).
-		Do()
+func existsIn(ctx context.Context, b Backend, parentID string) (found bool, err error) {
+	q := And(InParents(FileID(parentID)), Trashed(false))
+	res, err := b.ListFiles(ctx, ListFilesRequest{Query: q.String(), PageSize: 1})
 	if err != nil {
 		return false, newDriveError("failed to list files", err)
 	}
 	return len(res.Files) != 0, nil
 }
 
-func findByID(s *drive.Service, fileID string) (file *drive.File, found bool, err error) {
-	file, err = s.Files.Get(fileID).
-		SupportsAllDrives(true).
-		Fields(driveFileFields).
-		Do()
+func findByID(ctx context.Context, b Backend, fileID string) (file *drive.File, found bool, err error) {
+	file, err = b.GetFile(ctx, fileID)
 	if err != nil {
 		var gErr *googleapi.Error
 		if errors.As(err, &gErr) {
@@ -561,60 +689,63 @@ func findByID(s *drive.Service, fileID string) (file *drive.File, found bool, er
 	return file, true, nil
 }
 
-func findAllIn(s *drive.Service, parentID string) (files []*drive.File, err error) {
-	q := fmt.Sprintf("'%s' in parents and trashed = false", parentID)
-	return queryFileInfo(s, q)
+func findAllIn(ctx context.Context, b Backend, parentID string) (files []*drive.File, err error) {
+	q := And(InParents(FileID(parentID)), Trashed(false))
+	return queryFileInfo(ctx, b, q.String())
 }
 
-func createDirIn(s *drive.Service, parentID, name string) (file *drive.File, err error) {
-	file, err = s.Files.Create(&drive.File{
+// listChildren returns the children of the directory with the given parentID that are selected by opts.
+func listChildren(ctx context.Context, b Backend, parentID string, opts ListOptions) (files []*drive.File, err error) {
+	return queryFileInfo(ctx, b, childrenQuery(parentID, opts).String())
+}
+
+// childrenQuery returns the query for the children of the directory with the given parentID that are selected by opts.
+func childrenQuery(parentID string, opts ListOptions) Query {
+	q := And(InParents(FileID(parentID)), opts.Query)
+	if !opts.IncludeTrashed {
+		q = And(q, Trashed(false))
+	}
+	return q
+}
+
+func createDirIn(ctx context.Context, b Backend, parentID, name string) (file *drive.File, err error) {
+	file, err = b.CreateFile(ctx, CreateFileRequest{File: &drive.File{
 		Name:     name,
 		MimeType: mimeTypeGoogleAppFolder,
 		Parents:  []string{parentID},
-	}).
-		SupportsAllDrives(true).
-		Fields(driveFileFields).
-		Do()
+	}})
 	if err != nil {
 		return nil, newDriveError("failed to create directory", err)
 	}
 	return file, nil
 }
 
-func createFileIn(s *drive.Service, parentID, name string) (file *drive.File, err error) {
-	file, err = s.Files.Create(&drive.File{
+func createFileIn(ctx context.Context, b Backend, parentID, name string) (file *drive.File, err error) {
+	file, err = b.CreateFile(ctx, CreateFileRequest{File: &drive.File{
 		Name:    name,
 		Parents: []string{parentID},
-	}).
-		SupportsAllDrives(true).
-		Fields(driveFileFields).
-		Do()
+	}})
 	if err != nil {
 		return nil, newDriveError("failed to create file", err)
 	}
 	return file, nil
 }
 
-func createShortcutIn(s *drive.Service, parentID, name, targetID string) (file *drive.File, err error) {
-	file, err = s.Files.Create(&drive.File{
+func createShortcutIn(ctx context.Context, b Backend, parentID, name, targetID string) (file *drive.File, err error) {
+	file, err = b.CreateFile(ctx, CreateFileRequest{File: &drive.File{
 		Name:            name,
 		MimeType:        mimeTypeGoogleAppShortcut,
 		Parents:         []string{parentID},
 		ShortcutDetails: &drive.FileShortcutDetails{TargetId: targetID},
-	}).
-		SupportsAllDrives(true).
-		Fields(driveFileFields).
-		Do()
+	}})
 	if err != nil {
 		return nil, newDriveError("failed to create shortcut", err)
 	}
 	return file, nil
 }
 
-func downloadFile(s *drive.Service, fileID string) (data []byte, err error) {
-	file, err := s.Files.Get(fileID).
-		SupportsAllDrives(true).
-		Do()
+func downloadFile(ctx context.Context, b Backend, fileID string) (data []byte, err error) {
+	file, err := b.GetFile(ctx, fileID)
 	if err != nil {
 		return nil, newDriveError("failed to get file", err)
 	}
@@ -623,32 +754,31 @@ func downloadFile(s *drive.Service, fileID string) (data []byte, err error) {
 		return nil, fmt.Errorf("cannot download google-apps file: %w", ErrNotReadable)
 	}
 
-	resp, err := s.Files.Get(fileID).
-		SupportsAllDrives(true).
-		Download()
+	body, err := b.DownloadFile(ctx, DownloadFileRequest{FileID: fileID, Length: -1})
 	if err != nil {
 		return nil, newDriveError("failed to download file", err)
 	}
 	defer func() {
-		closeErr := resp.Body.Close()
+		closeErr := body.Close()
 		if closeErr != nil {
 			closeErr = newIOError("failed to close file body", closeErr)
 		}
 		err = errors.Join(err, closeErr)
 	}()
 
-	data, err = io.ReadAll(resp.Body)
+	data, err = io.ReadAll(body)
 	if err != nil {
 		return nil, newIOError("failed to read file body", err)
 	}
 	return data, nil
 }
 
-func uploadFile(s *drive.Service, fileID string, data []byte) (err error) {
-	_, err = s.Files.Update(fileID, &drive.File{}).
-		SupportsAllDrives(true).
-		Media(bytes.NewBuffer(data)).
-		Do()
+func uploadFile(ctx context.Context, b Backend, fileID string, data []byte) (err error) {
+	_, err = b.UpdateFile(ctx, UpdateFileRequest{
+		FileID: fileID,
+		File:   &drive.File{},
+		Media:  bytes.NewReader(data),
+	})
 	if err != nil {
 		return newDriveError("failed to upload file", err)
 	}
@@ -668,11 +798,24 @@ func newPermissions(perms []*drive.Permission) (permissions []Permission) {
 		case granteeTypeAnyone:
 			grantee = Anyone()
 		}
+		var details []PermissionDetail
+		for _, detail := range perm.PermissionDetails {
+			details = append(details, PermissionDetail{
+				Type:          PermissionType(detail.PermissionType),
+				Role:          Role(detail.Role),
+				Inherited:     detail.Inherited,
+				InheritedFrom: FileID(detail.InheritedFrom),
+			})
+		}
+		expirationTime, _ := time.Parse(time.RFC3339, perm.ExpirationTime)
 		permissions = append(permissions, permission{
 			grantee:            grantee,
 			role:               Role(perm.Role),
 			id:                 PermissionID(perm.Id),
 			allowFileDiscovery: perm.AllowFileDiscovery,
+			expirationTime:     expirationTime,
+			pendingOwner:       perm.PendingOwner,
+			details:            details,
 		})
 	}
 	return permissions
@@ -692,48 +835,124 @@ func granteeMatch(perm *drive.Permission, grantee Grantee) bool {
 	return false
 }
 
-func listPermissions(service *drive.Service, fileID string) ([]*drive.Permission, error) {
-	var permissions []*drive.Permission
-	err := service.Permissions.List(fileID).
-		SupportsAllDrives(true).
-		Fields(drivePermissionsFields).
-		Pages(context.Background(), func(list *drive.PermissionList) error {
-			permissions = append(permissions, list.Permissions...)
-			return nil
+// setPermissionIn updates the permissions in perms, which are those of the file with the given fileID,
+// that are set on the file for the grantee of permission, or creates permission as configured by opts if there are none.
+// Returns the permissions of the file after the operation.
+func setPermissionIn(ctx context.Context, b Backend, fileID string, perms []*drive.Permission, permission Permission, opts PermSetOptions) ([]*drive.Permission, error) {
+	expirationTime := formatExpirationTime(permission.ExpirationTime())
+	var updated bool
+	for _, perm := range perms {
+		if granteeMatch(perm, permission.Grantee()) && !inheritedOnly(perm) {
+			updated = true
+			removeExpiration := perm.ExpirationTime != "" && expirationTime == ""
+			perm.AllowFileDiscovery = permission.AllowFileDiscovery()
+			perm.Role = string(permission.Role())
+			perm.ExpirationTime = expirationTime
+			err := updatePermissions(ctx, b, fileID, perm, removeExpiration)
+			if err != nil {
+				return nil, newDriveError("failed to set permission", err)
+			}
+		}
+	}
+
+	if !updated {
+		var email, domain, granteeType string
+		switch grantee := permission.Grantee().(type) {
+		case GranteeUser:
+			email, granteeType = grantee.Email, granteeTypeUser
+		case GranteeGroup:
+			email, granteeType = grantee.Email, granteeTypeGroup
+		case GranteeDomain:
+			domain, granteeType = grantee.Domain, granteeTypeDomain
+		case GranteeAnyone:
+			granteeType = granteeTypeAnyone
+		}
+		perm, err := b.CreatePermission(ctx, CreatePermissionRequest{
+			FileID: fileID,
+			Permission: &drive.Permission{
+				AllowFileDiscovery: permission.AllowFileDiscovery(),
+				EmailAddress:       email,
+				Domain:             domain,
+				ExpirationTime:     expirationTime,
+				Id:                 string(permission.ID()),
+				Role:               string(permission.Role()),
+				Type:               granteeType,
+			},
+			SuppressNotificationEmail: opts.SuppressNotificationEmail,
+			EmailMessage:              opts.EmailMessage,
 		})
-	if err != nil {
-		return nil, newDriveError("failed to list permissions", err)
+		if err != nil {
+			return nil, newDriveError("failed to set permission", err)
+		}
+		// The created permission replaces the inherited one for the same grantee.
+		perms = slices.DeleteFunc(perms, func(p *drive.Permission) bool { return p.Id == perm.Id })
+		perms = append(perms, perm)
 	}
-	return permissions, nil
+	return perms, nil
 }
 
-func updatePermissions(s *drive.Service, fileID string, perm *drive.Permission) (err error) {
-	_, err = s.Permissions.Update(fileID, perm.Id, perm).
-		SupportsAllDrives(true).
-		Fields(drivePermissionFields).
-		Do()
-	if err != nil {
-		return newDriveError("failed to set permission", err)
+// deletePermissionsIn deletes the permissions in perms, which are those of the file with the given fileID,
+// that are set on the file for grantee.
+// Returns the remaining permissions of the file.
+func deletePermissionsIn(ctx context.Context, b Backend, fileID string, perms []*drive.Permission, grantee Grantee) ([]*drive.Permission, error) {
+	remainedPermissions := []*drive.Permission{}
+	for _, perm := range perms {
+		if granteeMatch(perm, grantee) && !inheritedOnly(perm) {
+			err := deletePermissions(ctx, b, fileID, perm.Id)
+			if err != nil {
+				return nil, newDriveError("failed to delete permission", err)
+			}
+		} else {
+			remainedPermissions = append(remainedPermissions, perm)
+		}
 	}
-	return nil
+	return remainedPermissions, nil
+}
+
+// formatExpirationTime formats t as the expiration time of a permission, or returns an empty string if t is zero.
+func formatExpirationTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// inheritedOnly reports whether perm is only inherited, so that it cannot be updated or deleted on the file.
+func inheritedOnly(perm *drive.Permission) bool {
+	for _, detail := range perm.PermissionDetails {
+		if !detail.Inherited {
+			return false
+		}
+	}
+	return len(perm.PermissionDetails) > 0
 }
 
-func createPermissions(s *drive.Service, fileID string, perm *drive.Permission) (permission *drive.Permission, err error) {
-	permission, err = s.Permissions.Create(fileID, perm).
-		SupportsAllDrives(true).
-		Fields(drivePermissionFields).
-		Do()
+func listPermissions(ctx context.Context, b Backend, fileID string) ([]*drive.Permission, error) {
+	var permissions []*drive.Permission
+	var pageToken string
+	for {
+		list, err := b.ListPermissions(ctx, fileID, pageToken)
+		if err != nil {
+			return nil, newDriveError("failed to list permissions", err)
+		}
+		permissions = append(permissions, list.Permissions...)
+		if list.NextPageToken == "" {
+			return permissions, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+func updatePermissions(ctx context.Context, b Backend, fileID string, perm *drive.Permission, removeExpiration bool) (err error) {
+	_, err = b.UpdatePermission(ctx, UpdatePermissionRequest{FileID: fileID, Permission: perm, RemoveExpiration: removeExpiration})
 	if err != nil {
-		return nil, newDriveError("failed to set permission", err)
+		return newDriveError("failed to set permission", err)
 	}
-	return permission, nil
+	return nil
 }
 
-func deletePermissions(s *drive.Service, fileID, permID string) (err error) {
-	err = s.Permissions.Delete(fileID, permID).
-		SupportsAllDrives(true).
-		Fields(drivePermissionFields).
-		Do()
+func deletePermissions(ctx context.Context, b Backend, fileID, permID string) (err error) {
+	err = b.DeletePermission(ctx, fileID, permID)
 	if err != nil {
 		return newDriveError("failed to set permission", err)
 	}
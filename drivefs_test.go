@@ -0,0 +1,313 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func TestDriveFS_ContextCanceled(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	service, err := drive.NewService(context.Background(),
+		option.WithEndpoint(srv.URL),
+		option.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	fs := drivefs.New(service)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cases := []struct {
+		name string
+		call func() error
+	}{
+		{"InfoContext", func() error { _, err := fs.InfoContext(ctx, "id"); return err }},
+		{"ReadDirContext", func() error { _, err := fs.ReadDirContext(ctx, "id"); return err }},
+		{"ReadFileContext", func() error { _, err := fs.ReadFileContext(ctx, "id"); return err }},
+		{"QueryContext", func() error { _, err := fs.QueryContext(ctx, "trashed = false"); return err }},
+		{"PermListContext", func() error { _, err := fs.PermListContext(ctx, "id"); return err }},
+		{"WalkContext", func() error {
+			return fs.WalkContext(ctx, "id", func(drivefs.Path, drivefs.FileInfo) error { return nil })
+		}},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			err := c.call()
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("%s() error = %v, want context.Canceled", c.name, err)
+			}
+		})
+	}
+	if called {
+		t.Fatalf("request reached the server despite canceled context")
+	}
+}
+
+func newMemDriveFS(t *testing.T) (*drivefs.DriveFS, drivefs.FileID) {
+	t.Helper()
+	d := drivefsmem.New(drivefsmem.Options{UserEmail: "owner@example.com"})
+	return drivefs.NewWithBackend(d), d.RootID()
+}
+
+func TestDriveFS_MkdirAllAndFindByPath(t *testing.T) {
+	fs, root := newMemDriveFS(t)
+
+	dir, err := fs.MkdirAll(root, "/a/b/c")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	again, err := fs.MkdirAll(root, "/a/b/c")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if again.ID != dir.ID {
+		t.Fatalf("MkdirAll() ID = %v, want existing %v", again.ID, dir.ID)
+	}
+	path, err := fs.ResolvePath(dir.ID)
+	if err != nil {
+		t.Fatalf("ResolvePath() error = %v", err)
+	}
+	if path != "/a/b/c" {
+		t.Fatalf("ResolvePath() = %q, want %q", path, "/a/b/c")
+	}
+
+	b, err := fs.FindByPath(root, "/a/b")
+	if err != nil || len(b) != 1 {
+		t.Fatalf("FindByPath() = %v, %v, want one file", b, err)
+	}
+	for range 2 {
+		if _, err := fs.Create(b[0].ID, "dup.txt"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	found, err := fs.FindByPath(root, "/a/b/dup.txt")
+	if err != nil {
+		t.Fatalf("FindByPath() error = %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("FindByPath() returned %d files, want 2", len(found))
+	}
+	if _, err := fs.MkdirAll(root, "/a/b/dup.txt/d"); !errors.Is(err, drivefs.ErrAlreadyExists) {
+		t.Fatalf("MkdirAll() error = %v, want ErrAlreadyExists", err)
+	}
+}
+
+func TestDriveFS_FileOperations(t *testing.T) {
+	fs, root := newMemDriveFS(t)
+
+	src, err := fs.Mkdir(root, "src")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	dst, err := fs.Mkdir(root, "dst")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	file, err := fs.Create(src.ID, "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := fs.WriteFile(file.ID, []byte("hello")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if data, err := fs.ReadFile(file.ID); err != nil || string(data) != "hello" {
+		t.Fatalf("ReadFile() = %q, %v, want %q", data, err, "hello")
+	}
+
+	if err := fs.Remove(src.ID, false); !errors.Is(err, drivefs.ErrNotRemovable) {
+		t.Fatalf("Remove() error = %v, want ErrNotRemovable", err)
+	}
+
+	copied, err := fs.Copy(file.ID, dst.ID, "copy.txt")
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if data, err := fs.ReadFile(copied.ID); err != nil || string(data) != "hello" {
+		t.Fatalf("ReadFile() of copy = %q, %v, want %q", data, err, "hello")
+	}
+
+	renamed, err := fs.Rename(file.ID, "renamed.txt")
+	if err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if renamed.Name != "renamed.txt" {
+		t.Fatalf("Rename() name = %q, want %q", renamed.Name, "renamed.txt")
+	}
+	if err := fs.Move(file.ID, dst.ID); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	children, err := fs.ReadDir(dst.ID)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, c := range children {
+		names = append(names, c.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"copy.txt", "renamed.txt"}; !slices.Equal(names, want) {
+		t.Fatalf("ReadDir() names = %v, want %v", names, want)
+	}
+
+	shortcut, err := fs.Shortcut(src.ID, "link", file.ID)
+	if err != nil {
+		t.Fatalf("Shortcut() error = %v", err)
+	}
+	if !shortcut.IsShortcut() || shortcut.ShortcutTarget != file.ID {
+		t.Fatalf("Shortcut() = %+v, want shortcut to %v", shortcut, file.ID)
+	}
+
+	if err := fs.Remove(file.ID, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := fs.Info(file.ID); err != nil {
+		t.Fatalf("Info() of trashed file error = %v", err)
+	}
+	if err := fs.RemoveAll(src.ID, false); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if _, err := fs.Info(src.ID); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("Info() of deleted directory error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDriveFS_Walk(t *testing.T) {
+	fs, root := newMemDriveFS(t)
+
+	for _, p := range []drivefs.Path{"/x/y", "/x/z"} {
+		if _, err := fs.MkdirAll(root, p); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+	}
+	x, err := fs.FindByPath(root, "/x")
+	if err != nil || len(x) != 1 {
+		t.Fatalf("FindByPath() = %v, %v, want one file", x, err)
+	}
+
+	var paths []string
+	err = fs.Walk(x[0].ID, func(p drivefs.Path, _ drivefs.FileInfo) error {
+		paths = append(paths, string(p))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	slices.Sort(paths)
+	if want := []string{"/", "/y", "/z"}; !slices.Equal(paths, want) {
+		t.Fatalf("Walk() paths = %v, want %v", paths, want)
+	}
+}
+
+func TestDriveFS_Permissions(t *testing.T) {
+	fs, root := newMemDriveFS(t)
+
+	file, err := fs.Create(root, "shared.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := fs.PermSet(file.ID, drivefs.UserPermission("alice@example.com", drivefs.RoleReader)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	perms, err := fs.PermSet(file.ID, drivefs.UserPermission("alice@example.com", drivefs.RoleWriter))
+	if err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	var roles []drivefs.Role
+	for _, p := range perms {
+		if p.Grantee() == drivefs.User("alice@example.com") {
+			roles = append(roles, p.Role())
+		}
+	}
+	if want := []drivefs.Role{drivefs.RoleWriter}; !slices.Equal(roles, want) {
+		t.Fatalf("PermSet() roles of alice = %v, want %v", roles, want)
+	}
+
+	if _, err := fs.PermDel(file.ID, drivefs.User("alice@example.com")); err != nil {
+		t.Fatalf("PermDel() error = %v", err)
+	}
+	perms, err = fs.PermList(file.ID)
+	if err != nil {
+		t.Fatalf("PermList() error = %v", err)
+	}
+	if len(perms) != 1 || perms[0].Role() != drivefs.RoleOwner {
+		t.Fatalf("PermList() = %v, want only the owner", perms)
+	}
+}
+
+// permissionRequestBackend records the requests to create permissions.
+type permissionRequestBackend struct {
+	drivefs.Backend
+	requests []drivefs.CreatePermissionRequest
+}
+
+func (b *permissionRequestBackend) CreatePermission(ctx context.Context, req drivefs.CreatePermissionRequest) (*drive.Permission, error) {
+	b.requests = append(b.requests, req)
+	return b.Backend.CreatePermission(ctx, req)
+}
+
+func TestDriveFS_ExpiringPermissions(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mem := drivefsmem.New(drivefsmem.Options{Now: func() time.Time { return now }})
+	backend := &permissionRequestBackend{Backend: mem}
+	fs := drivefs.NewWithBackend(backend)
+	file, err := fs.Create(mem.RootID(), "report.pdf")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	auditor := drivefs.User("auditor@example.com")
+	expiration := now.Add(24 * time.Hour)
+
+	_, err = fs.PermSetWithOptions(file.ID, drivefs.WithExpiration(drivefs.UserPermission("auditor@example.com", drivefs.RoleReader), expiration),
+		drivefs.PermSetOptions{SuppressNotificationEmail: true, EmailMessage: "for the audit"})
+	if err != nil {
+		t.Fatalf("PermSetWithOptions() error = %v", err)
+	}
+	if len(backend.requests) != 1 || !backend.requests[0].SuppressNotificationEmail || backend.requests[0].EmailMessage != "for the audit" {
+		t.Fatalf("CreatePermission() requests = %+v, want one without notification email", backend.requests)
+	}
+	perm := findPermission(t, fs, file.ID, auditor)
+	if perm == nil || !perm.ExpirationTime().Equal(expiration) {
+		t.Fatalf("permission = %+v, want one expiring at %v", perm, expiration)
+	}
+
+	if _, err := fs.PermSet(file.ID, drivefs.UserPermission("auditor@example.com", drivefs.RoleReader)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	if perm := findPermission(t, fs, file.ID, auditor); perm == nil || !perm.ExpirationTime().IsZero() {
+		t.Fatalf("permission = %+v, want one without expiration", perm)
+	}
+	if _, err := fs.PermSet(file.ID, drivefs.WithExpiration(drivefs.UserPermission("auditor@example.com", drivefs.RoleReader), expiration)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	now = expiration
+	if perm := findPermission(t, fs, file.ID, auditor); perm != nil {
+		t.Fatalf("permission after expiration = %+v, want none", perm)
+	}
+
+	if _, err := fs.PermSet(file.ID, drivefs.WithExpiration(drivefs.UserPermission("auditor@example.com", drivefs.RoleReader), now.Add(-time.Hour))); !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("PermSet() with a past expiration error = %v, want ErrDriveError", err)
+	}
+	if _, err := fs.PermSet(file.ID, drivefs.WithExpiration(drivefs.AnyonePermission(drivefs.RoleReader, false), now.Add(time.Hour))); !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("PermSet() with an expiring anyone permission error = %v, want ErrDriveError", err)
+	}
+}
@@ -0,0 +1,1548 @@
+// Package drivefsmem provides an in-memory implementation of drivefs.Backend for tests.
+//
+// It models the semantics of Google Drive that drivefs relies on: duplicate names in one folder,
+// multiple parents, shortcuts, trash, Google Apps MIME types, permissions, revisions, pagination, changes,
+// shared drives and the subset of the query language used by drivefs.
+// Failures are reported as *googleapi.Error with the status codes the Google Drive API would return.
+package drivefsmem
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	mimeTypeFolder        = "application/vnd.google-apps.folder"
+	mimeTypeShortcut      = "application/vnd.google-apps.shortcut"
+	mimeTypePrefixApp     = "application/vnd.google-apps."
+	mimeTypeOctetStream   = "application/octet-stream"
+	defaultPageSize       = 100
+	maxPageSize           = 1000
+	defaultUserEmail      = "me@example.com"
+	rootAlias             = "root"
+	webViewLinkFileFormat = "https://drive.google.com/file/d/%s/view"
+	exportLinkFormat      = "https://www.googleapis.com/drive/v3/files/%s/export?mimeType=%s"
+)
+
+// Options configures a Drive created by New.
+type Options struct {
+	// UserEmail is the email address of the authenticated user who owns the created files.
+	// Empty means "me@example.com".
+	UserEmail string
+
+	// Now returns the current time used for timestamps. Nil means time.Now.
+	Now func() time.Time
+}
+
+// Drive is an in-memory Google Drive that implements drivefs.Backend.
+// It is safe for concurrent use.
+type Drive struct {
+	mu      sync.Mutex
+	user    string
+	now     func() time.Time
+	rootID  string
+	files   map[string]*file
+	nextSeq int
+	created int
+	permIDs map[string]string
+	// generated holds the IDs returned by GenerateIDs that have not been used yet.
+	generated map[string]bool
+	// changes is the log of the changes to files, whose indexes are used as page tokens.
+	changes []change
+	// lastChange maps each file ID to the index of its latest change.
+	lastChange map[string]int
+	// drives maps the ID of each shared drive, which is also the ID of its root folder, to the shared drive.
+	drives map[string]*sharedDrive
+	// driveRequests holds the request IDs of CreateDrive that have been used.
+	driveRequests map[string]bool
+}
+
+var _ drivefs.Backend = (*Drive)(nil)
+
+type file struct {
+	seq         int
+	meta        *drive.File
+	content     []byte
+	exports     map[string][]byte
+	permissions []*drive.Permission
+	revisions   []*revision
+	// revSeq is the sequence number of the latest revision, which is used as its ID.
+	revSeq int
+}
+
+type revision struct {
+	meta    *drive.Revision
+	content []byte
+}
+
+type change struct {
+	fileID string
+	time   string
+	// driveID is the ID of the shared drive that contained the file when it was changed, or empty for My Drive.
+	driveID string
+}
+
+type sharedDrive struct {
+	seq  int
+	meta *drive.Drive
+}
+
+// New creates an empty Drive that only contains the root folder of My Drive.
+func New(opts Options) *Drive {
+	d := &Drive{
+		user:          opts.UserEmail,
+		now:           opts.Now,
+		files:         map[string]*file{},
+		permIDs:       map[string]string{},
+		generated:     map[string]bool{},
+		lastChange:    map[string]int{},
+		drives:        map[string]*sharedDrive{},
+		driveRequests: map[string]bool{},
+	}
+	if d.user == "" {
+		d.user = defaultUserEmail
+	}
+	if d.now == nil {
+		d.now = time.Now
+	}
+	root := d.newFile(&drive.File{Name: "My Drive", MimeType: mimeTypeFolder})
+	d.rootID = root.meta.Id
+	return d
+}
+
+// RootID returns the ID of the root folder of My Drive, which can also be referred to as "root".
+func (d *Drive) RootID() drivefs.FileID {
+	return drivefs.FileID(d.rootID)
+}
+
+// GetFile implements drivefs.Backend.
+func (d *Drive) GetFile(ctx context.Context, fileID string) (*drive.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, err := d.get(fileID)
+	if err != nil {
+		return nil, err
+	}
+	return d.view(f), nil
+}
+
+// ListFiles implements drivefs.Backend.
+func (d *Drive) ListFiles(ctx context.Context, req drivefs.ListFilesRequest) (*drive.FileList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkDrive(req.DriveID); err != nil {
+		return nil, err
+	}
+	q, err := parseQuery(d, req.Query)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: %v", err)
+	}
+	less, err := parseOrderBy(req.OrderBy)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: %v", err)
+	}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+	offset := 0
+	if req.PageToken != "" {
+		offset, err = strconv.Atoi(req.PageToken)
+		if err != nil || offset < 0 {
+			return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+		}
+	}
+
+	var matched []*file
+	for _, f := range d.files {
+		if !d.isRoot(f) && (req.DriveID == "" || d.driveOf(f) == req.DriveID) && q(f) {
+			matched = append(matched, f)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *file) int {
+		if c := less(a, b); c != 0 {
+			return c
+		}
+		return a.seq - b.seq
+	})
+
+	list := &drive.FileList{Files: []*drive.File{}}
+	if offset < len(matched) {
+		end := min(offset+pageSize, len(matched))
+		for _, f := range matched[offset:end] {
+			list.Files = append(list.Files, d.view(f))
+		}
+		if end < len(matched) {
+			list.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	return list, nil
+}
+
+// GenerateIDs implements drivefs.Backend.
+func (d *Drive) GenerateIDs(ctx context.Context, count int) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if count < 1 || count > maxPageSize {
+		return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: count %d", count)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = d.nextID()
+		d.generated[ids[i]] = true
+	}
+	return ids, nil
+}
+
+// CreateFile implements drivefs.Backend.
+func (d *Drive) CreateFile(ctx context.Context, req drivefs.CreateFileRequest) (*drive.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	content, contentType, err := readMedia(req.Media, req.MediaOptions, req.Progress)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	meta := cloneFile(req.File)
+	if meta == nil {
+		meta = &drive.File{}
+	}
+	if err := d.checkGeneratedID(meta.Id); err != nil {
+		return nil, err
+	}
+	if meta.Name == "" {
+		meta.Name = "Untitled"
+	}
+	if meta.MimeType == "" {
+		meta.MimeType = mimeTypeOctetStream
+		if contentType != "" {
+			meta.MimeType = contentType
+		}
+	}
+	parents := meta.Parents
+	if len(parents) == 0 {
+		parents = []string{d.rootID}
+	}
+	meta.Parents = nil
+	for _, parentID := range parents {
+		parent, err := d.get(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.meta.MimeType != mimeTypeFolder {
+			return nil, newError(http.StatusBadRequest, "invalidParent", "The specified parent is not a folder: %s", parentID)
+		}
+		meta.Parents = append(meta.Parents, parent.meta.Id)
+	}
+	if meta.MimeType == mimeTypeShortcut {
+		if meta.ShortcutDetails == nil || meta.ShortcutDetails.TargetId == "" {
+			return nil, newError(http.StatusBadRequest, "required", "Required: shortcutDetails.targetId")
+		}
+		target, err := d.get(meta.ShortcutDetails.TargetId)
+		if err != nil {
+			return nil, err
+		}
+		meta.ShortcutDetails.TargetId = target.meta.Id
+		meta.ShortcutDetails.TargetMimeType = target.meta.MimeType
+	} else {
+		meta.ShortcutDetails = nil
+	}
+
+	if content != nil && strings.HasPrefix(meta.MimeType, mimeTypePrefixApp) {
+		// The content is converted into a Google Apps file.
+		if !slices.Contains(drivefs.ImportFormats(contentType), meta.MimeType) {
+			return nil, newError(http.StatusBadRequest, "badRequest", "The requested conversion from %s to %s is not supported.", contentType, meta.MimeType)
+		}
+		f := d.newFile(meta)
+		if slices.Contains(drivefs.ExportFormats(meta.MimeType), contentType) {
+			// Drive does not render Google Apps files, but exporting to the original format returns the content.
+			f.exports = map[string][]byte{contentType: content}
+		}
+		return d.view(f), nil
+	}
+
+	f := d.newFile(meta)
+	if content != nil {
+		d.setContent(f, content)
+	}
+	d.addRevision(f)
+	return d.view(f), nil
+}
+
+// UpdateFile implements drivefs.Backend.
+func (d *Drive) UpdateFile(ctx context.Context, req drivefs.UpdateFileRequest) (*drive.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	content, _, err := readMedia(req.Media, req.MediaOptions, req.Progress)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if d.isRoot(f) && (len(req.AddParents) > 0 || len(req.RemoveParents) > 0) {
+		return nil, newError(http.StatusForbidden, "cannotMoveRoot", "The root folder cannot be moved")
+	}
+
+	// Validate everything before mutating the file.
+	var addParents []string
+	for _, parentID := range req.AddParents {
+		parent, err := d.get(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.meta.MimeType != mimeTypeFolder {
+			return nil, newError(http.StatusBadRequest, "invalidParent", "The specified parent is not a folder: %s", parentID)
+		}
+		if d.isAncestorOrSelf(f.meta.Id, parent) {
+			return nil, newError(http.StatusBadRequest, "invalidParent", "A folder cannot be moved into itself or its descendant")
+		}
+		addParents = append(addParents, parent.meta.Id)
+	}
+	if content != nil && strings.HasPrefix(f.meta.MimeType, mimeTypePrefixApp) {
+		return nil, newError(http.StatusBadRequest, "invalidContent", "Content cannot be uploaded to a Google Apps file of type %s", f.meta.MimeType)
+	}
+	if m := req.File; m != nil {
+		if m.MimeType != "" && m.MimeType != f.meta.MimeType &&
+			(strings.HasPrefix(m.MimeType, mimeTypePrefixApp) || strings.HasPrefix(f.meta.MimeType, mimeTypePrefixApp)) {
+			return nil, newError(http.StatusBadRequest, "invalidMimeType", "The MIME type cannot be changed to or from %s", mimeTypePrefixApp+"*")
+		}
+		if (m.Trashed || slices.Contains(m.ForceSendFields, "Trashed")) && d.isRoot(f) {
+			return nil, newError(http.StatusForbidden, "cannotTrashRoot", "The root folder cannot be trashed")
+		}
+	}
+
+	changed, trashed := false, d.isTrashed(f)
+	if m := req.File; m != nil {
+		force := func(name string) bool { return slices.Contains(m.ForceSendFields, name) }
+		if m.Name != "" {
+			f.meta.Name, changed = m.Name, true
+		}
+		if m.MimeType != "" && m.MimeType != f.meta.MimeType {
+			f.meta.MimeType, changed = m.MimeType, true
+		}
+		if m.Description != "" || force("Description") {
+			f.meta.Description, changed = m.Description, true
+		}
+		if m.Starred || force("Starred") {
+			f.meta.Starred = m.Starred
+		}
+		if m.Trashed || force("Trashed") {
+			f.meta.ExplicitlyTrashed = m.Trashed
+			if m.Trashed {
+				f.meta.TrashedTime = d.timestamp()
+			} else {
+				f.meta.TrashedTime = ""
+			}
+		}
+		for k, v := range m.Properties {
+			if f.meta.Properties == nil {
+				f.meta.Properties = map[string]string{}
+			}
+			f.meta.Properties[k] = v
+		}
+		for k, v := range m.AppProperties {
+			if f.meta.AppProperties == nil {
+				f.meta.AppProperties = map[string]string{}
+			}
+			f.meta.AppProperties[k] = v
+		}
+		if m.ModifiedTime != "" {
+			f.meta.ModifiedTime = m.ModifiedTime
+		}
+	}
+	for _, parentID := range req.RemoveParents {
+		parentID = d.resolveID(parentID)
+		f.meta.Parents = slices.DeleteFunc(f.meta.Parents, func(p string) bool { return p == parentID })
+	}
+	for _, parentID := range addParents {
+		if !slices.Contains(f.meta.Parents, parentID) {
+			f.meta.Parents = append(f.meta.Parents, parentID)
+		}
+	}
+	if !d.isRoot(f) && len(f.meta.Parents) == 0 {
+		// Google Drive places files without parents in the root folder.
+		f.meta.Parents = []string{d.rootID}
+	}
+	if content != nil {
+		d.setContent(f, content)
+		changed = true
+	}
+	if changed && (req.File == nil || req.File.ModifiedTime == "") {
+		f.meta.ModifiedTime = d.timestamp()
+	}
+	if content != nil {
+		d.addRevision(f)
+	}
+	if trashed != d.isTrashed(f) {
+		// The descendants are trashed or restored together.
+		d.recordDescendantChanges(f.meta.Id)
+	}
+	d.recordChange(f.meta.Id)
+	return d.view(f), nil
+}
+
+// CopyFile implements drivefs.Backend.
+func (d *Drive) CopyFile(ctx context.Context, req drivefs.CopyFileRequest) (*drive.File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	src, err := d.get(req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if src.meta.MimeType == mimeTypeFolder {
+		return nil, newError(http.StatusForbidden, "cannotCopyFile", "This file cannot be copied by the user.")
+	}
+	meta := cloneFile(src.meta)
+	meta.Id, meta.Parents = "", nil
+	if req.File != nil {
+		if err := d.checkGeneratedID(req.File.Id); err != nil {
+			return nil, err
+		}
+		meta.Id = req.File.Id
+	}
+	meta.ExplicitlyTrashed, meta.Trashed, meta.TrashedTime = false, false, ""
+	meta.ModifiedTime = ""
+	meta.Name = "Copy of " + src.meta.Name
+	parents := src.meta.Parents
+	if m := req.File; m != nil {
+		if m.Name != "" {
+			meta.Name = m.Name
+		}
+		if m.Description != "" {
+			meta.Description = m.Description
+		}
+		if m.ModifiedTime != "" {
+			meta.ModifiedTime = m.ModifiedTime
+		}
+		if len(m.Parents) > 0 {
+			parents = m.Parents
+		}
+	}
+	for _, parentID := range parents {
+		parent, err := d.get(parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.meta.MimeType != mimeTypeFolder {
+			return nil, newError(http.StatusBadRequest, "invalidParent", "The specified parent is not a folder: %s", parentID)
+		}
+		meta.Parents = append(meta.Parents, parent.meta.Id)
+	}
+	f := d.newFile(meta)
+	f.content = slices.Clone(src.content)
+	for format, data := range src.exports {
+		if f.exports == nil {
+			f.exports = map[string][]byte{}
+		}
+		f.exports[format] = slices.Clone(data)
+	}
+	d.addRevision(f)
+	return d.view(f), nil
+}
+
+// DeleteFile implements drivefs.Backend.
+// Deleting a folder also deletes the descendants that have no other parent.
+func (d *Drive) DeleteFile(ctx context.Context, fileID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(fileID)
+	if err != nil {
+		return err
+	}
+	if d.isRoot(f) {
+		return newError(http.StatusForbidden, "cannotDeleteRoot", "The root folder cannot be deleted")
+	}
+	d.delete(f.meta.Id)
+	return nil
+}
+
+// EmptyTrash implements drivefs.Backend.
+func (d *Drive) EmptyTrash(ctx context.Context, driveID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkDrive(driveID); err != nil {
+		return err
+	}
+
+	var trashed []string
+	for id, f := range d.files {
+		if f.meta.ExplicitlyTrashed && d.driveOf(f) == driveID {
+			trashed = append(trashed, id)
+		}
+	}
+	for _, id := range trashed {
+		if _, ok := d.files[id]; ok {
+			d.delete(id)
+		}
+	}
+	return nil
+}
+
+// DownloadFile implements drivefs.Backend.
+func (d *Drive) DownloadFile(ctx context.Context, req drivefs.DownloadFileRequest) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(f.meta.MimeType, mimeTypePrefixApp) {
+		return nil, newError(http.StatusForbidden, "fileNotDownloadable", "Only files with binary content can be downloaded. Use Export with Docs Editors files.")
+	}
+	size := int64(len(f.content))
+	if req.Offset < 0 || (req.Offset > 0 && req.Offset >= size) {
+		return nil, newError(http.StatusRequestedRangeNotSatisfiable, "requestedRangeNotSatisfiable", "Request range not satisfiable")
+	}
+	end := size
+	if req.Length >= 0 {
+		end = min(req.Offset+req.Length, size)
+	}
+	return io.NopCloser(bytes.NewReader(slices.Clone(f.content[req.Offset:end]))), nil
+}
+
+// ExportFile implements drivefs.Backend.
+// Drive does not render Google Apps files, so the exported content is the data registered by SetExport
+// or uploaded in the same format when the file was created by conversion, or empty otherwise.
+func (d *Drive) ExportFile(ctx context.Context, fileID, mimeType string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkExportable(f, mimeType); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(slices.Clone(f.exports[mimeType]))), nil
+}
+
+// SetExport registers data as the content of the Google Apps file with the given fileID exported to mimeType.
+func (d *Drive) SetExport(fileID, mimeType string, data []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(fileID)
+	if err != nil {
+		return err
+	}
+	if err := checkExportable(f, mimeType); err != nil {
+		return err
+	}
+	if f.exports == nil {
+		f.exports = map[string][]byte{}
+	}
+	f.exports[mimeType] = slices.Clone(data)
+	return nil
+}
+
+func checkExportable(f *file, mimeType string) error {
+	formats := drivefs.ExportFormats(f.meta.MimeType)
+	if len(formats) == 0 {
+		return newError(http.StatusForbidden, "fileNotExportable", "Export only supports Docs Editors files.")
+	}
+	if !slices.Contains(formats, mimeType) {
+		return newError(http.StatusBadRequest, "badRequest", "The requested conversion is not supported.")
+	}
+	return nil
+}
+
+// ListPermissions implements drivefs.Backend.
+func (d *Drive) ListPermissions(ctx context.Context, fileID string, pageToken string) (*drive.PermissionList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(fileID)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if pageToken != "" {
+		offset, err = strconv.Atoi(pageToken)
+		if err != nil || offset < 0 {
+			return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+		}
+	}
+	perms := d.permissionsOf(f)
+	list := &drive.PermissionList{Permissions: []*drive.Permission{}}
+	if offset < len(perms) {
+		end := min(offset+defaultPageSize, len(perms))
+		list.Permissions = append(list.Permissions, perms[offset:end]...)
+		if end < len(perms) {
+			list.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	return list, nil
+}
+
+// CreatePermission implements drivefs.Backend.
+// Creating a permission for a grantee that already has one replaces its role.
+// Notification emails are not sent.
+func (d *Drive) CreatePermission(ctx context.Context, req drivefs.CreatePermissionRequest) (*drive.Permission, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	perm := clonePermission(req.Permission)
+	if err := validatePermission(perm); err != nil {
+		return nil, err
+	}
+	if perm.Role == "owner" {
+		if !req.TransferOwnership {
+			return nil, newError(http.StatusForbidden, "forbidden", "The transferOwnership parameter must be enabled when the permission role is 'owner'.")
+		}
+		if err := d.checkOwnershipChange(f, perm); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.validateExpiration(perm, perm.ExpirationTime); err != nil {
+		return nil, err
+	}
+	if perm.PendingOwner {
+		if err := d.checkOwnershipChange(f, perm); err != nil {
+			return nil, err
+		}
+	}
+	perm.Id = d.permissionID(perm)
+	perm.Kind = "drive#permission"
+	i := slices.IndexFunc(f.permissions, func(p *drive.Permission) bool { return p.Id == perm.Id })
+	if i < 0 {
+		f.permissions = append(f.permissions, perm)
+	} else if f.permissions[i].Role == "owner" {
+		return nil, newError(http.StatusForbidden, "forbidden", "The role of the owner cannot be changed.")
+	} else {
+		f.permissions[i] = perm
+	}
+	if perm.Role == "owner" {
+		d.transferOwnership(f, perm)
+	}
+	d.recordChange(f.meta.Id)
+	return d.permissionOf(f, perm.Id), nil
+}
+
+// UpdatePermission implements drivefs.Backend.
+func (d *Drive) UpdatePermission(ctx context.Context, req drivefs.UpdatePermissionRequest) (*drive.Permission, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range f.permissions {
+		if p.Id != req.Permission.Id || d.expired(p) {
+			continue
+		}
+		updated := clonePermission(p)
+		if req.Permission.Role != "" && req.Permission.Role != p.Role {
+			switch {
+			case p.Role == "owner":
+				return nil, newError(http.StatusForbidden, "forbidden", "The role of the owner cannot be changed.")
+			case req.Permission.Role == "owner" && !req.TransferOwnership:
+				return nil, newError(http.StatusForbidden, "forbidden", "The transferOwnership parameter must be enabled when the permission role is 'owner'.")
+			}
+			updated.Role = req.Permission.Role
+		}
+		if req.Permission.ExpirationTime != "" {
+			updated.ExpirationTime = req.Permission.ExpirationTime
+		}
+		if req.RemoveExpiration {
+			updated.ExpirationTime = ""
+		}
+		if err := d.validateExpiration(updated, req.Permission.ExpirationTime); err != nil {
+			return nil, err
+		}
+		if p.Type == "domain" || p.Type == "anyone" {
+			updated.AllowFileDiscovery = req.Permission.AllowFileDiscovery
+		}
+		// Only writers can be pending owners.
+		updated.PendingOwner = (updated.PendingOwner || req.Permission.PendingOwner) && updated.Role == "writer"
+		if updated.Role == "owner" && p.Role != "owner" || updated.PendingOwner && !p.PendingOwner {
+			if err := d.checkOwnershipChange(f, updated); err != nil {
+				return nil, err
+			}
+		}
+		f.permissions[i] = updated
+		if updated.Role == "owner" {
+			d.transferOwnership(f, updated)
+		}
+		d.recordChange(f.meta.Id)
+		return d.permissionOf(f, p.Id), nil
+	}
+	if d.permissionOf(f, req.Permission.Id) != nil {
+		return nil, newError(http.StatusForbidden, "cannotModifyInheritedPermission", "The permission is inherited and cannot be modified on this item.")
+	}
+	return nil, newError(http.StatusNotFound, "notFound", "Permission not found: %s.", req.Permission.Id)
+}
+
+// DeletePermission implements drivefs.Backend.
+func (d *Drive) DeletePermission(ctx context.Context, fileID, permissionID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(fileID)
+	if err != nil {
+		return err
+	}
+	for i, p := range f.permissions {
+		if p.Id != permissionID || d.expired(p) {
+			continue
+		}
+		if p.Role == "owner" {
+			return newError(http.StatusForbidden, "cannotDeletePermission", "The owner of a file cannot be removed.")
+		}
+		f.permissions = slices.Delete(f.permissions, i, i+1)
+		d.recordChange(f.meta.Id)
+		return nil
+	}
+	if d.permissionOf(f, permissionID) != nil {
+		return newError(http.StatusForbidden, "cannotDeletePermission", "The permission is inherited and cannot be removed from this item.")
+	}
+	return newError(http.StatusNotFound, "notFound", "Permission not found: %s.", permissionID)
+}
+
+// ListRevisions implements drivefs.Backend.
+// Only files with binary content have revisions, one of which is added each time the content is uploaded.
+func (d *Drive) ListRevisions(ctx context.Context, fileID string, pageToken string) (*drive.RevisionList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := d.get(fileID)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if pageToken != "" {
+		offset, err = strconv.Atoi(pageToken)
+		if err != nil || offset < 0 {
+			return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+		}
+	}
+	list := &drive.RevisionList{Revisions: []*drive.Revision{}}
+	if offset < len(f.revisions) {
+		end := min(offset+defaultPageSize, len(f.revisions))
+		for _, rev := range f.revisions[offset:end] {
+			list.Revisions = append(list.Revisions, cloneRevision(rev.meta))
+		}
+		if end < len(f.revisions) {
+			list.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	return list, nil
+}
+
+// DownloadRevision implements drivefs.Backend.
+func (d *Drive) DownloadRevision(ctx context.Context, fileID, revisionID string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, rev, err := d.getRevision(fileID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(slices.Clone(rev.content))), nil
+}
+
+// UpdateRevision implements drivefs.Backend.
+func (d *Drive) UpdateRevision(ctx context.Context, req drivefs.UpdateRevisionRequest) (*drive.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, rev, err := d.getRevision(req.FileID, req.Revision.Id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Revision.KeepForever || slices.Contains(req.Revision.ForceSendFields, "KeepForever") {
+		rev.meta.KeepForever = req.Revision.KeepForever
+	}
+	return cloneRevision(rev.meta), nil
+}
+
+// DeleteRevision implements drivefs.Backend.
+// Like Google Drive, the latest revision, which is the current content of the file, cannot be deleted.
+func (d *Drive) DeleteRevision(ctx context.Context, fileID, revisionID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, rev, err := d.getRevision(fileID, revisionID)
+	if err != nil {
+		return err
+	}
+	if rev == f.revisions[len(f.revisions)-1] {
+		return newError(http.StatusBadRequest, "cannotDeleteHeadRevision", "The head revision of a file cannot be deleted.")
+	}
+	f.revisions = slices.DeleteFunc(f.revisions, func(r *revision) bool { return r == rev })
+	return nil
+}
+
+func (d *Drive) getRevision(fileID, revisionID string) (*file, *revision, error) {
+	f, err := d.get(fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, rev := range f.revisions {
+		if rev.meta.Id == revisionID {
+			return f, rev, nil
+		}
+	}
+	return nil, nil, newError(http.StatusNotFound, "notFound", "Revision not found: %s.", revisionID)
+}
+
+// GetStartPageToken implements drivefs.Backend.
+func (d *Drive) GetStartPageToken(ctx context.Context, driveID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkDrive(driveID); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(len(d.changes)), nil
+}
+
+// ListChanges implements drivefs.Backend.
+// The changes to My Drive are listed if req.DriveID is empty, and those to the shared drive otherwise.
+// Like Google Drive, a file changed several times since the page token is listed once with its latest state.
+func (d *Drive) ListChanges(ctx context.Context, req drivefs.ListChangesRequest) (*drive.ChangeList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.checkDrive(req.DriveID); err != nil {
+		return nil, err
+	}
+	offset, err := strconv.Atoi(req.PageToken)
+	if err != nil || offset < 0 || offset > len(d.changes) {
+		return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+	}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+
+	list := &drive.ChangeList{Changes: []*drive.Change{}}
+	i := offset
+	for ; i < len(d.changes) && len(list.Changes) < pageSize; i++ {
+		c := d.changes[i]
+		// The root folders are not listed, and the root folder of a shared drive is the only file whose ID is its drive ID.
+		if d.lastChange[c.fileID] != i || c.fileID == d.rootID || c.fileID == c.driveID || c.driveID != req.DriveID {
+			continue
+		}
+		item := &drive.Change{Kind: "drive#change", ChangeType: "file", FileId: c.fileID, Time: c.time, DriveId: c.driveID}
+		if f, ok := d.files[c.fileID]; ok {
+			item.File = d.view(f)
+			item.File.Permissions = d.permissionsOf(f)
+		} else {
+			item.Removed = true
+		}
+		list.Changes = append(list.Changes, item)
+	}
+	if i < len(d.changes) {
+		list.NextPageToken = strconv.Itoa(i)
+	} else {
+		list.NewStartPageToken = strconv.Itoa(len(d.changes))
+	}
+	return list, nil
+}
+
+// ListDrives implements drivefs.Backend.
+// Shared drives are listed in the order of creation, including hidden ones.
+// The query is evaluated against the root folder of each shared drive, whose name and creation time are those of the shared drive.
+func (d *Drive) ListDrives(ctx context.Context, req drivefs.ListDrivesRequest) (*drive.DriveList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q, err := parseQuery(d, req.Query)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: %v", err)
+	}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	pageSize = min(pageSize, maxPageSize)
+	offset := 0
+	if req.PageToken != "" {
+		offset, err = strconv.Atoi(req.PageToken)
+		if err != nil || offset < 0 {
+			return nil, newError(http.StatusBadRequest, "invalid", "Invalid Value: pageToken")
+		}
+	}
+
+	var matched []*sharedDrive
+	for id, drv := range d.drives {
+		if q(d.files[id]) {
+			matched = append(matched, drv)
+		}
+	}
+	slices.SortFunc(matched, func(a, b *sharedDrive) int { return a.seq - b.seq })
+
+	list := &drive.DriveList{Kind: "drive#driveList", Drives: []*drive.Drive{}}
+	if offset < len(matched) {
+		end := min(offset+pageSize, len(matched))
+		for _, drv := range matched[offset:end] {
+			list.Drives = append(list.Drives, cloneDrive(drv.meta))
+		}
+		if end < len(matched) {
+			list.NextPageToken = strconv.Itoa(end)
+		}
+	}
+	return list, nil
+}
+
+// GetDrive implements drivefs.Backend.
+func (d *Drive) GetDrive(ctx context.Context, driveID string) (*drive.Drive, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drv, err := d.getDrive(driveID)
+	if err != nil {
+		return nil, err
+	}
+	return cloneDrive(drv.meta), nil
+}
+
+// CreateDrive implements drivefs.Backend.
+// The shared drive has a root folder with the same ID and name, in which files can be created.
+func (d *Drive) CreateDrive(ctx context.Context, req drivefs.CreateDriveRequest) (*drive.Drive, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if req.RequestID == "" {
+		return nil, newError(http.StatusBadRequest, "required", "Required parameter: requestId")
+	}
+	if req.Drive == nil || req.Drive.Name == "" {
+		return nil, newError(http.StatusBadRequest, "required", "Required: name")
+	}
+	if d.driveRequests[req.RequestID] {
+		return nil, newError(http.StatusConflict, "duplicate", "A shared drive has already been created with the request ID: %s.", req.RequestID)
+	}
+	d.driveRequests[req.RequestID] = true
+
+	meta := &drive.Drive{
+		Kind:         "drive#drive",
+		Id:           d.nextID(),
+		Name:         req.Drive.Name,
+		CreatedTime:  d.timestamp(),
+		Restrictions: &drive.DriveRestrictions{},
+	}
+	if r := req.Drive.Restrictions; r != nil {
+		meta.Restrictions.DomainUsersOnly = r.DomainUsersOnly
+		meta.Restrictions.CopyRequiresWriterPermission = r.CopyRequiresWriterPermission
+		meta.Restrictions.DriveMembersOnly = r.DriveMembersOnly
+	}
+	d.created++
+	d.drives[meta.Id] = &sharedDrive{seq: d.created, meta: meta}
+	root := d.newFile(&drive.File{Id: meta.Id, Name: meta.Name, MimeType: mimeTypeFolder})
+	root.meta.CreatedTime = meta.CreatedTime
+	return cloneDrive(meta), nil
+}
+
+// UpdateDrive implements drivefs.Backend.
+func (d *Drive) UpdateDrive(ctx context.Context, req drivefs.UpdateDriveRequest) (*drive.Drive, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drv, err := d.getDrive(req.Drive.Id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Drive.Name != "" {
+		drv.meta.Name = req.Drive.Name
+		d.files[drv.meta.Id].meta.Name = req.Drive.Name
+	}
+	if r := req.Drive.Restrictions; r != nil {
+		force := func(name string) bool { return slices.Contains(r.ForceSendFields, name) }
+		if r.DomainUsersOnly || force("DomainUsersOnly") {
+			drv.meta.Restrictions.DomainUsersOnly = r.DomainUsersOnly
+		}
+		if r.CopyRequiresWriterPermission || force("CopyRequiresWriterPermission") {
+			drv.meta.Restrictions.CopyRequiresWriterPermission = r.CopyRequiresWriterPermission
+		}
+		if r.DriveMembersOnly || force("DriveMembersOnly") {
+			drv.meta.Restrictions.DriveMembersOnly = r.DriveMembersOnly
+		}
+	}
+	return cloneDrive(drv.meta), nil
+}
+
+// HideDrive implements drivefs.Backend.
+func (d *Drive) HideDrive(ctx context.Context, driveID string) (*drive.Drive, error) {
+	return d.setDriveHidden(ctx, driveID, true)
+}
+
+// UnhideDrive implements drivefs.Backend.
+func (d *Drive) UnhideDrive(ctx context.Context, driveID string) (*drive.Drive, error) {
+	return d.setDriveHidden(ctx, driveID, false)
+}
+
+func (d *Drive) setDriveHidden(ctx context.Context, driveID string, hidden bool) (*drive.Drive, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drv, err := d.getDrive(driveID)
+	if err != nil {
+		return nil, err
+	}
+	drv.meta.Hidden = hidden
+	return cloneDrive(drv.meta), nil
+}
+
+// DeleteDrive implements drivefs.Backend.
+// Like Google Drive, a shared drive that contains any items, including trashed ones, cannot be deleted.
+func (d *Drive) DeleteDrive(ctx context.Context, driveID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	drv, err := d.getDrive(driveID)
+	if err != nil {
+		return err
+	}
+	for _, f := range d.files {
+		if slices.Contains(f.meta.Parents, drv.meta.Id) {
+			return newError(http.StatusForbidden, "cannotDeleteResourceWithChildren", "The shared drive cannot be deleted because it contains items.")
+		}
+	}
+	d.delete(drv.meta.Id)
+	delete(d.drives, drv.meta.Id)
+	return nil
+}
+
+func (d *Drive) getDrive(driveID string) (*sharedDrive, error) {
+	drv, ok := d.drives[driveID]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "notFound", "Shared drive not found: %s", driveID)
+	}
+	return drv, nil
+}
+
+func (d *Drive) nextID() string {
+	d.nextSeq++
+	return fmt.Sprintf("mem%08d", d.nextSeq)
+}
+
+// checkGeneratedID reports an error unless id is empty or has been generated by GenerateIDs and not used yet.
+func (d *Drive) checkGeneratedID(id string) error {
+	if id == "" {
+		return nil
+	}
+	if _, exists := d.files[id]; exists {
+		return newError(http.StatusConflict, "conflict", "A file already exists with the provided ID: %s.", id)
+	}
+	if !d.generated[id] {
+		return newError(http.StatusBadRequest, "fileIdNotUsable", "The provided file ID is not usable: %s.", id)
+	}
+	return nil
+}
+
+// newFile registers a file with meta, using meta.Id if it has been generated by GenerateIDs
+// and meta.ModifiedTime if it has been specified by the client.
+func (d *Drive) newFile(meta *drive.File) *file {
+	if meta.Id == "" {
+		meta.Id = d.nextID()
+	}
+	delete(d.generated, meta.Id)
+	now := d.timestamp()
+	meta.Kind = "drive#file"
+	meta.CreatedTime = now
+	if meta.ModifiedTime == "" {
+		meta.ModifiedTime = now
+	}
+	meta.WebViewLink = fmt.Sprintf(webViewLinkFileFormat, meta.Id)
+	d.created++
+	f := &file{seq: d.created, meta: meta}
+	owner := &drive.Permission{Kind: "drive#permission", Type: "user", Role: "owner", EmailAddress: d.user}
+	owner.Id = d.permissionID(owner)
+	f.permissions = []*drive.Permission{owner}
+	d.files[meta.Id] = f
+	d.recordChange(meta.Id)
+	return f
+}
+
+func (d *Drive) setContent(f *file, content []byte) {
+	sum := md5.Sum(content)
+	f.content = content
+	f.meta.Size = int64(len(content))
+	f.meta.Md5Checksum = hex.EncodeToString(sum[:])
+}
+
+// addRevision adds the current content of f as its latest revision, unless f has no binary content.
+func (d *Drive) addRevision(f *file) {
+	if strings.HasPrefix(f.meta.MimeType, mimeTypePrefixApp) {
+		return
+	}
+	sum := md5.Sum(f.content)
+	f.revSeq++
+	f.revisions = append(f.revisions, &revision{
+		meta: &drive.Revision{
+			Kind:         "drive#revision",
+			Id:           strconv.Itoa(f.revSeq),
+			MimeType:     f.meta.MimeType,
+			ModifiedTime: f.meta.ModifiedTime,
+			Size:         int64(len(f.content)),
+			Md5Checksum:  hex.EncodeToString(sum[:]),
+			LastModifyingUser: &drive.User{
+				Kind:         "drive#user",
+				DisplayName:  strings.Split(d.user, "@")[0],
+				EmailAddress: d.user,
+				Me:           true,
+			},
+		},
+		content: slices.Clone(f.content),
+	})
+}
+
+func (d *Drive) delete(id string) {
+	for _, child := range d.files {
+		if !slices.Contains(child.meta.Parents, id) {
+			continue
+		}
+		if len(child.meta.Parents) == 1 {
+			d.delete(child.meta.Id)
+		} else {
+			child.meta.Parents = slices.DeleteFunc(child.meta.Parents, func(p string) bool { return p == id })
+			d.recordChange(child.meta.Id)
+		}
+	}
+	// The change is recorded before the file is removed so that it is attributed to the drive of the file.
+	d.recordChange(id)
+	delete(d.files, id)
+}
+
+// recordDescendantChanges records a change to each descendant of the folder with the given id.
+func (d *Drive) recordDescendantChanges(id string) {
+	for _, child := range d.files {
+		if slices.Contains(child.meta.Parents, id) {
+			d.recordChange(child.meta.Id)
+			d.recordDescendantChanges(child.meta.Id)
+		}
+	}
+}
+
+// recordChange appends a change to the file with the given id to the log of changes.
+func (d *Drive) recordChange(id string) {
+	var driveID string
+	if f, ok := d.files[id]; ok {
+		driveID = d.driveOf(f)
+	}
+	d.lastChange[id] = len(d.changes)
+	d.changes = append(d.changes, change{fileID: id, time: d.timestamp(), driveID: driveID})
+}
+
+func (d *Drive) get(fileID string) (*file, error) {
+	f, ok := d.files[d.resolveID(fileID)]
+	if !ok {
+		return nil, newError(http.StatusNotFound, "notFound", "File not found: %s.", fileID)
+	}
+	return f, nil
+}
+
+func (d *Drive) resolveID(fileID string) string {
+	if fileID == rootAlias {
+		return d.rootID
+	}
+	return fileID
+}
+
+// isRoot reports whether f is the root folder of My Drive or of a shared drive.
+func (d *Drive) isRoot(f *file) bool {
+	_, ok := d.drives[f.meta.Id]
+	return ok || f.meta.Id == d.rootID
+}
+
+// driveOf returns the ID of the shared drive that contains f, or an empty string if f is in My Drive.
+func (d *Drive) driveOf(f *file) string {
+	if _, ok := d.drives[f.meta.Id]; ok {
+		return f.meta.Id
+	}
+	for _, parentID := range f.meta.Parents {
+		if parent, ok := d.files[parentID]; ok {
+			return d.driveOf(parent)
+		}
+	}
+	return ""
+}
+
+// checkDrive reports an error unless driveID is empty or the ID of an existing shared drive.
+func (d *Drive) checkDrive(driveID string) error {
+	if _, ok := d.drives[driveID]; driveID != "" && !ok {
+		return newError(http.StatusNotFound, "notFound", "Shared drive not found: %s", driveID)
+	}
+	return nil
+}
+
+// isTrashed reports whether f is trashed explicitly or because one of its ancestors is trashed.
+func (d *Drive) isTrashed(f *file) bool {
+	if f.meta.ExplicitlyTrashed {
+		return true
+	}
+	for _, parentID := range f.meta.Parents {
+		if parent, ok := d.files[parentID]; ok && d.isTrashed(parent) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAncestorOrSelf reports whether the file with the given id is f or one of its ancestors.
+func (d *Drive) isAncestorOrSelf(id string, f *file) bool {
+	if f.meta.Id == id {
+		return true
+	}
+	for _, parentID := range f.meta.Parents {
+		if parent, ok := d.files[parentID]; ok && d.isAncestorOrSelf(id, parent) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Drive) permissionID(perm *drive.Permission) string {
+	var key string
+	switch perm.Type {
+	case "anyone":
+		return "anyoneWithLink"
+	case "domain":
+		key = "domain:" + perm.Domain
+	default:
+		key = perm.Type + ":" + perm.EmailAddress
+	}
+	id, ok := d.permIDs[key]
+	if !ok {
+		id = fmt.Sprintf("%020d", len(d.permIDs)+1)
+		d.permIDs[key] = id
+	}
+	return id
+}
+
+// permissionsOf returns copies of the permissions of f as they would be returned by the Google Drive API,
+// which are those set on f itself and those inherited from the ancestors of f except their owners,
+// with PermissionDetails describing where each role comes from.
+// The permissions on the root folder of a shared drive are those of its members.
+func (d *Drive) permissionsOf(f *file) []*drive.Permission {
+	var perms []*drive.Permission
+	byID := map[string]*drive.Permission{}
+	add := func(from *file, p *drive.Permission, inherited bool) {
+		detail := &drive.PermissionPermissionDetails{PermissionType: "file", Role: p.Role, Inherited: inherited}
+		if _, ok := d.drives[from.meta.Id]; ok {
+			detail.PermissionType = "member"
+		}
+		if inherited {
+			detail.InheritedFrom = from.meta.Id
+		}
+		perm, ok := byID[p.Id]
+		if !ok {
+			perm = clonePermission(p)
+			byID[p.Id] = perm
+			perms = append(perms, perm)
+		} else if roleRank(p.Role) > roleRank(perm.Role) {
+			perm.Role = p.Role
+		}
+		perm.PermissionDetails = append(perm.PermissionDetails, detail)
+	}
+	for _, p := range f.permissions {
+		if !d.expired(p) {
+			add(f, p, false)
+		}
+	}
+	visited := map[string]bool{f.meta.Id: true}
+	ancestors := slices.Clone(f.meta.Parents)
+	for len(ancestors) > 0 {
+		id := ancestors[0]
+		ancestors = ancestors[1:]
+		a, ok := d.files[id]
+		if !ok || visited[id] {
+			continue
+		}
+		visited[id] = true
+		for _, p := range a.permissions {
+			if p.Role != "owner" && !d.expired(p) {
+				add(a, p, true)
+			}
+		}
+		ancestors = append(ancestors, a.meta.Parents...)
+	}
+	return perms
+}
+
+// checkOwnershipChange reports an error unless the ownership of f can be transferred to,
+// or requested to be accepted by, the grantee of perm.
+func (d *Drive) checkOwnershipChange(f *file, perm *drive.Permission) error {
+	if d.driveOf(f) != "" {
+		return newError(http.StatusForbidden, "teamDriveOwnershipTransferNotSupported", "Ownership of items in shared drives cannot be transferred.")
+	}
+	if perm.Type != "user" {
+		return newError(http.StatusBadRequest, "invalidSharingRequest", "Ownership can only be transferred to a user.")
+	}
+	if perm.PendingOwner && perm.Role != "writer" {
+		return newError(http.StatusBadRequest, "invalidSharingRequest", "Only writers can be pending owners.")
+	}
+	if !slices.ContainsFunc(f.permissions, func(p *drive.Permission) bool { return p.Role == "owner" && p.EmailAddress == d.user }) {
+		return newError(http.StatusForbidden, "insufficientFilePermissions", "The user does not have sufficient permissions for this file.")
+	}
+	return nil
+}
+
+// transferOwnership makes the grantee of the permission owner of f the only owner of f,
+// and the previous owner a writer.
+func (d *Drive) transferOwnership(f *file, owner *drive.Permission) {
+	owner.PendingOwner = false
+	for _, p := range f.permissions {
+		if p.Role == "owner" && p != owner {
+			p.Role = "writer"
+		}
+	}
+}
+
+// expired reports whether p has expired, so that it no longer exists.
+func (d *Drive) expired(p *drive.Permission) bool {
+	if p.ExpirationTime == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, p.ExpirationTime)
+	return err == nil && !t.After(d.now())
+}
+
+// validateExpiration reports an error unless expirationTime is empty
+// or a time in the future at which the permission p of a user or group can expire.
+func (d *Drive) validateExpiration(p *drive.Permission, expirationTime string) error {
+	if expirationTime == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, expirationTime)
+	if err != nil {
+		return newError(http.StatusBadRequest, "invalid", "Invalid Value: expirationTime %q", expirationTime)
+	}
+	if p.Type != "user" && p.Type != "group" || p.Role == "owner" || p.Role == "organizer" || p.Role == "fileOrganizer" {
+		return newError(http.StatusBadRequest, "invalidSharingRequest", "Expiration dates cannot be set on this permission.")
+	}
+	if !t.After(d.now()) {
+		return newError(http.StatusBadRequest, "invalidSharingRequest", "The expiration time must be in the future.")
+	}
+	return nil
+}
+
+// permissionOf returns a copy of the permission of f with the given ID, or nil if there is none.
+func (d *Drive) permissionOf(f *file, id string) *drive.Permission {
+	for _, perm := range d.permissionsOf(f) {
+		if perm.Id == id {
+			return perm
+		}
+	}
+	return nil
+}
+
+// view returns a copy of the metadata of f as it would be returned by the Google Drive API.
+func (d *Drive) view(f *file) *drive.File {
+	v := cloneFile(f.meta)
+	v.Trashed = d.isTrashed(f)
+	// Files in shared drives are owned by the shared drive.
+	v.DriveId = d.driveOf(f)
+	v.OwnedByMe = v.DriveId == "" && slices.ContainsFunc(f.permissions, func(p *drive.Permission) bool {
+		return p.Role == "owner" && p.EmailAddress == d.user
+	})
+	if f.meta.Id == d.rootID {
+		v.Parents = nil
+	}
+	for _, format := range drivefs.ExportFormats(f.meta.MimeType) {
+		if v.ExportLinks == nil {
+			v.ExportLinks = map[string]string{}
+		}
+		v.ExportLinks[format] = fmt.Sprintf(exportLinkFormat, f.meta.Id, url.QueryEscape(format))
+	}
+	if f.meta.MimeType == mimeTypeShortcut && v.ShortcutDetails != nil {
+		if target, ok := d.files[v.ShortcutDetails.TargetId]; ok {
+			v.ShortcutDetails.TargetMimeType = target.meta.MimeType
+		}
+	}
+	return v
+}
+
+func (d *Drive) timestamp() string {
+	return d.now().UTC().Format(time.RFC3339Nano)
+}
+
+func validatePermission(perm *drive.Permission) error {
+	switch perm.Type {
+	case "user", "group":
+		if perm.EmailAddress == "" {
+			return newError(http.StatusBadRequest, "required", "Required: emailAddress")
+		}
+	case "domain":
+		if perm.Domain == "" {
+			return newError(http.StatusBadRequest, "required", "Required: domain")
+		}
+	case "anyone":
+	default:
+		return newError(http.StatusBadRequest, "invalid", "Invalid Value: type %q", perm.Type)
+	}
+	switch perm.Role {
+	case "owner", "organizer", "fileOrganizer", "writer", "commenter", "reader":
+	default:
+		return newError(http.StatusBadRequest, "invalid", "Invalid Value: role %q", perm.Role)
+	}
+	return nil
+}
+
+// roleRank orders the roles by the access they grant.
+func roleRank(role string) int {
+	return slices.Index([]string{"reader", "commenter", "writer", "fileOrganizer", "organizer", "owner"}, role)
+}
+
+func readMedia(r io.Reader, opts []googleapi.MediaOption, progress func(int64)) (content []byte, contentType string, err error) {
+	if r == nil {
+		return nil, "", nil
+	}
+	content, err = io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if progress != nil {
+		progress(int64(len(content)))
+	}
+	contentType = googleapi.ProcessMediaOptions(opts).ContentType
+	if contentType == "" {
+		contentType, _, _ = strings.Cut(http.DetectContentType(content), ";")
+	}
+	return content, contentType, nil
+}
+
+func newError(code int, reason, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return &googleapi.Error{
+		Code:    code,
+		Message: msg,
+		Errors:  []googleapi.ErrorItem{{Reason: reason, Message: msg}},
+	}
+}
+
+func cloneFile(f *drive.File) *drive.File {
+	if f == nil {
+		return nil
+	}
+	return cloneJSON(f)
+}
+
+func clonePermission(p *drive.Permission) *drive.Permission {
+	return cloneJSON(p)
+}
+
+func cloneRevision(r *drive.Revision) *drive.Revision {
+	return cloneJSON(r)
+}
+
+func cloneDrive(drv *drive.Drive) *drive.Drive {
+	return cloneJSON(drv)
+}
+
+func cloneJSON[T any](v *T) *T {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var c T
+	if err := json.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return &c
+}
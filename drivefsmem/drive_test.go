@@ -0,0 +1,200 @@
+package drivefsmem_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const mimeTypeFolder = "application/vnd.google-apps.folder"
+
+func create(t *testing.T, d *drivefsmem.Drive, parentID, name, mimeType, content string) *drive.File {
+	t.Helper()
+	req := drivefs.CreateFileRequest{File: &drive.File{Name: name, MimeType: mimeType, Parents: []string{parentID}}}
+	if content != "" {
+		req.Media = strings.NewReader(content)
+	}
+	f, err := d.CreateFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+	return f
+}
+
+func listNames(t *testing.T, d *drivefsmem.Drive, q, orderBy string) []string {
+	t.Helper()
+	res, err := d.ListFiles(context.Background(), drivefs.ListFilesRequest{Query: q, OrderBy: orderBy})
+	if err != nil {
+		t.Fatalf("ListFiles(%q) error = %v", q, err)
+	}
+	var names []string
+	for _, f := range res.Files {
+		names = append(names, f.Name)
+	}
+	if orderBy == "" {
+		slices.Sort(names)
+	}
+	return names
+}
+
+func wantCode(t *testing.T, err error, code int) {
+	t.Helper()
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Code != code {
+		t.Fatalf("error = %v, want googleapi.Error with code %d", err, code)
+	}
+}
+
+func TestDrive_ListFiles_Query(t *testing.T) {
+	d := drivefsmem.New(drivefsmem.Options{UserEmail: "owner@example.com"})
+	root := string(d.RootID())
+	dir := create(t, d, "root", "dir", mimeTypeFolder, "")
+	create(t, d, root, "Report.txt", "text/plain", "hello world")
+	create(t, d, dir.Id, "it's.txt", "text/plain", "x")
+	trashed := create(t, d, dir.Id, "old.txt", "text/plain", "x")
+	if _, err := d.UpdateFile(context.Background(), drivefs.UpdateFileRequest{FileID: trashed.Id, File: &drive.File{Trashed: true}}); err != nil {
+		t.Fatalf("UpdateFile() error = %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"'root' in parents", []string{"Report.txt", "dir"}},
+		{"'" + dir.Id + "' in parents and trashed = false", []string{"it's.txt"}},
+		{"trashed = true", []string{"old.txt"}},
+		{`name = 'it\'s.txt'`, []string{"it's.txt"}},
+		{"name contains 'report'", []string{"Report.txt"}},
+		{"not mimeType = '" + mimeTypeFolder + "' and (name = 'dir' or name = 'Report.txt')", []string{"Report.txt"}},
+		{"fullText contains 'world'", []string{"Report.txt"}},
+		{"'owner@example.com' in owners and name = 'dir'", []string{"dir"}},
+		{"'other@example.com' in readers", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			if got := listNames(t, d, c.query, ""); !slices.Equal(got, c.want) {
+				t.Fatalf("ListFiles(%q) = %v, want %v", c.query, got, c.want)
+			}
+		})
+	}
+
+	_, err := d.ListFiles(context.Background(), drivefs.ListFilesRequest{Query: "name ~ 'x'"})
+	wantCode(t, err, http.StatusBadRequest)
+}
+
+func TestDrive_ListFiles_Paging(t *testing.T) {
+	d := drivefsmem.New(drivefsmem.Options{})
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		create(t, d, "root", name, "text/plain", "")
+	}
+	create(t, d, "root", "z", mimeTypeFolder, "")
+
+	var names []string
+	pageToken := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("ListFiles() returned too many pages")
+		}
+		res, err := d.ListFiles(context.Background(), drivefs.ListFilesRequest{PageSize: 2, PageToken: pageToken, OrderBy: "folder,name desc"})
+		if err != nil {
+			t.Fatalf("ListFiles() error = %v", err)
+		}
+		for _, f := range res.Files {
+			names = append(names, f.Name)
+		}
+		if pageToken = res.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+	if want := []string{"z", "e", "d", "c", "b", "a"}; !slices.Equal(names, want) {
+		t.Fatalf("ListFiles() = %v, want %v", names, want)
+	}
+}
+
+func TestDrive_Errors(t *testing.T) {
+	ctx := context.Background()
+	d := drivefsmem.New(drivefsmem.Options{})
+	dir := create(t, d, "root", "dir", mimeTypeFolder, "")
+	sub := create(t, d, dir.Id, "sub", mimeTypeFolder, "")
+	doc := create(t, d, "root", "doc", "application/vnd.google-apps.document", "")
+	blob := create(t, d, "root", "blob", "", "0123456789")
+
+	if blob.MimeType != "text/plain" || blob.Size != 10 || blob.Md5Checksum == "" {
+		t.Fatalf("CreateFile() = %+v, want detected MIME type, size and checksum", blob)
+	}
+
+	t.Run("GetFile not found", func(t *testing.T) {
+		_, err := d.GetFile(ctx, "missing")
+		wantCode(t, err, http.StatusNotFound)
+	})
+	t.Run("DownloadFile Google Apps file", func(t *testing.T) {
+		_, err := d.DownloadFile(ctx, drivefs.DownloadFileRequest{FileID: doc.Id, Length: -1})
+		wantCode(t, err, http.StatusForbidden)
+	})
+	t.Run("DownloadFile range", func(t *testing.T) {
+		body, err := d.DownloadFile(ctx, drivefs.DownloadFileRequest{FileID: blob.Id, Offset: 3, Length: 4})
+		if err != nil {
+			t.Fatalf("DownloadFile() error = %v", err)
+		}
+		defer body.Close()
+		if data, _ := io.ReadAll(body); string(data) != "3456" {
+			t.Fatalf("DownloadFile() = %q, want %q", data, "3456")
+		}
+		_, err = d.DownloadFile(ctx, drivefs.DownloadFileRequest{FileID: blob.Id, Offset: 10, Length: -1})
+		wantCode(t, err, http.StatusRequestedRangeNotSatisfiable)
+	})
+	t.Run("UpdateFile move into descendant", func(t *testing.T) {
+		_, err := d.UpdateFile(ctx, drivefs.UpdateFileRequest{FileID: dir.Id, File: &drive.File{}, AddParents: []string{sub.Id}})
+		wantCode(t, err, http.StatusBadRequest)
+	})
+	t.Run("UpdateFile rejected without change", func(t *testing.T) {
+		_, err := d.UpdateFile(ctx, drivefs.UpdateFileRequest{FileID: doc.Id, File: &drive.File{Name: "renamed", MimeType: "text/plain"}})
+		wantCode(t, err, http.StatusBadRequest)
+		_, err = d.UpdateFile(ctx, drivefs.UpdateFileRequest{FileID: "root", File: &drive.File{Name: "renamed", Trashed: true}})
+		wantCode(t, err, http.StatusForbidden)
+		for _, id := range []string{doc.Id, "root"} {
+			if f, err := d.GetFile(ctx, id); err != nil || f.Name == "renamed" {
+				t.Fatalf("GetFile() = %+v, %v, want the file unchanged", f, err)
+			}
+		}
+	})
+	t.Run("CopyFile folder", func(t *testing.T) {
+		_, err := d.CopyFile(ctx, drivefs.CopyFileRequest{FileID: dir.Id})
+		wantCode(t, err, http.StatusForbidden)
+	})
+	t.Run("CreateFile shortcut to missing target", func(t *testing.T) {
+		_, err := d.CreateFile(ctx, drivefs.CreateFileRequest{File: &drive.File{
+			Name:            "link",
+			MimeType:        "application/vnd.google-apps.shortcut",
+			ShortcutDetails: &drive.FileShortcutDetails{TargetId: "missing"},
+		}})
+		wantCode(t, err, http.StatusNotFound)
+	})
+	t.Run("CreatePermission owner", func(t *testing.T) {
+		_, err := d.CreatePermission(ctx, drivefs.CreatePermissionRequest{FileID: blob.Id, Permission: &drive.Permission{Type: "user", Role: "owner", EmailAddress: "x@example.com"}})
+		wantCode(t, err, http.StatusForbidden)
+	})
+	t.Run("DeleteFile recursive", func(t *testing.T) {
+		if err := d.DeleteFile(ctx, dir.Id); err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		_, err := d.GetFile(ctx, sub.Id)
+		wantCode(t, err, http.StatusNotFound)
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := d.GetFile(ctx, blob.Id); !errors.Is(err, context.Canceled) {
+			t.Fatalf("GetFile() error = %v, want context.Canceled", err)
+		}
+	})
+}
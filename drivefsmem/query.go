@@ -0,0 +1,452 @@
+package drivefsmem
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// query is a parsed search query in the Google Drive query syntax.
+// It reports whether a file matches.
+type query func(f *file) bool
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBrace
+	tokenRBrace
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(q string) ([]token, error) {
+	var tokens []token
+	rs := []rune(q)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")"})
+			i++
+		case r == '{':
+			tokens = append(tokens, token{tokenLBrace, "{"})
+			i++
+		case r == '}':
+			tokens = append(tokens, token{tokenRBrace, "}"})
+			i++
+		case r == '\'' || r == '"':
+			quote := r
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(rs) {
+					return nil, fmt.Errorf("unterminated string literal")
+				}
+				if rs[i] == '\\' && i+1 < len(rs) {
+					sb.WriteRune(rs[i+1])
+					i += 2
+					continue
+				}
+				if rs[i] == quote {
+					i++
+					break
+				}
+				sb.WriteRune(rs[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenString, sb.String()})
+		case strings.ContainsRune("=!<>", r):
+			op := string(r)
+			if i+1 < len(rs) && rs[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, token{tokenOperator, op})
+			i += len(op)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == ':':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || strings.ContainsRune("_.-:", rs[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, string(rs[i:j])})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	drive  *Drive
+}
+
+// parseQuery parses q, which must be evaluated while holding d.mu.
+func parseQuery(d *Drive, q string) (query, error) {
+	if strings.TrimSpace(q) == "" {
+		return func(*file) bool { return true }, nil
+	}
+	tokens, err := tokenize(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, drive: d}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected token %q", t.value)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.value, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f *file) bool { return l(f) || right(f) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (query, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(f *file) bool { return l(f) && right(f) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (query, error) {
+	if p.keyword("not") {
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(f *file) bool { return !q(f) }, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, fmt.Errorf("expected ')' but got %q", t.value)
+		}
+		return q, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *parser) parseTerm() (query, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		// 'value' in collection
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("expected 'in' after %q", t.value)
+		}
+		collection := p.next()
+		if collection.kind != tokenIdent {
+			return nil, fmt.Errorf("expected collection after 'in' but got %q", collection.value)
+		}
+		return p.membership(t.value, collection.value)
+	case tokenIdent:
+		field := t.value
+		if p.keyword("has") {
+			return p.parseHas(field)
+		}
+		op := p.next()
+		if op.kind == tokenIdent && strings.EqualFold(op.value, "contains") {
+			op.value = "contains"
+		} else if op.kind != tokenOperator {
+			return nil, fmt.Errorf("expected operator after %q but got %q", field, op.value)
+		}
+		value := p.next()
+		if value.kind != tokenString && value.kind != tokenIdent {
+			return nil, fmt.Errorf("expected value after %q but got %q", op.value, value.value)
+		}
+		return p.comparison(field, op.value, value)
+	default:
+		return nil, fmt.Errorf("unexpected token %q", t.value)
+	}
+}
+
+func (p *parser) parseHas(field string) (query, error) {
+	if field != "properties" && field != "appProperties" {
+		return nil, fmt.Errorf("'has' is not supported for %q", field)
+	}
+	if t := p.next(); t.kind != tokenLBrace {
+		return nil, fmt.Errorf("expected '{' but got %q", t.value)
+	}
+	var key, value string
+	for i := 0; i < 2; i++ {
+		name := p.next()
+		if op := p.next(); op.kind != tokenOperator || op.value != "=" {
+			return nil, fmt.Errorf("expected '=' but got %q", op.value)
+		}
+		v := p.next()
+		if v.kind != tokenString {
+			return nil, fmt.Errorf("expected string but got %q", v.value)
+		}
+		switch name.value {
+		case "key":
+			key = v.value
+		case "value":
+			value = v.value
+		default:
+			return nil, fmt.Errorf("unexpected %q in 'has' clause", name.value)
+		}
+		if i == 0 && !p.keyword("and") {
+			return nil, fmt.Errorf("expected 'and' in 'has' clause")
+		}
+	}
+	if t := p.next(); t.kind != tokenRBrace {
+		return nil, fmt.Errorf("expected '}' but got %q", t.value)
+	}
+	return func(f *file) bool {
+		props := f.meta.Properties
+		if field == "appProperties" {
+			props = f.meta.AppProperties
+		}
+		v, ok := props[key]
+		return ok && v == value
+	}, nil
+}
+
+func (p *parser) membership(value, collection string) (query, error) {
+	d := p.drive
+	switch collection {
+	case "parents":
+		parentID := d.resolveID(value)
+		return func(f *file) bool {
+			for _, parent := range f.meta.Parents {
+				if parent == parentID {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "owners", "writers", "readers":
+		return func(f *file) bool {
+			for _, perm := range f.permissions {
+				if perm.EmailAddress != value {
+					continue
+				}
+				switch collection {
+				case "owners":
+					if perm.Role == "owner" {
+						return true
+					}
+				case "writers":
+					if perm.Role == "owner" || perm.Role == "organizer" || perm.Role == "fileOrganizer" || perm.Role == "writer" {
+						return true
+					}
+				default:
+					return true
+				}
+			}
+			return false
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported collection %q", collection)
+	}
+}
+
+func (p *parser) comparison(field, op string, value token) (query, error) {
+	switch field {
+	case "name", "mimeType", "fullText", "shortcutDetails.targetId":
+		if value.kind != tokenString {
+			return nil, fmt.Errorf("expected string value for %q", field)
+		}
+		get := func(f *file) string {
+			switch field {
+			case "name":
+				return f.meta.Name
+			case "mimeType":
+				return f.meta.MimeType
+			case "shortcutDetails.targetId":
+				if f.meta.ShortcutDetails == nil {
+					return ""
+				}
+				return f.meta.ShortcutDetails.TargetId
+			default:
+				return f.meta.Name + "\n" + f.meta.Description + "\n" + string(f.content)
+			}
+		}
+		switch op {
+		case "=":
+			return func(f *file) bool { return get(f) == value.value }, nil
+		case "!=":
+			return func(f *file) bool { return get(f) != value.value }, nil
+		case "contains":
+			// Google Drive matches word prefixes; a case-insensitive substring match approximates it.
+			v := strings.ToLower(value.value)
+			return func(f *file) bool { return strings.Contains(strings.ToLower(get(f)), v) }, nil
+		}
+	case "trashed", "starred":
+		if value.kind != tokenIdent || (value.value != "true" && value.value != "false") {
+			return nil, fmt.Errorf("expected boolean value for %q", field)
+		}
+		want := value.value == "true"
+		get := func(f *file) bool {
+			if field == "trashed" {
+				return p.drive.isTrashed(f)
+			}
+			return f.meta.Starred
+		}
+		switch op {
+		case "=":
+			return func(f *file) bool { return get(f) == want }, nil
+		case "!=":
+			return func(f *file) bool { return get(f) != want }, nil
+		}
+	case "modifiedTime", "createdTime":
+		if value.kind != tokenString {
+			return nil, fmt.Errorf("expected string value for %q", field)
+		}
+		t, err := time.Parse(time.RFC3339Nano, value.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q: %w", value.value, err)
+		}
+		get := func(f *file) time.Time {
+			s := f.meta.ModifiedTime
+			if field == "createdTime" {
+				s = f.meta.CreatedTime
+			}
+			v, _ := time.Parse(time.RFC3339Nano, s)
+			return v
+		}
+		var cmp func(c int) bool
+		switch op {
+		case "=":
+			cmp = func(c int) bool { return c == 0 }
+		case "!=":
+			cmp = func(c int) bool { return c != 0 }
+		case "<":
+			cmp = func(c int) bool { return c < 0 }
+		case "<=":
+			cmp = func(c int) bool { return c <= 0 }
+		case ">":
+			cmp = func(c int) bool { return c > 0 }
+		case ">=":
+			cmp = func(c int) bool { return c >= 0 }
+		}
+		if cmp != nil {
+			return func(f *file) bool { return cmp(get(f).Compare(t)) }, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported field %q", field)
+	}
+	return nil, fmt.Errorf("unsupported operator %q for %q", op, field)
+}
+
+// parseOrderBy parses a comma-separated list of sort keys, each optionally followed by "desc",
+// into a comparison function of files.
+func parseOrderBy(orderBy string) (func(a, b *file) int, error) {
+	var cmps []func(a, b *file) int
+	for _, key := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(key)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 || (len(fields) == 2 && fields[1] != "desc" && fields[1] != "asc") {
+			return nil, fmt.Errorf("invalid sort key %q", key)
+		}
+		var cmp func(a, b *file) int
+		switch fields[0] {
+		case "name", "name_natural":
+			cmp = func(a, b *file) int { return strings.Compare(a.meta.Name, b.meta.Name) }
+		case "modifiedTime":
+			cmp = func(a, b *file) int { return compareTime(a.meta.ModifiedTime, b.meta.ModifiedTime) }
+		case "createdTime":
+			cmp = func(a, b *file) int { return compareTime(a.meta.CreatedTime, b.meta.CreatedTime) }
+		case "folder":
+			isFolder := func(f *file) int {
+				if f.meta.MimeType == mimeTypeFolder {
+					return 0
+				}
+				return 1
+			}
+			cmp = func(a, b *file) int { return isFolder(a) - isFolder(b) }
+		default:
+			return nil, fmt.Errorf("unsupported sort key %q", fields[0])
+		}
+		if len(fields) == 2 && fields[1] == "desc" {
+			asc := cmp
+			cmp = func(a, b *file) int { return asc(b, a) }
+		}
+		cmps = append(cmps, cmp)
+	}
+	return func(a, b *file) int {
+		for _, cmp := range cmps {
+			if c := cmp(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}, nil
+}
+
+func compareTime(a, b string) int {
+	ta, _ := time.Parse(time.RFC3339Nano, a)
+	tb, _ := time.Parse(time.RFC3339Nano, b)
+	return ta.Compare(tb)
+}
@@ -5,6 +5,10 @@
 package drivefsmust
 
 import (
+	"context"
+	"io"
+	"iter"
+
 	"github.com/Jumpaku/go-drivefs"
 	"google.golang.org/api/drive/v3"
 )
@@ -23,6 +27,29 @@ func New(service *drive.Service) *DriveFS {
 	return &DriveFS{driveFS: drivefs.New(service)}
 }
 
+// NewWithBackend creates a new DriveFS instance that performs all Google Drive operations through the given backend.
+func NewWithBackend(backend drivefs.Backend) *DriveFS {
+	return &DriveFS{driveFS: drivefs.NewWithBackend(backend)}
+}
+
+// WithRetry returns a DriveFS that performs the same operations as s
+// but retries every Google Drive API call according to policy.
+func (s *DriveFS) WithRetry(policy drivefs.RetryPolicy) *DriveFS {
+	return &DriveFS{driveFS: s.driveFS.WithRetry(policy)}
+}
+
+// WithCache returns a DriveFS that performs the same operations as s
+// but caches the lookups of files used to resolve paths according to opts.
+func (s *DriveFS) WithCache(opts drivefs.CacheOptions) *DriveFS {
+	return &DriveFS{driveFS: s.driveFS.WithCache(opts)}
+}
+
+// InvalidateCache removes the cached lookups of the files and directories with the given fileIDs,
+// or all cached lookups if no fileIDs are given.
+func (s *DriveFS) InvalidateCache(fileIDs ...drivefs.FileID) {
+	s.driveFS.InvalidateCache(fileIDs...)
+}
+
 // PermList lists all permissions for the file or directory with the given fileID.
 // Returns a slice of Permission objects representing the access permissions.
 //
@@ -31,6 +58,13 @@ func (s *DriveFS) PermList(fileID drivefs.FileID) (permissions []drivefs.Permiss
 	return must1(s.driveFS.PermList(fileID))
 }
 
+// PermListContext is like PermList but uses ctx for all Google Drive API calls.
+//
+// It panics if listing permissions fails for any reason.
+func (s *DriveFS) PermListContext(ctx context.Context, fileID drivefs.FileID) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.PermListContext(ctx, fileID))
+}
+
 // PermSet sets a permission for the file or directory with the given fileID.
 // If a permission for the same grantee already exists, it will be updated.
 // Otherwise, a new permission will be created.
@@ -41,6 +75,27 @@ func (s *DriveFS) PermSet(fileID drivefs.FileID, permission drivefs.Permission)
 	return must1(s.driveFS.PermSet(fileID, permission))
 }
 
+// PermSetContext is like PermSet but uses ctx for all Google Drive API calls.
+//
+// It panics if setting the permission fails.
+func (s *DriveFS) PermSetContext(ctx context.Context, fileID drivefs.FileID, permission drivefs.Permission) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.PermSetContext(ctx, fileID, permission))
+}
+
+// PermSetWithOptions is like PermSet but notifies the grantee of a new permission as configured by opts.
+//
+// It panics if setting the permission fails.
+func (s *DriveFS) PermSetWithOptions(fileID drivefs.FileID, permission drivefs.Permission, opts drivefs.PermSetOptions) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.PermSetWithOptions(fileID, permission, opts))
+}
+
+// PermSetWithOptionsContext is like PermSetWithOptions but uses ctx for all Google Drive API calls.
+//
+// It panics if setting the permission fails.
+func (s *DriveFS) PermSetWithOptionsContext(ctx context.Context, fileID drivefs.FileID, permission drivefs.Permission, opts drivefs.PermSetOptions) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.PermSetWithOptionsContext(ctx, fileID, permission, opts))
+}
+
 // PermDel deletes all permissions matching the given grantee for the file or directory with the given fileID.
 // Returns all remaining permissions after the operation.
 //
@@ -49,6 +104,77 @@ func (s *DriveFS) PermDel(fileID drivefs.FileID, grantee drivefs.Grantee) (permi
 	return must1(s.driveFS.PermDel(fileID, grantee))
 }
 
+// PermDelContext is like PermDel but uses ctx for all Google Drive API calls.
+//
+// It panics if deleting permissions fails.
+func (s *DriveFS) PermDelContext(ctx context.Context, fileID drivefs.FileID, grantee drivefs.Grantee) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.PermDelContext(ctx, fileID, grantee))
+}
+
+// PermSetAll sets a permission for the file or directory with the given rootID and all of its descendants,
+// skipping the items that inherit a permission for the same grantee with at least the same role.
+// Returns which items have been changed or skipped.
+//
+// It panics if setting the permissions fails.
+func (s *DriveFS) PermSetAll(rootID drivefs.FileID, permission drivefs.Permission, opts drivefs.PermTreeOptions) (result drivefs.PermTreeResult) {
+	return must1(s.driveFS.PermSetAll(rootID, permission, opts))
+}
+
+// PermSetAllContext is like PermSetAll but uses ctx for all Google Drive API calls.
+//
+// It panics if setting the permissions fails.
+func (s *DriveFS) PermSetAllContext(ctx context.Context, rootID drivefs.FileID, permission drivefs.Permission, opts drivefs.PermTreeOptions) (result drivefs.PermTreeResult) {
+	return must1(s.driveFS.PermSetAllContext(ctx, rootID, permission, opts))
+}
+
+// PermDelAll deletes all permissions matching the given grantee for the file or directory with the given rootID
+// and all of its descendants, except inherited ones.
+// Returns which items have been changed or skipped.
+//
+// It panics if deleting the permissions fails.
+func (s *DriveFS) PermDelAll(rootID drivefs.FileID, grantee drivefs.Grantee, opts drivefs.PermTreeOptions) (result drivefs.PermTreeResult) {
+	return must1(s.driveFS.PermDelAll(rootID, grantee, opts))
+}
+
+// PermDelAllContext is like PermDelAll but uses ctx for all Google Drive API calls.
+//
+// It panics if deleting the permissions fails.
+func (s *DriveFS) PermDelAllContext(ctx context.Context, rootID drivefs.FileID, grantee drivefs.Grantee, opts drivefs.PermTreeOptions) (result drivefs.PermTreeResult) {
+	return must1(s.driveFS.PermDelAllContext(ctx, rootID, grantee, opts))
+}
+
+// TransferOwnership transfers the ownership of the file or directory with the given fileID
+// to the user with the given newOwnerEmail, and the previous owner becomes a writer.
+// Returns all permissions after the operation.
+//
+// It panics if transferring the ownership fails.
+func (s *DriveFS) TransferOwnership(fileID drivefs.FileID, newOwnerEmail string, opts drivefs.TransferOptions) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.TransferOwnership(fileID, newOwnerEmail, opts))
+}
+
+// TransferOwnershipContext is like TransferOwnership but uses ctx for all Google Drive API calls.
+//
+// It panics if transferring the ownership fails.
+func (s *DriveFS) TransferOwnershipContext(ctx context.Context, fileID drivefs.FileID, newOwnerEmail string, opts drivefs.TransferOptions) (permissions []drivefs.Permission) {
+	return must1(s.driveFS.TransferOwnershipContext(ctx, fileID, newOwnerEmail, opts))
+}
+
+// TransferOwnershipAll transfers the ownership of the file or directory with the given rootID and all of its descendants
+// to the user with the given newOwnerEmail.
+// Returns which items have been transferred and which could not be transferred.
+//
+// It panics if the root does not exist or ctx is done.
+func (s *DriveFS) TransferOwnershipAll(rootID drivefs.FileID, newOwnerEmail string, opts drivefs.TransferOptions) (result drivefs.TransferResult) {
+	return must1(s.driveFS.TransferOwnershipAll(rootID, newOwnerEmail, opts))
+}
+
+// TransferOwnershipAllContext is like TransferOwnershipAll but uses ctx for all Google Drive API calls.
+//
+// It panics if the root does not exist or ctx is done.
+func (s *DriveFS) TransferOwnershipAllContext(ctx context.Context, rootID drivefs.FileID, newOwnerEmail string, opts drivefs.TransferOptions) (result drivefs.TransferResult) {
+	return must1(s.driveFS.TransferOwnershipAllContext(ctx, rootID, newOwnerEmail, opts))
+}
+
 // MkdirAll creates all directories along the given path if they do not already exist.
 // The path must be absolute (starting with '/') and is resolved from the specified rootID.
 // Returns the FileInfo of the final directory in the path.
@@ -58,6 +184,13 @@ func (s *DriveFS) MkdirAll(rootID drivefs.FileID, path drivefs.Path) (info drive
 	return must1(s.driveFS.MkdirAll(rootID, path))
 }
 
+// MkdirAllContext is like MkdirAll but uses ctx for all Google Drive API calls.
+//
+// It panics if an error occurs, including cases where two or more directories with the same name exist at any level.
+func (s *DriveFS) MkdirAllContext(ctx context.Context, rootID drivefs.FileID, path drivefs.Path) (info drivefs.FileInfo) {
+	return must1(s.driveFS.MkdirAllContext(ctx, rootID, path))
+}
+
 // Mkdir creates a single directory with the given name in the specified parent directory.
 // Returns the FileInfo of the created directory.
 //
@@ -66,6 +199,13 @@ func (s *DriveFS) Mkdir(parentID drivefs.FileID, name string) (info drivefs.File
 	return must1(s.driveFS.Mkdir(parentID, name))
 }
 
+// MkdirContext is like Mkdir but uses ctx for all Google Drive API calls.
+//
+// It panics if creating the directory fails.
+func (s *DriveFS) MkdirContext(ctx context.Context, parentID drivefs.FileID, name string) (info drivefs.FileInfo) {
+	return must1(s.driveFS.MkdirContext(ctx, parentID, name))
+}
+
 // ReadFile reads the entire contents of the file with the given fileID.
 // Returns the file data as a byte slice.
 //
@@ -75,6 +215,85 @@ func (s *DriveFS) ReadFile(fileID drivefs.FileID) (data []byte) {
 	return must1(s.driveFS.ReadFile(fileID))
 }
 
+// ReadFileContext is like ReadFile but uses ctx for all Google Drive API calls.
+//
+// It panics if reading the file fails for any reason,
+// including for Google Apps files (Docs, Sheets, etc.) that cannot be directly downloaded.
+func (s *DriveFS) ReadFileContext(ctx context.Context, fileID drivefs.FileID) (data []byte) {
+	return must1(s.driveFS.ReadFileContext(ctx, fileID))
+}
+
+// Open opens the file with the given fileID for streaming reads.
+// The returned FileReader implements io.ReadCloser, io.Seeker and io.ReaderAt and must be closed by the caller.
+//
+// It panics if opening the file fails, including for directories and Google Apps files
+// that cannot be directly downloaded.
+// Errors from the returned FileReader are returned as usual.
+func (s *DriveFS) Open(fileID drivefs.FileID) (r *drivefs.FileReader) {
+	return must1(s.driveFS.Open(fileID))
+}
+
+// OpenContext is like Open but uses ctx for all Google Drive API calls, including those made by the returned FileReader.
+//
+// It panics if opening the file fails, including for directories and Google Apps files
+// that cannot be directly downloaded.
+// Errors from the returned FileReader are returned as usual.
+func (s *DriveFS) OpenContext(ctx context.Context, fileID drivefs.FileID) (r *drivefs.FileReader) {
+	return must1(s.driveFS.OpenContext(ctx, fileID))
+}
+
+// Import uploads the data read from r until EOF as a new file with the given name in the specified parent directory,
+// converting it into a Google Apps file such as a Google Doc, Sheet or Slides presentation.
+// Returns the FileInfo of the converted file.
+//
+// It panics if the import fails, including when the data cannot be converted into opts.TargetMimeType.
+func (s *DriveFS) Import(parentID drivefs.FileID, name string, r io.Reader, opts drivefs.ImportOptions) (info drivefs.FileInfo) {
+	return must1(s.driveFS.Import(parentID, name, r, opts))
+}
+
+// ImportContext is like Import but uses ctx for all Google Drive API calls.
+//
+// It panics if the import fails, including when the data cannot be converted into opts.TargetMimeType.
+func (s *DriveFS) ImportContext(ctx context.Context, parentID drivefs.FileID, name string, r io.Reader, opts drivefs.ImportOptions) (info drivefs.FileInfo) {
+	return must1(s.driveFS.ImportContext(ctx, parentID, name, r, opts))
+}
+
+// Export exports the Google Apps file with the given fileID to the given MIME type and returns the exported data.
+// The available MIME types are listed by FileInfo.ExportFormats and drivefs.ExportFormats.
+//
+// It panics if exporting fails, including for files that are not Google Apps files
+// and for MIME types that are not supported for the file.
+func (s *DriveFS) Export(fileID drivefs.FileID, mimeType string) (data []byte) {
+	return must1(s.driveFS.Export(fileID, mimeType))
+}
+
+// ExportContext is like Export but uses ctx for all Google Drive API calls.
+//
+// It panics if exporting fails, including for files that are not Google Apps files
+// and for MIME types that are not supported for the file.
+func (s *DriveFS) ExportContext(ctx context.Context, fileID drivefs.FileID, mimeType string) (data []byte) {
+	return must1(s.driveFS.ExportContext(ctx, fileID, mimeType))
+}
+
+// OpenExport exports the Google Apps file with the given fileID to the given MIME type
+// and returns a reader that streams the exported data. The caller must close the returned reader.
+//
+// It panics if starting the export fails, including for files that are not Google Apps files
+// and for MIME types that are not supported for the file.
+// Errors from the returned reader are returned as usual.
+func (s *DriveFS) OpenExport(fileID drivefs.FileID, mimeType string) (r io.ReadCloser) {
+	return must1(s.driveFS.OpenExport(fileID, mimeType))
+}
+
+// OpenExportContext is like OpenExport but uses ctx for all Google Drive API calls made by the returned reader.
+//
+// It panics if starting the export fails, including for files that are not Google Apps files
+// and for MIME types that are not supported for the file.
+// Errors from the returned reader are returned as usual.
+func (s *DriveFS) OpenExportContext(ctx context.Context, fileID drivefs.FileID, mimeType string) (r io.ReadCloser) {
+	return must1(s.driveFS.OpenExportContext(ctx, fileID, mimeType))
+}
+
 // Remove deletes the file or directory with the given fileID.
 // If moveToTrash is true, the file is moved to trash; otherwise it is permanently deleted.
 // For directories, only empty directories can be removed.
@@ -85,6 +304,14 @@ func (s *DriveFS) Remove(fileID drivefs.FileID, moveToTrash bool) {
 	must0(s.driveFS.Remove(fileID, moveToTrash))
 }
 
+// RemoveContext is like Remove but uses ctx for all Google Drive API calls.
+//
+// It panics if removal fails for any reason, including when attempting to remove
+// a non-empty directory.
+func (s *DriveFS) RemoveContext(ctx context.Context, fileID drivefs.FileID, moveToTrash bool) {
+	must0(s.driveFS.RemoveContext(ctx, fileID, moveToTrash))
+}
+
 // RemoveAll deletes the file or directory with the given fileID, including all children if it is a directory.
 // If moveToTrash is true, the file is moved to trash; otherwise it is permanently deleted.
 //
@@ -93,6 +320,13 @@ func (s *DriveFS) RemoveAll(fileID drivefs.FileID, moveToTrash bool) {
 	must0(s.driveFS.RemoveAll(fileID, moveToTrash))
 }
 
+// RemoveAllContext is like RemoveAll but uses ctx for all Google Drive API calls.
+//
+// It panics if deletion fails for any reason.
+func (s *DriveFS) RemoveAllContext(ctx context.Context, fileID drivefs.FileID, moveToTrash bool) {
+	must0(s.driveFS.RemoveAllContext(ctx, fileID, moveToTrash))
+}
+
 // Move moves the file or directory with the given fileID to a new parent directory.
 //
 // It panics if the move fails, including if the file does not exist.
@@ -100,6 +334,13 @@ func (s *DriveFS) Move(fileID, newParentID drivefs.FileID) {
 	must0(s.driveFS.Move(fileID, newParentID))
 }
 
+// MoveContext is like Move but uses ctx for all Google Drive API calls.
+//
+// It panics if the move fails, including if the file does not exist.
+func (s *DriveFS) MoveContext(ctx context.Context, fileID, newParentID drivefs.FileID) {
+	must0(s.driveFS.MoveContext(ctx, fileID, newParentID))
+}
+
 // WriteFile writes data to the file with the given fileID, overwriting any existing content.
 //
 // It panics if writing the file fails for any reason.
@@ -107,6 +348,45 @@ func (s *DriveFS) WriteFile(fileID drivefs.FileID, data []byte) {
 	must0(s.driveFS.WriteFile(fileID, data))
 }
 
+// WriteFileContext is like WriteFile but uses ctx for all Google Drive API calls.
+//
+// It panics if writing the file fails for any reason.
+func (s *DriveFS) WriteFileContext(ctx context.Context, fileID drivefs.FileID, data []byte) {
+	must0(s.driveFS.WriteFileContext(ctx, fileID, data))
+}
+
+// WriteFrom writes the data read from r to the file with the given fileID until EOF,
+// overwriting any existing content, using the resumable-upload protocol.
+//
+// It panics if the upload fails.
+func (s *DriveFS) WriteFrom(fileID drivefs.FileID, r io.Reader, opts drivefs.WriteOptions) {
+	must0(s.driveFS.WriteFrom(fileID, r, opts))
+}
+
+// WriteFromContext is like WriteFrom but uses ctx for all Google Drive API calls.
+//
+// It panics if the upload fails.
+func (s *DriveFS) WriteFromContext(ctx context.Context, fileID drivefs.FileID, r io.Reader, opts drivefs.WriteOptions) {
+	must0(s.driveFS.WriteFromContext(ctx, fileID, r, opts))
+}
+
+// OpenWriter opens the file with the given fileID for streaming writes.
+// The content of the file is replaced by the data written to the returned FileWriter once it is closed successfully.
+//
+// It panics if opening the file fails, including if the file does not exist.
+// Errors from the returned FileWriter are returned as usual.
+func (s *DriveFS) OpenWriter(fileID drivefs.FileID, opts drivefs.WriteOptions) (w *drivefs.FileWriter) {
+	return must1(s.driveFS.OpenWriter(fileID, opts))
+}
+
+// OpenWriterContext is like OpenWriter but uses ctx for all Google Drive API calls made by the returned FileWriter.
+//
+// It panics if opening the file fails, including if the file does not exist.
+// Errors from the returned FileWriter are returned as usual.
+func (s *DriveFS) OpenWriterContext(ctx context.Context, fileID drivefs.FileID, opts drivefs.WriteOptions) (w *drivefs.FileWriter) {
+	return must1(s.driveFS.OpenWriterContext(ctx, fileID, opts))
+}
+
 // ReadDir reads the directory with the given fileID and returns a slice of FileInfo
 // for all files and subdirectories within it. Does not include trashed items.
 //
@@ -115,6 +395,13 @@ func (s *DriveFS) ReadDir(fileID drivefs.FileID) (children []drivefs.FileInfo) {
 	return must1(s.driveFS.ReadDir(fileID))
 }
 
+// ReadDirContext is like ReadDir but uses ctx for all Google Drive API calls.
+//
+// It panics if listing the directory fails.
+func (s *DriveFS) ReadDirContext(ctx context.Context, fileID drivefs.FileID) (children []drivefs.FileInfo) {
+	return must1(s.driveFS.ReadDirContext(ctx, fileID))
+}
+
 // Create creates a new empty file with the given name in the specified parent directory.
 // Returns the FileInfo of the created file.
 //
@@ -123,6 +410,13 @@ func (s *DriveFS) Create(parentID drivefs.FileID, name string) (info drivefs.Fil
 	return must1(s.driveFS.Create(parentID, name))
 }
 
+// CreateContext is like Create but uses ctx for all Google Drive API calls.
+//
+// It panics if creating the file fails.
+func (s *DriveFS) CreateContext(ctx context.Context, parentID drivefs.FileID, name string) (info drivefs.FileInfo) {
+	return must1(s.driveFS.CreateContext(ctx, parentID, name))
+}
+
 // Shortcut creates a new shortcut with the given name that points to the target file.
 // The shortcut is created in the specified parent directory.
 // Returns the FileInfo of the created shortcut.
@@ -132,6 +426,13 @@ func (s *DriveFS) Shortcut(parentID drivefs.FileID, name string, targetID drivef
 	return must1(s.driveFS.Shortcut(parentID, name, targetID))
 }
 
+// ShortcutContext is like Shortcut but uses ctx for all Google Drive API calls.
+//
+// It panics if creating the shortcut fails.
+func (s *DriveFS) ShortcutContext(ctx context.Context, parentID drivefs.FileID, name string, targetID drivefs.FileID) (info drivefs.FileInfo) {
+	return must1(s.driveFS.ShortcutContext(ctx, parentID, name, targetID))
+}
+
 // Info retrieves metadata for the file or directory with the given fileID.
 // Returns the FileInfo for the file or directory.
 //
@@ -140,6 +441,13 @@ func (s *DriveFS) Info(fileID drivefs.FileID) (info drivefs.FileInfo) {
 	return must1(s.driveFS.Info(fileID))
 }
 
+// InfoContext is like Info but uses ctx for all Google Drive API calls.
+//
+// It panics if retrieving metadata fails, including if the file does not exist.
+func (s *DriveFS) InfoContext(ctx context.Context, fileID drivefs.FileID) (info drivefs.FileInfo) {
+	return must1(s.driveFS.InfoContext(ctx, fileID))
+}
+
 // Copy creates a copy of the file with the given fileID.
 // The copy is placed in the specified parent directory with the given name.
 // Returns the FileInfo of the copied file.
@@ -149,6 +457,29 @@ func (s *DriveFS) Copy(fileID, newParentID drivefs.FileID, newName string) (info
 	return must1(s.driveFS.Copy(fileID, newParentID, newName))
 }
 
+// CopyContext is like Copy but uses ctx for all Google Drive API calls.
+//
+// It panics if copying the file fails.
+func (s *DriveFS) CopyContext(ctx context.Context, fileID, newParentID drivefs.FileID, newName string) (info drivefs.FileInfo) {
+	return must1(s.driveFS.CopyContext(ctx, fileID, newParentID, newName))
+}
+
+// CopyAll copies the file or directory with the given srcID, including all of its contents,
+// into the directory with the given dstParentID under newName, or under the name of the source if newName is empty.
+// Failures to copy items inside the tree are reported in CopyResult.Errors.
+//
+// It panics if the source cannot be copied.
+func (s *DriveFS) CopyAll(srcID, dstParentID drivefs.FileID, newName string, opts drivefs.CopyOptions) (result drivefs.CopyResult) {
+	return must1(s.driveFS.CopyAll(srcID, dstParentID, newName, opts))
+}
+
+// CopyAllContext is like CopyAll but uses ctx for all Google Drive API calls.
+//
+// It panics if the source cannot be copied.
+func (s *DriveFS) CopyAllContext(ctx context.Context, srcID, dstParentID drivefs.FileID, newName string, opts drivefs.CopyOptions) (result drivefs.CopyResult) {
+	return must1(s.driveFS.CopyAllContext(ctx, srcID, dstParentID, newName, opts))
+}
+
 // Rename changes the name of the file or directory with the given fileID.
 // Returns the updated FileInfo.
 //
@@ -157,6 +488,13 @@ func (s *DriveFS) Rename(fileID drivefs.FileID, newName string) (info drivefs.Fi
 	return must1(s.driveFS.Rename(fileID, newName))
 }
 
+// RenameContext is like Rename but uses ctx for all Google Drive API calls.
+//
+// It panics if renaming the file or directory fails.
+func (s *DriveFS) RenameContext(ctx context.Context, fileID drivefs.FileID, newName string) (info drivefs.FileInfo) {
+	return must1(s.driveFS.RenameContext(ctx, fileID, newName))
+}
+
 // Query executes a Google Drive API search query and returns matching files.
 // The query uses Google Drive's query syntax.
 // See https://developers.google.com/drive/api/guides/search-files for query syntax.
@@ -166,6 +504,27 @@ func (s *DriveFS) Query(query string) (results []drivefs.FileInfo) {
 	return must1(s.driveFS.Query(query))
 }
 
+// QueryContext is like Query but uses ctx for all Google Drive API calls.
+//
+// It panics if the query fails.
+func (s *DriveFS) QueryContext(ctx context.Context, query string) (results []drivefs.FileInfo) {
+	return must1(s.driveFS.QueryContext(ctx, query))
+}
+
+// QuerySeq is like Query but returns an iterator that fetches the pages of matching files lazily.
+//
+// The iterator panics if the query fails.
+func (s *DriveFS) QuerySeq(query string, pageOpts drivefs.PageOptions) iter.Seq[drivefs.FileInfo] {
+	return mustSeq(s.driveFS.QuerySeq(query, pageOpts))
+}
+
+// QuerySeqContext is like QuerySeq but uses ctx for all Google Drive API calls.
+//
+// The iterator panics if the query fails.
+func (s *DriveFS) QuerySeqContext(ctx context.Context, query string, pageOpts drivefs.PageOptions) iter.Seq[drivefs.FileInfo] {
+	return mustSeq(s.driveFS.QuerySeqContext(ctx, query, pageOpts))
+}
+
 // FindByPath resolves the given absolute path from the specified root directory.
 // Returns all files matching the path (multiple results if duplicates exist at any level).
 // The path must be absolute (starting with '/').
@@ -175,6 +534,13 @@ func (s *DriveFS) FindByPath(rootID drivefs.FileID, path drivefs.Path) (info []d
 	return must1(s.driveFS.FindByPath(rootID, path))
 }
 
+// FindByPathContext is like FindByPath but uses ctx for all Google Drive API calls.
+//
+// It panics if resolving the path fails.
+func (s *DriveFS) FindByPathContext(ctx context.Context, rootID drivefs.FileID, path drivefs.Path) (info []drivefs.FileInfo) {
+	return must1(s.driveFS.FindByPathContext(ctx, rootID, path))
+}
+
 // ResolvePath returns the absolute path from the root to the file with the given fileID.
 // The returned path is a slash-separated string (e.g., "/folder/subfolder/file").
 //
@@ -183,6 +549,13 @@ func (s *DriveFS) ResolvePath(fileID drivefs.FileID) (path drivefs.Path) {
 	return must1(s.driveFS.ResolvePath(fileID))
 }
 
+// ResolvePathContext is like ResolvePath but uses ctx for all Google Drive API calls.
+//
+// It panics if resolving the path fails, including if the file has multiple parents.
+func (s *DriveFS) ResolvePathContext(ctx context.Context, fileID drivefs.FileID) (path drivefs.Path) {
+	return must1(s.driveFS.ResolvePathContext(ctx, fileID))
+}
+
 // Walk traverses the file tree rooted at the given fileID.
 // For each file or directory (including the root), it calls the provided function with
 // the relative path and FileInfo.
@@ -191,3 +564,383 @@ func (s *DriveFS) ResolvePath(fileID drivefs.FileID) (path drivefs.Path) {
 func (s *DriveFS) Walk(rootID drivefs.FileID, f func(drivefs.Path, drivefs.FileInfo) error) {
 	must0(s.driveFS.Walk(rootID, f))
 }
+
+// WalkContext is like Walk but uses ctx for all Google Drive API calls.
+//
+// It panics if traversal fails or if the callback function returns an error.
+func (s *DriveFS) WalkContext(ctx context.Context, rootID drivefs.FileID, f func(drivefs.Path, drivefs.FileInfo) error) {
+	must0(s.driveFS.WalkContext(ctx, rootID, f))
+}
+
+// SyncUp makes the Google Drive folder with the given remoteFolderID mirror the local directory localDir,
+// uploading only new and changed files, and returns a summary of the changes.
+// See drivefs.DriveFS.SyncUp for details.
+//
+// It panics if reading the local directory or any Google Drive operation fails.
+func (s *DriveFS) SyncUp(localDir string, remoteFolderID drivefs.FileID, opts drivefs.SyncOptions) (result drivefs.SyncResult) {
+	return must1(s.driveFS.SyncUp(localDir, remoteFolderID, opts))
+}
+
+// SyncUpContext is like SyncUp but uses ctx for all Google Drive API calls.
+//
+// It panics if reading the local directory or any Google Drive operation fails.
+func (s *DriveFS) SyncUpContext(ctx context.Context, localDir string, remoteFolderID drivefs.FileID, opts drivefs.SyncOptions) (result drivefs.SyncResult) {
+	return must1(s.driveFS.SyncUpContext(ctx, localDir, remoteFolderID, opts))
+}
+
+// SyncDown makes the local directory localDir mirror the Google Drive folder with the given remoteFolderID,
+// downloading only new and changed files, and returns a summary of the changes.
+// See drivefs.DriveFS.SyncDown for details.
+//
+// It panics if writing the local directory or any Google Drive operation fails.
+func (s *DriveFS) SyncDown(remoteFolderID drivefs.FileID, localDir string, opts drivefs.SyncOptions) (result drivefs.SyncResult) {
+	return must1(s.driveFS.SyncDown(remoteFolderID, localDir, opts))
+}
+
+// SyncDownContext is like SyncDown but uses ctx for all Google Drive API calls.
+//
+// It panics if writing the local directory or any Google Drive operation fails.
+func (s *DriveFS) SyncDownContext(ctx context.Context, remoteFolderID drivefs.FileID, localDir string, opts drivefs.SyncOptions) (result drivefs.SyncResult) {
+	return must1(s.driveFS.SyncDownContext(ctx, remoteFolderID, localDir, opts))
+}
+
+// Bisync synchronizes the local directory localDir and the Google Drive folder with the given remoteFolderID
+// in both directions using the state persisted in a state file, and returns a summary of the changes.
+// See drivefs.DriveFS.Bisync for details.
+//
+// It panics if accessing the local directory or the state file, or any Google Drive operation fails.
+func (s *DriveFS) Bisync(localDir string, remoteFolderID drivefs.FileID, opts drivefs.BisyncOptions) (result drivefs.BisyncResult) {
+	return must1(s.driveFS.Bisync(localDir, remoteFolderID, opts))
+}
+
+// BisyncContext is like Bisync but uses ctx for all Google Drive API calls.
+//
+// It panics if accessing the local directory or the state file, or any Google Drive operation fails.
+func (s *DriveFS) BisyncContext(ctx context.Context, localDir string, remoteFolderID drivefs.FileID, opts drivefs.BisyncOptions) (result drivefs.BisyncResult) {
+	return must1(s.driveFS.BisyncContext(ctx, localDir, remoteFolderID, opts))
+}
+
+// Revisions lists the revisions of the file with the given fileID, from the oldest to the latest.
+// The latest revision is the current content of the file.
+//
+// It panics if listing the revisions fails, including when the file does not exist.
+func (s *DriveFS) Revisions(fileID drivefs.FileID) (revisions []drivefs.Revision) {
+	return must1(s.driveFS.Revisions(fileID))
+}
+
+// RevisionsContext is like Revisions but uses ctx for all Google Drive API calls.
+//
+// It panics if listing the revisions fails, including when the file does not exist.
+func (s *DriveFS) RevisionsContext(ctx context.Context, fileID drivefs.FileID) (revisions []drivefs.Revision) {
+	return must1(s.driveFS.RevisionsContext(ctx, fileID))
+}
+
+// ReadRevision reads the content of the revision with the given revisionID of the file with the given fileID.
+//
+// It panics if reading the revision fails for any reason,
+// including for Google Apps files whose revisions cannot be directly downloaded.
+func (s *DriveFS) ReadRevision(fileID drivefs.FileID, revisionID drivefs.RevisionID) (data []byte) {
+	return must1(s.driveFS.ReadRevision(fileID, revisionID))
+}
+
+// ReadRevisionContext is like ReadRevision but uses ctx for all Google Drive API calls.
+//
+// It panics if reading the revision fails for any reason,
+// including for Google Apps files whose revisions cannot be directly downloaded.
+func (s *DriveFS) ReadRevisionContext(ctx context.Context, fileID drivefs.FileID, revisionID drivefs.RevisionID) (data []byte) {
+	return must1(s.driveFS.ReadRevisionContext(ctx, fileID, revisionID))
+}
+
+// KeepRevision pins the revision with the given revisionID of the file with the given fileID
+// so that it is kept forever if keepForever is true, or unpins it otherwise.
+// Returns the Revision after the update.
+//
+// It panics if updating the revision fails, including when the file or the revision does not exist.
+func (s *DriveFS) KeepRevision(fileID drivefs.FileID, revisionID drivefs.RevisionID, keepForever bool) (revision drivefs.Revision) {
+	return must1(s.driveFS.KeepRevision(fileID, revisionID, keepForever))
+}
+
+// KeepRevisionContext is like KeepRevision but uses ctx for all Google Drive API calls.
+//
+// It panics if updating the revision fails, including when the file or the revision does not exist.
+func (s *DriveFS) KeepRevisionContext(ctx context.Context, fileID drivefs.FileID, revisionID drivefs.RevisionID, keepForever bool) (revision drivefs.Revision) {
+	return must1(s.driveFS.KeepRevisionContext(ctx, fileID, revisionID, keepForever))
+}
+
+// DeleteRevision permanently deletes the revision with the given revisionID of the file with the given fileID.
+//
+// It panics if the deletion fails, including when the revision is the latest one or does not exist.
+func (s *DriveFS) DeleteRevision(fileID drivefs.FileID, revisionID drivefs.RevisionID) {
+	must0(s.driveFS.DeleteRevision(fileID, revisionID))
+}
+
+// DeleteRevisionContext is like DeleteRevision but uses ctx for all Google Drive API calls.
+//
+// It panics if the deletion fails, including when the revision is the latest one or does not exist.
+func (s *DriveFS) DeleteRevisionContext(ctx context.Context, fileID drivefs.FileID, revisionID drivefs.RevisionID) {
+	must0(s.driveFS.DeleteRevisionContext(ctx, fileID, revisionID))
+}
+
+// RestoreRevision makes the content of the revision with the given revisionID the current content
+// of the file with the given fileID by uploading it again.
+// Returns the FileInfo of the file after the restoration.
+//
+// It panics if reading the revision or uploading its content fails for any reason.
+func (s *DriveFS) RestoreRevision(fileID drivefs.FileID, revisionID drivefs.RevisionID) (info drivefs.FileInfo) {
+	return must1(s.driveFS.RestoreRevision(fileID, revisionID))
+}
+
+// RestoreRevisionContext is like RestoreRevision but uses ctx for all Google Drive API calls.
+//
+// It panics if reading the revision or uploading its content fails for any reason.
+func (s *DriveFS) RestoreRevisionContext(ctx context.Context, fileID drivefs.FileID, revisionID drivefs.RevisionID) (info drivefs.FileInfo) {
+	return must1(s.driveFS.RestoreRevisionContext(ctx, fileID, revisionID))
+}
+
+// ReadDirWithOptions is like ReadDir but lists the files selected by opts.
+//
+// It panics if listing the directory contents fails for any reason.
+func (s *DriveFS) ReadDirWithOptions(fileID drivefs.FileID, opts drivefs.ListOptions) (children []drivefs.FileInfo) {
+	return must1(s.driveFS.ReadDirWithOptions(fileID, opts))
+}
+
+// ReadDirWithOptionsContext is like ReadDirWithOptions but uses ctx for all Google Drive API calls.
+//
+// It panics if listing the directory contents fails for any reason.
+func (s *DriveFS) ReadDirWithOptionsContext(ctx context.Context, fileID drivefs.FileID, opts drivefs.ListOptions) (children []drivefs.FileInfo) {
+	return must1(s.driveFS.ReadDirWithOptionsContext(ctx, fileID, opts))
+}
+
+// ReadDirSeq is like ReadDirWithOptions but returns an iterator that fetches the pages of children lazily.
+//
+// The iterator panics if listing the directory fails.
+func (s *DriveFS) ReadDirSeq(fileID drivefs.FileID, opts drivefs.ListOptions, pageOpts drivefs.PageOptions) iter.Seq[drivefs.FileInfo] {
+	return mustSeq(s.driveFS.ReadDirSeq(fileID, opts, pageOpts))
+}
+
+// ReadDirSeqContext is like ReadDirSeq but uses ctx for all Google Drive API calls.
+//
+// The iterator panics if listing the directory fails.
+func (s *DriveFS) ReadDirSeqContext(ctx context.Context, fileID drivefs.FileID, opts drivefs.ListOptions, pageOpts drivefs.PageOptions) iter.Seq[drivefs.FileInfo] {
+	return mustSeq(s.driveFS.ReadDirSeqContext(ctx, fileID, opts, pageOpts))
+}
+
+// WalkWithOptions is like Walk but visits the files selected by opts.
+//
+// It panics if walking fails for any reason, including errors returned by f.
+func (s *DriveFS) WalkWithOptions(rootID drivefs.FileID, opts drivefs.ListOptions, f func(drivefs.Path, drivefs.FileInfo) error) {
+	must0(s.driveFS.WalkWithOptions(rootID, opts, f))
+}
+
+// WalkWithOptionsContext is like WalkWithOptions but uses ctx for all Google Drive API calls.
+//
+// It panics if walking fails for any reason, including errors returned by f.
+func (s *DriveFS) WalkWithOptionsContext(ctx context.Context, rootID drivefs.FileID, opts drivefs.ListOptions, f func(drivefs.Path, drivefs.FileInfo) error) {
+	must0(s.driveFS.WalkWithOptionsContext(ctx, rootID, opts, f))
+}
+
+// WalkParallel is like WalkWithOptions but lists up to opts.Concurrency directories at the same time.
+// The calls of f are serialized, and f can return fs.SkipDir or fs.SkipAll to skip items.
+//
+// It panics if walking fails for any reason, including errors returned by f.
+func (s *DriveFS) WalkParallel(rootID drivefs.FileID, opts drivefs.WalkOptions, f func(drivefs.Path, drivefs.FileInfo) error) {
+	must0(s.driveFS.WalkParallel(rootID, opts, f))
+}
+
+// WalkParallelContext is like WalkParallel but uses ctx for all Google Drive API calls.
+//
+// It panics if walking fails for any reason, including errors returned by f.
+func (s *DriveFS) WalkParallelContext(ctx context.Context, rootID drivefs.FileID, opts drivefs.WalkOptions, f func(drivefs.Path, drivefs.FileInfo) error) {
+	must0(s.driveFS.WalkParallelContext(ctx, rootID, opts, f))
+}
+
+// Snapshot enumerates the file or directory with the given rootID and all of its descendants
+// with a few bulk requests and returns an in-memory index of them.
+//
+// It panics if the root does not exist or listing the files fails.
+func (s *DriveFS) Snapshot(rootID drivefs.FileID) (snapshot *drivefs.Snapshot) {
+	return must1(s.driveFS.Snapshot(rootID))
+}
+
+// SnapshotContext is like Snapshot but uses ctx for all Google Drive API calls.
+//
+// It panics if the root does not exist or listing the files fails.
+func (s *DriveFS) SnapshotContext(ctx context.Context, rootID drivefs.FileID) (snapshot *drivefs.Snapshot) {
+	return must1(s.driveFS.SnapshotContext(ctx, rootID))
+}
+
+// ListTrash lists the items that have been moved to the trash explicitly, selected by opts.
+//
+// It panics if listing the trash fails for any reason.
+func (s *DriveFS) ListTrash(opts drivefs.TrashOptions) (items []drivefs.FileInfo) {
+	return must1(s.driveFS.ListTrash(opts))
+}
+
+// ListTrashContext is like ListTrash but uses ctx for all Google Drive API calls.
+//
+// It panics if listing the trash fails for any reason.
+func (s *DriveFS) ListTrashContext(ctx context.Context, opts drivefs.TrashOptions) (items []drivefs.FileInfo) {
+	return must1(s.driveFS.ListTrashContext(ctx, opts))
+}
+
+// Restore restores the file or directory with the given fileID from the trash,
+// into opts.FallbackParentID if none of its original parents is available and it is not empty.
+// See drivefs.DriveFS.Restore for details.
+//
+// It panics if the restoration fails, including when the file does not exist.
+func (s *DriveFS) Restore(fileID drivefs.FileID, opts drivefs.RestoreOptions) (result drivefs.RestoreResult) {
+	return must1(s.driveFS.Restore(fileID, opts))
+}
+
+// RestoreContext is like Restore but uses ctx for all Google Drive API calls.
+//
+// It panics if the restoration fails, including when the file does not exist.
+func (s *DriveFS) RestoreContext(ctx context.Context, fileID drivefs.FileID, opts drivefs.RestoreOptions) (result drivefs.RestoreResult) {
+	return must1(s.driveFS.RestoreContext(ctx, fileID, opts))
+}
+
+// EmptyTrash permanently deletes all items in the trash of My Drive if driveID is empty,
+// or in the trash of the shared drive with the given driveID otherwise.
+//
+// It panics if emptying the trash fails.
+func (s *DriveFS) EmptyTrash(driveID string) {
+	must0(s.driveFS.EmptyTrash(driveID))
+}
+
+// EmptyTrashContext is like EmptyTrash but uses ctx for all Google Drive API calls.
+//
+// It panics if emptying the trash fails.
+func (s *DriveFS) EmptyTrashContext(ctx context.Context, driveID string) {
+	must0(s.driveFS.EmptyTrashContext(ctx, driveID))
+}
+
+// ListSharedDrives lists the shared drives of which the user is a member, including hidden ones,
+// selected by opts.
+//
+// It panics if listing the shared drives fails for any reason.
+func (s *DriveFS) ListSharedDrives(opts drivefs.SharedDriveListOptions) (sharedDrives []drivefs.SharedDrive) {
+	return must1(s.driveFS.ListSharedDrives(opts))
+}
+
+// ListSharedDrivesContext is like ListSharedDrives but uses ctx for all Google Drive API calls.
+//
+// It panics if listing the shared drives fails for any reason.
+func (s *DriveFS) ListSharedDrivesContext(ctx context.Context, opts drivefs.SharedDriveListOptions) (sharedDrives []drivefs.SharedDrive) {
+	return must1(s.driveFS.ListSharedDrivesContext(ctx, opts))
+}
+
+// SharedDriveInfo returns the SharedDrive with the given driveID.
+//
+// It panics if getting the shared drive fails, including when it does not exist.
+func (s *DriveFS) SharedDriveInfo(driveID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.SharedDriveInfo(driveID))
+}
+
+// SharedDriveInfoContext is like SharedDriveInfo but uses ctx for all Google Drive API calls.
+//
+// It panics if getting the shared drive fails, including when it does not exist.
+func (s *DriveFS) SharedDriveInfoContext(ctx context.Context, driveID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.SharedDriveInfoContext(ctx, driveID))
+}
+
+// SharedDriveRoot returns the ID of the root folder of the shared drive with the given driveID,
+// which can be passed to methods that accept a rootID or parentID, such as MkdirAll and FindByPath.
+//
+// It panics if getting the shared drive fails, including when it does not exist.
+func (s *DriveFS) SharedDriveRoot(driveID string) (rootID drivefs.FileID) {
+	return must1(s.driveFS.SharedDriveRoot(driveID))
+}
+
+// SharedDriveRootContext is like SharedDriveRoot but uses ctx for all Google Drive API calls.
+//
+// It panics if getting the shared drive fails, including when it does not exist.
+func (s *DriveFS) SharedDriveRootContext(ctx context.Context, driveID string) (rootID drivefs.FileID) {
+	return must1(s.driveFS.SharedDriveRootContext(ctx, driveID))
+}
+
+// CreateSharedDrive creates a shared drive with the given name and returns its SharedDrive.
+// See drivefs.DriveFS.CreateSharedDrive for the use of requestID.
+//
+// It panics if creating the shared drive fails, including when requestID has already been used.
+func (s *DriveFS) CreateSharedDrive(name, requestID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.CreateSharedDrive(name, requestID))
+}
+
+// CreateSharedDriveContext is like CreateSharedDrive but uses ctx for all Google Drive API calls.
+//
+// It panics if creating the shared drive fails, including when requestID has already been used.
+func (s *DriveFS) CreateSharedDriveContext(ctx context.Context, name, requestID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.CreateSharedDriveContext(ctx, name, requestID))
+}
+
+// RenameSharedDrive renames the shared drive with the given driveID to newName and returns its SharedDrive.
+//
+// It panics if renaming fails, including when the shared drive does not exist.
+func (s *DriveFS) RenameSharedDrive(driveID, newName string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.RenameSharedDrive(driveID, newName))
+}
+
+// RenameSharedDriveContext is like RenameSharedDrive but uses ctx for all Google Drive API calls.
+//
+// It panics if renaming fails, including when the shared drive does not exist.
+func (s *DriveFS) RenameSharedDriveContext(ctx context.Context, driveID, newName string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.RenameSharedDriveContext(ctx, driveID, newName))
+}
+
+// SetSharedDriveRestrictions replaces the restrictions of the shared drive with the given driveID
+// and returns its SharedDrive.
+//
+// It panics if updating the shared drive fails, including when it does not exist.
+func (s *DriveFS) SetSharedDriveRestrictions(driveID string, restrictions drivefs.SharedDriveRestrictions) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.SetSharedDriveRestrictions(driveID, restrictions))
+}
+
+// SetSharedDriveRestrictionsContext is like SetSharedDriveRestrictions but uses ctx for all Google Drive API calls.
+//
+// It panics if updating the shared drive fails, including when it does not exist.
+func (s *DriveFS) SetSharedDriveRestrictionsContext(ctx context.Context, driveID string, restrictions drivefs.SharedDriveRestrictions) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.SetSharedDriveRestrictionsContext(ctx, driveID, restrictions))
+}
+
+// HideSharedDrive hides the shared drive with the given driveID from the default view and returns its SharedDrive.
+//
+// It panics if hiding fails, including when the shared drive does not exist.
+func (s *DriveFS) HideSharedDrive(driveID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.HideSharedDrive(driveID))
+}
+
+// HideSharedDriveContext is like HideSharedDrive but uses ctx for all Google Drive API calls.
+//
+// It panics if hiding fails, including when the shared drive does not exist.
+func (s *DriveFS) HideSharedDriveContext(ctx context.Context, driveID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.HideSharedDriveContext(ctx, driveID))
+}
+
+// UnhideSharedDrive restores the hidden shared drive with the given driveID to the default view
+// and returns its SharedDrive.
+//
+// It panics if unhiding fails, including when the shared drive does not exist.
+func (s *DriveFS) UnhideSharedDrive(driveID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.UnhideSharedDrive(driveID))
+}
+
+// UnhideSharedDriveContext is like UnhideSharedDrive but uses ctx for all Google Drive API calls.
+//
+// It panics if unhiding fails, including when the shared drive does not exist.
+func (s *DriveFS) UnhideSharedDriveContext(ctx context.Context, driveID string) (sharedDrive drivefs.SharedDrive) {
+	return must1(s.driveFS.UnhideSharedDriveContext(ctx, driveID))
+}
+
+// DeleteSharedDrive permanently deletes the shared drive with the given driveID, which must be empty.
+//
+// It panics if the deletion fails, including when the shared drive does not exist or is not empty.
+func (s *DriveFS) DeleteSharedDrive(driveID string) {
+	must0(s.driveFS.DeleteSharedDrive(driveID))
+}
+
+// DeleteSharedDriveContext is like DeleteSharedDrive but uses ctx for all Google Drive API calls.
+//
+// It panics if the deletion fails, including when the shared drive does not exist or is not empty.
+func (s *DriveFS) DeleteSharedDriveContext(ctx context.Context, driveID string) {
+	must0(s.driveFS.DeleteSharedDriveContext(ctx, driveID))
+}
@@ -1,5 +1,7 @@
 package drivefsmust
 
+import "iter"
+
 func must0(err error) {
 	if err != nil {
 		panic(err)
@@ -12,3 +14,15 @@ func must1[T any](t T, err error) T {
 	}
 	return t
 }
+
+// mustSeq returns an iterator over the values of seq that panics when seq yields an error.
+func mustSeq[T any](seq iter.Seq2[T, error]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for t, err := range seq {
+			must0(err)
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
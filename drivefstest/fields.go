@@ -0,0 +1,110 @@
+package drivefstest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldMask is a parsed partial response selector such as "nextPageToken,files(id,name)".
+// A nil sub-mask selects the whole value of the field.
+type fieldMask map[string]fieldMask
+
+func parseFieldMask(s string) (fieldMask, error) {
+	p := &maskParser{s: strings.ReplaceAll(s, " ", "")}
+	m := fieldMask{}
+	if err := p.parseList(m); err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("invalid fields %q: unexpected %q", s, p.s[p.pos:])
+	}
+	return m, nil
+}
+
+type maskParser struct {
+	s   string
+	pos int
+}
+
+func (p *maskParser) parseList(m fieldMask) error {
+	for {
+		if err := p.parseItem(m); err != nil {
+			return err
+		}
+		if p.pos >= len(p.s) || p.s[p.pos] != ',' {
+			return nil
+		}
+		p.pos++
+	}
+}
+
+func (p *maskParser) parseItem(m fieldMask) error {
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(",/()", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	name := p.s[start:p.pos]
+	if name == "" {
+		return fmt.Errorf("invalid fields %q: empty field name at %d", p.s, start)
+	}
+	if p.pos >= len(p.s) || (p.s[p.pos] != '/' && p.s[p.pos] != '(') {
+		m[name] = nil
+		return nil
+	}
+	whole := false
+	sub, ok := m[name]
+	if ok && sub == nil {
+		// The whole field is already selected; the nested selection is parsed but has no effect.
+		whole = true
+	}
+	if sub == nil {
+		sub = fieldMask{}
+	}
+	if p.s[p.pos] == '/' {
+		p.pos++
+		if err := p.parseItem(sub); err != nil {
+			return err
+		}
+	} else {
+		p.pos++
+		if err := p.parseList(sub); err != nil {
+			return err
+		}
+		if p.pos >= len(p.s) || p.s[p.pos] != ')' {
+			return fmt.Errorf("invalid fields %q: missing ')'", p.s)
+		}
+		p.pos++
+	}
+	if !whole {
+		m[name] = sub
+	}
+	return nil
+}
+
+// apply returns v, which is a value decoded from JSON, restricted to the selected fields.
+func (m fieldMask) apply(v any) any {
+	if m == nil {
+		return v
+	}
+	if _, ok := m["*"]; ok {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		out := map[string]any{}
+		for k, sub := range m {
+			if fv, ok := v[k]; ok {
+				out[k] = sub.apply(fv)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = m.apply(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
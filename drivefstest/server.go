@@ -0,0 +1,1051 @@
+// Package drivefstest provides an HTTP server that emulates the subset of the Google Drive API v3
+// used by drivefs, for integration tests without network access.
+//
+// The server is backed by an in-memory drivefsmem.Drive and speaks the REST protocol of the
+// Google Drive API, including media downloads with Range requests, multipart and resumable uploads,
+// partial responses selected by the fields parameter, pagination with nextPageToken,
+// search queries in the q parameter and error payloads.
+// A *drive.Service pointed at the server with NewService or ClientOptions exercises the same HTTP code paths
+// as it does against Google Drive.
+package drivefstest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const (
+	apiPrefix    = "/drive/v3/"
+	uploadPrefix = "/upload/drive/v3/"
+
+	defaultFileFields       = "kind,id,name,mimeType"
+	defaultFileListFields   = "kind,nextPageToken,incompleteSearch,files(kind,id,name,mimeType)"
+	defaultPermissionFields = "kind,id,type,role"
+	defaultPermListFields   = "kind,nextPageToken,permissions(kind,id,type,role)"
+	defaultRevisionFields   = "kind,id,mimeType,modifiedTime"
+	defaultRevListFields    = "kind,nextPageToken,revisions(kind,id,mimeType,modifiedTime)"
+	defaultChangeListFields = "kind,nextPageToken,newStartPageToken,changes(kind,changeType,time,removed,fileId,file(kind,id,name,mimeType))"
+	defaultDriveFields      = "kind,id,name"
+	defaultDriveListFields  = "kind,nextPageToken,drives(kind,id,name)"
+)
+
+// Options configures a Server created by NewServer.
+type Options struct {
+	// Drive configures the in-memory Drive that backs the server.
+	Drive drivefsmem.Options
+
+	// MaxPageSize caps the number of items in a page of files.list, permissions.list, revisions.list, changes.list
+	// and drives.list.
+	// Zero means the limits of the in-memory Drive.
+	// Small values are useful to exercise pagination.
+	MaxPageSize int64
+}
+
+// Fault is an error response that the server returns instead of serving matching requests.
+type Fault struct {
+	// Method matches the HTTP method of requests. Empty matches any method.
+	Method string
+
+	// Path matches requests whose URL path starts with it, such as "/drive/v3/files" or "/upload/drive/v3/files".
+	// Empty matches any path.
+	Path string
+
+	// Code is the HTTP status code of the error response, such as 403 or 429.
+	Code int
+
+	// Reason is the reason of the error, such as "rateLimitExceeded" or "userRateLimitExceeded".
+	// Empty means a typical reason for Code.
+	Reason string
+
+	// RetryAfter, if positive, is sent in the Retry-After header.
+	RetryAfter time.Duration
+
+	// Times is the number of matching requests that fail. Zero or less means one.
+	Times int
+}
+
+// Request is a request received by the server.
+type Request struct {
+	// Method is the HTTP method of the request.
+	Method string
+
+	// Path is the URL path of the request.
+	Path string
+
+	// Query is the URL query parameters of the request.
+	Query url.Values
+}
+
+// Server is an HTTP server that emulates the Google Drive API v3.
+// It must be closed by Close after use.
+type Server struct {
+	// URL is the base URL of the server, of the form http://ipaddr:port with no trailing slash.
+	URL string
+
+	drive       *drivefsmem.Drive
+	maxPageSize int64
+	server      *httptest.Server
+
+	mu       sync.Mutex
+	faults   []*Fault
+	requests []Request
+	sessions map[string]*uploadSession
+}
+
+type uploadSession struct {
+	fileID      string
+	file        *drive.File
+	contentType string
+	content     []byte
+	params      url.Values
+}
+
+// NewServer starts and returns a new Server backed by an empty in-memory Drive.
+func NewServer(opts Options) *Server {
+	s := &Server{
+		drive:       drivefsmem.New(opts.Drive),
+		maxPageSize: opts.MaxPageSize,
+		sessions:    map[string]*uploadSession{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+apiPrefix+"files", s.listFiles)
+	mux.HandleFunc("POST "+apiPrefix+"files", s.createFile)
+	mux.HandleFunc("GET "+apiPrefix+"files/generateIds", s.generateIDs)
+	mux.HandleFunc("GET "+apiPrefix+"files/{fileId}", s.getFile)
+	mux.HandleFunc("PATCH "+apiPrefix+"files/{fileId}", s.updateFile)
+	mux.HandleFunc("DELETE "+apiPrefix+"files/{fileId}", s.deleteFile)
+	mux.HandleFunc("DELETE "+apiPrefix+"files/trash", s.emptyTrash)
+	mux.HandleFunc("POST "+apiPrefix+"files/{fileId}/copy", s.copyFile)
+	mux.HandleFunc("GET "+apiPrefix+"files/{fileId}/export", s.exportFile)
+	mux.HandleFunc("GET "+apiPrefix+"files/{fileId}/permissions", s.listPermissions)
+	mux.HandleFunc("POST "+apiPrefix+"files/{fileId}/permissions", s.createPermission)
+	mux.HandleFunc("PATCH "+apiPrefix+"files/{fileId}/permissions/{permissionId}", s.updatePermission)
+	mux.HandleFunc("DELETE "+apiPrefix+"files/{fileId}/permissions/{permissionId}", s.deletePermission)
+	mux.HandleFunc("GET "+apiPrefix+"files/{fileId}/revisions", s.listRevisions)
+	mux.HandleFunc("GET "+apiPrefix+"files/{fileId}/revisions/{revisionId}", s.getRevision)
+	mux.HandleFunc("PATCH "+apiPrefix+"files/{fileId}/revisions/{revisionId}", s.updateRevision)
+	mux.HandleFunc("DELETE "+apiPrefix+"files/{fileId}/revisions/{revisionId}", s.deleteRevision)
+	mux.HandleFunc("GET "+apiPrefix+"changes/startPageToken", s.getStartPageToken)
+	mux.HandleFunc("GET "+apiPrefix+"changes", s.listChanges)
+	mux.HandleFunc("GET "+apiPrefix+"drives", s.listDrives)
+	mux.HandleFunc("POST "+apiPrefix+"drives", s.createDrive)
+	mux.HandleFunc("GET "+apiPrefix+"drives/{driveId}", s.getDrive)
+	mux.HandleFunc("PATCH "+apiPrefix+"drives/{driveId}", s.updateDrive)
+	mux.HandleFunc("DELETE "+apiPrefix+"drives/{driveId}", s.deleteDrive)
+	mux.HandleFunc("POST "+apiPrefix+"drives/{driveId}/hide", s.hideDrive)
+	mux.HandleFunc("POST "+apiPrefix+"drives/{driveId}/unhide", s.unhideDrive)
+	mux.HandleFunc("POST "+uploadPrefix+"files", s.uploadFile)
+	mux.HandleFunc("PUT "+uploadPrefix+"files", s.uploadFile)
+	mux.HandleFunc("PATCH "+uploadPrefix+"files/{fileId}", s.uploadFile)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &googleapi.Error{Code: http.StatusNotFound, Message: fmt.Sprintf("Not Found: %s %s", r.Method, r.URL.Path)})
+	})
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.intercept(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server and blocks until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Drive returns the in-memory Drive that backs the server.
+// It can be used to prepare and inspect files without going through HTTP.
+func (s *Server) Drive() *drivefsmem.Drive {
+	return s.drive
+}
+
+// RootID returns the ID of the root folder of My Drive, which can also be referred to as "root".
+func (s *Server) RootID() drivefs.FileID {
+	return s.drive.RootID()
+}
+
+// ClientOptions returns the options that point a Google API client at the server.
+func (s *Server) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(s.URL + apiPrefix),
+		option.WithHTTPClient(s.server.Client()),
+	}
+}
+
+// NewService returns a *drive.Service that sends all requests to the server.
+// The given options are applied after ClientOptions.
+func (s *Server) NewService(ctx context.Context, opts ...option.ClientOption) (*drive.Service, error) {
+	return drive.NewService(ctx, append(s.ClientOptions(), opts...)...)
+}
+
+// InjectFault makes the server return the error response described by f
+// for the next f.Times requests that match it.
+// Faults are checked in the order of injection before requests are served.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+	s.faults = append(s.faults, &f)
+}
+
+// Requests returns the requests received by the server so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) intercept(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()})
+	for i, f := range s.faults {
+		if (f.Method != "" && f.Method != r.Method) || !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times--; f.Times <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+		}
+		reason := f.Reason
+		if reason == "" {
+			reason = defaultReason(f.Code)
+		}
+		writeError(w, &googleapi.Error{
+			Code:    f.Code,
+			Message: "Injected fault: " + reason,
+			Errors:  []googleapi.ErrorItem{{Reason: reason, Message: "Injected fault: " + reason}},
+		})
+		return true
+	}
+	return false
+}
+
+func (s *Server) listFiles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pageSize, err := s.pageSize(q.Get("pageSize"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.drive.ListFiles(r.Context(), drivefs.ListFilesRequest{
+		Query:     q.Get("q"),
+		PageSize:  pageSize,
+		PageToken: q.Get("pageToken"),
+		OrderBy:   q.Get("orderBy"),
+		DriveID:   q.Get("driveId"),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res.Kind = "drive#fileList"
+	writeJSON(w, r, res, defaultFileListFields)
+}
+
+func (s *Server) generateIDs(w http.ResponseWriter, r *http.Request) {
+	count := 10
+	if v := r.URL.Query().Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, newBadRequest("Invalid Value: count %q", v))
+			return
+		}
+		count = n
+	}
+	ids, err := s.drive.GenerateIDs(r.Context(), count)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, &drive.GeneratedIds{Kind: "drive#generatedIds", Space: "drive", Ids: ids}, "kind,space,ids")
+}
+
+func (s *Server) createFile(w http.ResponseWriter, r *http.Request) {
+	meta, err := decodeFile(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	f, err := s.drive.CreateFile(r.Context(), drivefs.CreateFileRequest{File: meta})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, f, defaultFileFields)
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request) {
+	fileID := r.PathValue("fileId")
+	if r.URL.Query().Get("alt") == "media" {
+		s.downloadFile(w, r, fileID)
+		return
+	}
+	f, err := s.drive.GetFile(r.Context(), fileID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, f, defaultFileFields)
+}
+
+func (s *Server) downloadFile(w http.ResponseWriter, r *http.Request, fileID string) {
+	f, err := s.drive.GetFile(r.Context(), fileID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	body, err := s.drive.DownloadFile(r.Context(), drivefs.DownloadFileRequest{FileID: fileID, Length: -1})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer body.Close()
+	content, err := io.ReadAll(body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", f.MimeType)
+	// ServeContent handles Range requests, including 206 and 416 responses.
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+func (s *Server) updateFile(w http.ResponseWriter, r *http.Request) {
+	meta, err := decodeFile(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	f, err := s.drive.UpdateFile(r.Context(), drivefs.UpdateFileRequest{
+		FileID:        r.PathValue("fileId"),
+		File:          meta,
+		AddParents:    splitIDs(q.Get("addParents")),
+		RemoveParents: splitIDs(q.Get("removeParents")),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, f, defaultFileFields)
+}
+
+func (s *Server) deleteFile(w http.ResponseWriter, r *http.Request) {
+	if err := s.drive.DeleteFile(r.Context(), r.PathValue("fileId")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) emptyTrash(w http.ResponseWriter, r *http.Request) {
+	if err := s.drive.EmptyTrash(r.Context(), r.URL.Query().Get("driveId")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) copyFile(w http.ResponseWriter, r *http.Request) {
+	meta, err := decodeFile(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	f, err := s.drive.CopyFile(r.Context(), drivefs.CopyFileRequest{FileID: r.PathValue("fileId"), File: meta})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, f, defaultFileFields)
+}
+
+func (s *Server) exportFile(w http.ResponseWriter, r *http.Request) {
+	mimeType := r.URL.Query().Get("mimeType")
+	body, err := s.drive.ExportFile(r.Context(), r.PathValue("fileId"), mimeType)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer body.Close()
+	w.Header().Set("Content-Type", mimeType)
+	_, _ = io.Copy(w, body)
+}
+
+func (s *Server) listPermissions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fileID := r.PathValue("fileId")
+	pageSize, err := s.pageSize(q.Get("pageSize"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.drive.ListPermissions(r.Context(), fileID, q.Get("pageToken"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if pageSize > 0 && int64(len(res.Permissions)) > pageSize {
+		// Re-slice the page of the in-memory Drive, whose page tokens are offsets.
+		offset, _ := strconv.ParseInt(q.Get("pageToken"), 10, 64)
+		res.Permissions = res.Permissions[:pageSize]
+		res.NextPageToken = strconv.FormatInt(offset+pageSize, 10)
+	}
+	res.Kind = "drive#permissionList"
+	writeJSON(w, r, res, defaultPermListFields)
+}
+
+func (s *Server) createPermission(w http.ResponseWriter, r *http.Request) {
+	var perm drive.Permission
+	if err := json.NewDecoder(r.Body).Decode(&perm); err != nil {
+		writeError(w, newBadRequest("Invalid JSON payload: %v", err))
+		return
+	}
+	q := r.URL.Query()
+	p, err := s.drive.CreatePermission(r.Context(), drivefs.CreatePermissionRequest{
+		FileID:                    r.PathValue("fileId"),
+		Permission:                &perm,
+		SuppressNotificationEmail: q.Get("sendNotificationEmail") == "false",
+		EmailMessage:              q.Get("emailMessage"),
+		TransferOwnership:         q.Get("transferOwnership") == "true",
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, p, defaultPermissionFields)
+}
+
+func (s *Server) updatePermission(w http.ResponseWriter, r *http.Request) {
+	var perm drive.Permission
+	if err := json.NewDecoder(r.Body).Decode(&perm); err != nil {
+		writeError(w, newBadRequest("Invalid JSON payload: %v", err))
+		return
+	}
+	perm.Id = r.PathValue("permissionId")
+	q := r.URL.Query()
+	p, err := s.drive.UpdatePermission(r.Context(), drivefs.UpdatePermissionRequest{
+		FileID:            r.PathValue("fileId"),
+		Permission:        &perm,
+		RemoveExpiration:  q.Get("removeExpiration") == "true",
+		TransferOwnership: q.Get("transferOwnership") == "true",
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, p, defaultPermissionFields)
+}
+
+func (s *Server) deletePermission(w http.ResponseWriter, r *http.Request) {
+	if err := s.drive.DeletePermission(r.Context(), r.PathValue("fileId"), r.PathValue("permissionId")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listRevisions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pageSize, err := s.pageSize(q.Get("pageSize"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.drive.ListRevisions(r.Context(), r.PathValue("fileId"), q.Get("pageToken"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if pageSize > 0 && int64(len(res.Revisions)) > pageSize {
+		// Re-slice the page of the in-memory Drive, whose page tokens are offsets.
+		offset, _ := strconv.ParseInt(q.Get("pageToken"), 10, 64)
+		res.Revisions = res.Revisions[:pageSize]
+		res.NextPageToken = strconv.FormatInt(offset+pageSize, 10)
+	}
+	res.Kind = "drive#revisionList"
+	writeJSON(w, r, res, defaultRevListFields)
+}
+
+func (s *Server) getRevision(w http.ResponseWriter, r *http.Request) {
+	fileID, revisionID := r.PathValue("fileId"), r.PathValue("revisionId")
+	if r.URL.Query().Get("alt") == "media" {
+		body, err := s.drive.DownloadRevision(r.Context(), fileID, revisionID)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer body.Close()
+		_, _ = io.Copy(w, body)
+		return
+	}
+	// The in-memory Drive has no method to get a single revision, so it is looked up in the list.
+	pageToken := ""
+	for {
+		res, err := s.drive.ListRevisions(r.Context(), fileID, pageToken)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for _, rev := range res.Revisions {
+			if rev.Id == revisionID {
+				writeJSON(w, r, rev, defaultRevisionFields)
+				return
+			}
+		}
+		if res.NextPageToken == "" {
+			writeError(w, &googleapi.Error{Code: http.StatusNotFound, Message: "Revision not found: " + revisionID})
+			return
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+func (s *Server) updateRevision(w http.ResponseWriter, r *http.Request) {
+	rev, err := decodeRevision(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	rev.Id = r.PathValue("revisionId")
+	res, err := s.drive.UpdateRevision(r.Context(), drivefs.UpdateRevisionRequest{
+		FileID:   r.PathValue("fileId"),
+		Revision: rev,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultRevisionFields)
+}
+
+func (s *Server) deleteRevision(w http.ResponseWriter, r *http.Request) {
+	if err := s.drive.DeleteRevision(r.Context(), r.PathValue("fileId"), r.PathValue("revisionId")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getStartPageToken(w http.ResponseWriter, r *http.Request) {
+	token, err := s.drive.GetStartPageToken(r.Context(), r.URL.Query().Get("driveId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, &drive.StartPageToken{Kind: "drive#startPageToken", StartPageToken: token}, "kind,startPageToken")
+}
+
+func (s *Server) listChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("pageToken") == "" {
+		writeError(w, newBadRequest("Required parameter: pageToken"))
+		return
+	}
+	pageSize, err := s.pageSize(q.Get("pageSize"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.drive.ListChanges(r.Context(), drivefs.ListChangesRequest{
+		DriveID:   q.Get("driveId"),
+		PageToken: q.Get("pageToken"),
+		PageSize:  pageSize,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res.Kind = "drive#changeList"
+	writeJSON(w, r, res, defaultChangeListFields)
+}
+
+func (s *Server) listDrives(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pageSize, err := s.pageSize(q.Get("pageSize"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.drive.ListDrives(r.Context(), drivefs.ListDrivesRequest{
+		Query:     q.Get("q"),
+		PageSize:  pageSize,
+		PageToken: q.Get("pageToken"),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultDriveListFields)
+}
+
+func (s *Server) createDrive(w http.ResponseWriter, r *http.Request) {
+	drv, err := decodeDrive(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.drive.CreateDrive(r.Context(), drivefs.CreateDriveRequest{
+		RequestID: r.URL.Query().Get("requestId"),
+		Drive:     drv,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultDriveFields)
+}
+
+func (s *Server) getDrive(w http.ResponseWriter, r *http.Request) {
+	res, err := s.drive.GetDrive(r.Context(), r.PathValue("driveId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultDriveFields)
+}
+
+func (s *Server) updateDrive(w http.ResponseWriter, r *http.Request) {
+	drv, err := decodeDrive(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	drv.Id = r.PathValue("driveId")
+	res, err := s.drive.UpdateDrive(r.Context(), drivefs.UpdateDriveRequest{Drive: drv})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultDriveFields)
+}
+
+func (s *Server) deleteDrive(w http.ResponseWriter, r *http.Request) {
+	if err := s.drive.DeleteDrive(r.Context(), r.PathValue("driveId")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) hideDrive(w http.ResponseWriter, r *http.Request) {
+	res, err := s.drive.HideDrive(r.Context(), r.PathValue("driveId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultDriveFields)
+}
+
+func (s *Server) unhideDrive(w http.ResponseWriter, r *http.Request) {
+	res, err := s.drive.UnhideDrive(r.Context(), r.PathValue("driveId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, r, res, defaultDriveFields)
+}
+
+// uploadFile serves the upload URIs of files.create and files.update.
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if uploadID := q.Get("upload_id"); uploadID != "" {
+		s.uploadChunk(w, r, uploadID)
+		return
+	}
+	if r.Method == http.MethodPut {
+		writeError(w, newBadRequest("Missing upload_id"))
+		return
+	}
+	fileID := r.PathValue("fileId")
+	switch uploadType := q.Get("uploadType"); uploadType {
+	case "media":
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.commitUpload(w, r, fileID, &drive.File{}, r.Header.Get("Content-Type"), content, q)
+	case "multipart":
+		meta, contentType, content, err := readMultipart(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.commitUpload(w, r, fileID, meta, contentType, content, q)
+	case "resumable":
+		meta, err := decodeFile(r.Body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		uploadID := rand.Text()
+		s.mu.Lock()
+		s.sessions[uploadID] = &uploadSession{
+			fileID:      fileID,
+			file:        meta,
+			contentType: r.Header.Get("X-Upload-Content-Type"),
+			params:      q,
+		}
+		s.mu.Unlock()
+		location := url.URL{Scheme: "http", Host: r.Host, Path: uploadPrefix + "files"}
+		location.RawQuery = url.Values{"uploadType": {"resumable"}, "upload_id": {uploadID}}.Encode()
+		w.Header().Set("Location", location.String())
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeError(w, newBadRequest("Invalid uploadType: %q", uploadType))
+	}
+}
+
+// uploadChunk receives a chunk of a resumable upload.
+// Incomplete uploads are reported with the status code 308 in the X-Http-Status-Code-Override header,
+// as Google Drive does for clients that send "X-GUploader-No-308: yes".
+func (s *Server) uploadChunk(w http.ResponseWriter, r *http.Request, uploadID string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	session, ok := s.sessions[uploadID]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, &googleapi.Error{Code: http.StatusNotFound, Message: "Upload session not found: " + uploadID})
+		return
+	}
+	start, end, total, err := parseContentRange(r.Header.Get("Content-Range"))
+	if err != nil {
+		s.mu.Unlock()
+		writeError(w, err)
+		return
+	}
+	if start >= 0 {
+		committed := int64(len(session.content))
+		if start > committed || end-start+1 != int64(len(body)) {
+			s.mu.Unlock()
+			writeError(w, newBadRequest("Invalid Content-Range: %s", r.Header.Get("Content-Range")))
+			return
+		}
+		// A chunk may be resent after a failure; only the part beyond the committed offset is appended.
+		session.content = append(session.content, body[committed-start:]...)
+	}
+	if ct := r.Header.Get("Content-Type"); session.contentType == "" && ct != "" {
+		session.contentType = ct
+	}
+	complete := total >= 0 && int64(len(session.content)) == total
+	if complete {
+		delete(s.sessions, uploadID)
+	}
+	n := len(session.content)
+	s.mu.Unlock()
+
+	if complete {
+		s.commitUpload(w, r, session.fileID, session.file, session.contentType, session.content, session.params)
+		return
+	}
+	if n > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+	}
+	w.Header().Set("X-Http-Status-Code-Override", "308")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) commitUpload(w http.ResponseWriter, r *http.Request, fileID string, meta *drive.File, contentType string, content []byte, params url.Values) {
+	var mediaOptions []googleapi.MediaOption
+	if contentType != "" {
+		mediaOptions = append(mediaOptions, googleapi.ContentType(contentType))
+	}
+	var (
+		f   *drive.File
+		err error
+	)
+	if fileID == "" {
+		f, err = s.drive.CreateFile(r.Context(), drivefs.CreateFileRequest{
+			File:         meta,
+			Media:        bytes.NewReader(content),
+			MediaOptions: mediaOptions,
+			OCRLanguage:  params.Get("ocrLanguage"),
+		})
+	} else {
+		f, err = s.drive.UpdateFile(r.Context(), drivefs.UpdateFileRequest{
+			FileID:        fileID,
+			File:          meta,
+			AddParents:    splitIDs(params.Get("addParents")),
+			RemoveParents: splitIDs(params.Get("removeParents")),
+			Media:         bytes.NewReader(content),
+			MediaOptions:  mediaOptions,
+		})
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	// The fields parameter is given to the request that starts the upload.
+	r.URL.RawQuery = params.Encode()
+	writeJSON(w, r, f, defaultFileFields)
+}
+
+func (s *Server) pageSize(v string) (int64, error) {
+	var pageSize int64
+	if v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return 0, newBadRequest("Invalid Value: pageSize %q", v)
+		}
+		pageSize = n
+	}
+	if s.maxPageSize > 0 && (pageSize == 0 || pageSize > s.maxPageSize) {
+		pageSize = s.maxPageSize
+	}
+	return pageSize, nil
+}
+
+func readMultipart(r *http.Request) (meta *drive.File, contentType string, content []byte, err error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, "", nil, newBadRequest("Invalid multipart Content-Type: %q", r.Header.Get("Content-Type"))
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		return nil, "", nil, newBadRequest("Missing metadata part: %v", err)
+	}
+	meta, err = decodeFile(part)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	part, err = mr.NextPart()
+	if err != nil {
+		return nil, "", nil, newBadRequest("Missing media part: %v", err)
+	}
+	content, err = io.ReadAll(part)
+	if err != nil {
+		return nil, "", nil, newBadRequest("Invalid media part: %v", err)
+	}
+	return meta, part.Header.Get("Content-Type"), content, nil
+}
+
+// decodeFile decodes the metadata of a file.
+// The fields present in the JSON payload are listed in ForceSendFields so that explicit zero values,
+// such as "trashed": false, are applied.
+func decodeFile(r io.Reader) (*drive.File, error) {
+	f := &drive.File{}
+	forceSendFields, err := decodeResource(r, f)
+	if err != nil {
+		return nil, err
+	}
+	f.ForceSendFields = forceSendFields
+	return f, nil
+}
+
+func decodeRevision(r io.Reader) (*drive.Revision, error) {
+	rev := &drive.Revision{}
+	forceSendFields, err := decodeResource(r, rev)
+	if err != nil {
+		return nil, err
+	}
+	rev.ForceSendFields = forceSendFields
+	return rev, nil
+}
+
+// decodeDrive decodes the metadata of a shared drive,
+// listing the fields present in the payload as ForceSendFields of the drive and of its restrictions.
+func decodeDrive(r io.Reader) (*drive.Drive, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	drv := &drive.Drive{}
+	forceSendFields, err := decodeResource(bytes.NewReader(data), drv)
+	if err != nil {
+		return nil, err
+	}
+	drv.ForceSendFields = forceSendFields
+	if drv.Restrictions != nil {
+		var raw struct {
+			Restrictions json.RawMessage `json:"restrictions"`
+		}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, newBadRequest("Invalid JSON payload: %v", err)
+		}
+		drv.Restrictions.ForceSendFields, err = decodeResource(bytes.NewReader(raw.Restrictions), &drive.DriveRestrictions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return drv, nil
+}
+
+// decodeResource decodes the JSON payload read from r into v,
+// and returns the names of the Go fields that are present in the payload as ForceSendFields.
+func decodeResource(r io.Reader, v any) (forceSendFields []string, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, newBadRequest("Invalid JSON payload: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, newBadRequest("Invalid JSON payload: %v", err)
+	}
+	for key := range raw {
+		rs := []rune(key)
+		rs[0] = unicode.ToUpper(rs[0])
+		forceSendFields = append(forceSendFields, string(rs))
+	}
+	return forceSendFields, nil
+}
+
+func parseContentRange(v string) (start, end, total int64, err error) {
+	invalid := newBadRequest("Invalid Content-Range: %q", v)
+	rangePart, totalPart, ok := strings.Cut(strings.TrimPrefix(v, "bytes "), "/")
+	if !ok || !strings.HasPrefix(v, "bytes ") {
+		return 0, 0, 0, invalid
+	}
+	total = -1
+	if totalPart != "*" {
+		if total, err = strconv.ParseInt(totalPart, 10, 64); err != nil {
+			return 0, 0, 0, invalid
+		}
+	}
+	if rangePart == "*" {
+		return -1, -1, total, nil
+	}
+	startPart, endPart, ok := strings.Cut(rangePart, "-")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	if start, err = strconv.ParseInt(startPart, 10, 64); err != nil {
+		return 0, 0, 0, invalid
+	}
+	if end, err = strconv.ParseInt(endPart, 10, 64); err != nil || end < start {
+		return 0, 0, 0, invalid
+	}
+	return start, end, total, nil
+}
+
+func defaultReason(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "badRequest"
+	case http.StatusUnauthorized:
+		return "authError"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "notFound"
+	case http.StatusTooManyRequests:
+		return "rateLimitExceeded"
+	default:
+		return "backendError"
+	}
+}
+
+func splitIDs(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func newBadRequest(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return &googleapi.Error{
+		Code:    http.StatusBadRequest,
+		Message: msg,
+		Errors:  []googleapi.ErrorItem{{Reason: "badRequest", Message: msg}},
+	}
+}
+
+// writeJSON writes v restricted to the fields parameter of the request, or to defaultFields if it is absent.
+func writeJSON(w http.ResponseWriter, r *http.Request, v any, defaultFields string) {
+	fields := r.URL.Query().Get("fields")
+	if fields == "" {
+		fields = defaultFields
+	}
+	mask, err := parseFieldMask(fields)
+	if err != nil {
+		writeError(w, newBadRequest("%v", err))
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(mask.apply(decoded))
+}
+
+// writeError writes err in the error payload format of Google APIs.
+// Errors other than *googleapi.Error are reported as internal errors,
+// and context errors as if the client has gone away.
+func writeError(w http.ResponseWriter, err error) {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		code := http.StatusInternalServerError
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			code = 499
+		}
+		gerr = &googleapi.Error{Code: code, Message: err.Error()}
+	}
+	type errorItem struct {
+		Domain  string `json:"domain"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}
+	items := []errorItem{}
+	for _, item := range gerr.Errors {
+		items = append(items, errorItem{Domain: "global", Reason: item.Reason, Message: item.Message})
+	}
+	payload := map[string]any{"error": map[string]any{
+		"code":    gerr.Code,
+		"message": gerr.Message,
+		"errors":  items,
+	}}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(gerr.Code)
+	_ = json.NewEncoder(w).Encode(payload)
+}
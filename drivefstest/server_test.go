@@ -0,0 +1,610 @@
+package drivefstest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefstest"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func newDriveFS(t *testing.T, opts drivefstest.Options) (*drivefstest.Server, *drivefs.DriveFS) {
+	t.Helper()
+	srv := drivefstest.NewServer(opts)
+	t.Cleanup(srv.Close)
+	service, err := srv.NewService(context.Background())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return srv, drivefs.New(service)
+}
+
+func TestServer_FileOperations(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+
+	dir, err := fs.MkdirAll(srv.RootID(), "/a/b")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	file, err := fs.Create(dir.ID, "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := fs.WriteFile(file.ID, []byte("hello, world")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	info, err := fs.Info(file.ID)
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if info.Size != 12 || info.ModTime.IsZero() {
+		t.Fatalf("Info() = %+v, want size 12 and a modification time", info)
+	}
+	if data, err := fs.ReadFile(file.ID); err != nil || string(data) != "hello, world" {
+		t.Fatalf("ReadFile() = %q, %v, want %q", data, err, "hello, world")
+	}
+
+	r, err := fs.Open(file.ID)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer r.Close()
+	buf := make([]byte, 5)
+	if n, err := r.ReadAt(buf, 7); err != nil || string(buf[:n]) != "world" {
+		t.Fatalf("ReadAt() = %q, %v, want %q", buf[:n], err, "world")
+	}
+
+	path, err := fs.ResolvePath(file.ID)
+	if err != nil || path != "/a/b/file.txt" {
+		t.Fatalf("ResolvePath() = %q, %v, want %q", path, err, "/a/b/file.txt")
+	}
+	if err := fs.Remove(dir.ID, false); !errors.Is(err, drivefs.ErrNotRemovable) {
+		t.Fatalf("Remove() error = %v, want ErrNotRemovable", err)
+	}
+	if _, err := fs.Rename(file.ID, "renamed.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if err := fs.Move(file.ID, srv.RootID()); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if found, err := fs.FindByPath(srv.RootID(), "/renamed.txt"); err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath() = %v, %v, want one file", found, err)
+	}
+	if err := fs.Remove(file.ID, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if found, err := fs.FindByPath(srv.RootID(), "/renamed.txt"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath() of trashed file = %v, %v, want none", found, err)
+	}
+
+	perms, err := fs.PermSet(dir.ID, drivefs.AnyonePermission(drivefs.RoleReader, false))
+	if err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	if len(perms) != 2 {
+		t.Fatalf("PermSet() = %v, want the owner and anyone", perms)
+	}
+}
+
+func TestServer_Pagination(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{MaxPageSize: 2})
+
+	var want []string
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := fs.Create(srv.RootID(), name); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		want = append(want, name)
+	}
+	children, err := fs.ReadDir(srv.RootID())
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	var names []string
+	for _, c := range children {
+		names = append(names, c.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, want) {
+		t.Fatalf("ReadDir() = %v, want %v", names, want)
+	}
+
+	var pageTokens []string
+	for _, r := range srv.Requests() {
+		if r.Method == http.MethodGet && r.Path == "/drive/v3/files" {
+			pageTokens = append(pageTokens, r.Query.Get("pageToken"))
+		}
+	}
+	if len(pageTokens) != 3 || pageTokens[0] != "" || pageTokens[1] == "" || pageTokens[2] == "" {
+		t.Fatalf("page tokens of files.list requests = %q, want 3 pages", pageTokens)
+	}
+}
+
+func TestServer_Changes(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{MaxPageSize: 2})
+	feed := drivefs.NewChangeFeed(fs, drivefs.ChangeFeedOptions{})
+	if _, err := feed.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	want := map[drivefs.FileID]drivefs.ChangeKind{}
+	for _, name := range []string{"a", "b", "c"} {
+		info, err := fs.Create(srv.RootID(), name)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		want[info.ID] = drivefs.ChangeCreated
+	}
+	dir, err := fs.Mkdir(srv.RootID(), "dir")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	if err := fs.Remove(dir.ID, false); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	want[dir.ID] = drivefs.ChangeRemoved
+
+	changes, err := feed.Poll()
+	if err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	got := map[drivefs.FileID]drivefs.ChangeKind{}
+	for _, c := range changes {
+		got[c.File.ID] = c.Kind
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("Poll() = %v, want %v", got, want)
+	}
+}
+
+func TestServer_Revisions(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{MaxPageSize: 1})
+	file, err := fs.Create(srv.RootID(), "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for _, content := range []string{"v1", "v2"} {
+		if err := fs.WriteFile(file.ID, []byte(content)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	revisions, err := fs.Revisions(file.ID)
+	if err != nil || len(revisions) != 3 {
+		t.Fatalf("Revisions() = %+v, %v, want 3 revisions", revisions, err)
+	}
+	v1 := revisions[1]
+	if v1.Size != 2 || v1.MD5Checksum == "" || v1.LastModifyingUserEmail == "" {
+		t.Fatalf("Revisions()[1] = %+v, want the metadata of v1", v1)
+	}
+	if data, err := fs.ReadRevision(file.ID, v1.ID); err != nil || string(data) != "v1" {
+		t.Fatalf("ReadRevision() = %q, %v, want %q", data, err, "v1")
+	}
+	for _, keep := range []bool{true, false} {
+		if rev, err := fs.KeepRevision(file.ID, v1.ID, keep); err != nil || rev.KeepForever != keep {
+			t.Fatalf("KeepRevision(%t) = %+v, %v", keep, rev, err)
+		}
+	}
+	if _, err := fs.RestoreRevision(file.ID, v1.ID); err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if data, err := fs.ReadFile(file.ID); err != nil || string(data) != "v1" {
+		t.Fatalf("ReadFile() = %q, %v, want %q", data, err, "v1")
+	}
+	if err := fs.DeleteRevision(file.ID, v1.ID); err != nil {
+		t.Fatalf("DeleteRevision() error = %v", err)
+	}
+	if _, err := fs.ReadRevision(file.ID, v1.ID); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("ReadRevision() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServer_Trash(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	var ids []drivefs.FileID
+	for _, name := range []string{"a", "b"} {
+		info, err := fs.Create(srv.RootID(), name)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := fs.Remove(info.ID, true); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+		ids = append(ids, info.ID)
+	}
+	if items, err := fs.ListTrash(drivefs.TrashOptions{}); err != nil || len(items) != 2 {
+		t.Fatalf("ListTrash() = %+v, %v, want 2 items", items, err)
+	}
+	if result, err := fs.Restore(ids[0], drivefs.RestoreOptions{}); err != nil || !result.OriginalParentExists || result.File.Trashed {
+		t.Fatalf("Restore() = %+v, %v, want the file restored", result, err)
+	}
+	if err := fs.EmptyTrash(""); err != nil {
+		t.Fatalf("EmptyTrash() error = %v", err)
+	}
+	if items, err := fs.ListTrash(drivefs.TrashOptions{}); err != nil || len(items) != 0 {
+		t.Fatalf("ListTrash() after EmptyTrash() = %+v, %v, want none", items, err)
+	}
+	if _, err := fs.Info(ids[0]); err != nil {
+		t.Fatalf("Info() of the restored file error = %v", err)
+	}
+}
+
+func TestServer_SharedDrives(t *testing.T) {
+	_, fs := newDriveFS(t, drivefstest.Options{MaxPageSize: 1})
+	team, err := fs.CreateSharedDrive("Team", "request-1")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	if _, err := fs.CreateSharedDrive("Team", "request-1"); !errors.Is(err, drivefs.ErrAlreadyExists) {
+		t.Fatalf("CreateSharedDrive() with a used request ID error = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := fs.CreateSharedDrive("Archive", ""); err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	if drives, err := fs.ListSharedDrives(drivefs.SharedDriveListOptions{}); err != nil || len(drives) != 2 {
+		t.Fatalf("ListSharedDrives() = %+v, %v, want 2 shared drives", drives, err)
+	}
+	if drives, err := fs.ListSharedDrives(drivefs.SharedDriveListOptions{NameContains: "team"}); err != nil || len(drives) != 1 || drives[0].ID != team.ID {
+		t.Fatalf("ListSharedDrives() containing 'team' = %+v, %v, want %s", drives, err, team.ID)
+	}
+
+	if _, err := fs.RenameSharedDrive(team.ID, "Team 2"); err != nil {
+		t.Fatalf("RenameSharedDrive() error = %v", err)
+	}
+	if _, err := fs.HideSharedDrive(team.ID); err != nil {
+		t.Fatalf("HideSharedDrive() error = %v", err)
+	}
+	restrictions := drivefs.SharedDriveRestrictions{DomainUsersOnly: true}
+	if _, err := fs.SetSharedDriveRestrictions(team.ID, drivefs.SharedDriveRestrictions{DriveMembersOnly: true}); err != nil {
+		t.Fatalf("SetSharedDriveRestrictions() error = %v", err)
+	}
+	if _, err := fs.SetSharedDriveRestrictions(team.ID, restrictions); err != nil {
+		t.Fatalf("SetSharedDriveRestrictions() error = %v", err)
+	}
+	info, err := fs.SharedDriveInfo(team.ID)
+	if err != nil || info.Name != "Team 2" || !info.Hidden || info.Restrictions != restrictions || info.CreatedTime.IsZero() {
+		t.Fatalf("SharedDriveInfo() = %+v, %v", info, err)
+	}
+	if info, err := fs.UnhideSharedDrive(team.ID); err != nil || info.Hidden {
+		t.Fatalf("UnhideSharedDrive() = %+v, %v", info, err)
+	}
+
+	rootID, err := fs.SharedDriveRoot(team.ID)
+	if err != nil {
+		t.Fatalf("SharedDriveRoot() error = %v", err)
+	}
+	if _, err := fs.MkdirAll(rootID, "/a/b"); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := fs.DeleteSharedDrive(team.ID); !errors.Is(err, drivefs.ErrNotRemovable) {
+		t.Fatalf("DeleteSharedDrive() of a non-empty shared drive error = %v, want ErrNotRemovable", err)
+	}
+	found, err := fs.FindByPath(rootID, "/a")
+	if err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath() = %+v, %v, want one directory", found, err)
+	}
+	if err := fs.RemoveAll(found[0].ID, false); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if err := fs.DeleteSharedDrive(team.ID); err != nil {
+		t.Fatalf("DeleteSharedDrive() error = %v", err)
+	}
+	if _, err := fs.SharedDriveInfo(team.ID); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("SharedDriveInfo() of a deleted shared drive error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestServer_InheritedPermissions(t *testing.T) {
+	_, fs := newDriveFS(t, drivefstest.Options{})
+	sharedDrive, err := fs.CreateSharedDrive("Team", "")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	if _, err := fs.PermSet(sharedDrive.RootID, drivefs.UserPermission("alice@example.com", drivefs.RoleWriter)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	dir, err := fs.MkdirAll(sharedDrive.RootID, "/dir")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	file, err := fs.Create(dir.ID, "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	result, err := fs.PermSetAll(dir.ID, drivefs.UserPermission("alice@example.com", drivefs.RoleReader), drivefs.PermTreeOptions{})
+	if err != nil {
+		t.Fatalf("PermSetAll() error = %v", err)
+	}
+	if len(result.Changed) != 0 || len(result.Inherited) != 2 {
+		t.Fatalf("PermSetAll() = %+v, want all items inherited", result)
+	}
+	perms, err := fs.PermList(file.ID)
+	if err != nil {
+		t.Fatalf("PermList() error = %v", err)
+	}
+	for _, perm := range perms {
+		if perm.Grantee() != drivefs.User("alice@example.com") {
+			continue
+		}
+		details := perm.Details()
+		if !perm.Inherited() || perm.InheritedFrom() != sharedDrive.RootID || len(details) != 1 || details[0].Type != drivefs.PermissionTypeMember {
+			t.Fatalf("permission = %+v, want a membership inherited from the shared drive", details)
+		}
+		return
+	}
+	t.Fatalf("PermList() = %v, want a permission for alice", perms)
+}
+
+func TestServer_ExpiringPermissions(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	file, err := fs.Create(srv.RootID(), "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	expiration := time.Now().Add(time.Hour).Truncate(time.Second)
+	perms, err := fs.PermSetWithOptions(file.ID, drivefs.WithExpiration(drivefs.UserPermission("alice@example.com", drivefs.RoleReader), expiration),
+		drivefs.PermSetOptions{SuppressNotificationEmail: true})
+	if err != nil {
+		t.Fatalf("PermSetWithOptions() error = %v", err)
+	}
+	if i := slices.IndexFunc(perms, func(p drivefs.Permission) bool { return p.ExpirationTime().Equal(expiration) }); i < 0 {
+		t.Fatalf("PermSetWithOptions() = %v, want a permission expiring at %v", perms, expiration)
+	}
+	if _, err := fs.PermSet(file.ID, drivefs.UserPermission("alice@example.com", drivefs.RoleReader)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	perms, err = fs.PermList(file.ID)
+	if err != nil {
+		t.Fatalf("PermList() error = %v", err)
+	}
+	for _, perm := range perms {
+		if !perm.ExpirationTime().IsZero() {
+			t.Fatalf("PermList() = %v, want no expiration", perms)
+		}
+	}
+}
+
+func TestServer_TransferOwnership(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	dir, err := fs.MkdirAll(srv.RootID(), "/dir")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	file, err := fs.Create(dir.ID, "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	perms, err := fs.TransferOwnership(file.ID, "alice@example.com", drivefs.TransferOptions{Pending: true})
+	if err != nil {
+		t.Fatalf("TransferOwnership() error = %v", err)
+	}
+	if i := slices.IndexFunc(perms, func(p drivefs.Permission) bool { return p.PendingOwner() && p.Role() == drivefs.RoleWriter }); i < 0 {
+		t.Fatalf("TransferOwnership() = %v, want a pending owner", perms)
+	}
+
+	result, err := fs.TransferOwnershipAll(dir.ID, "alice@example.com", drivefs.TransferOptions{EmailMessage: "Take over"})
+	if err != nil {
+		t.Fatalf("TransferOwnershipAll() error = %v", err)
+	}
+	if len(result.Transferred) != 2 || len(result.Errors) != 0 {
+		t.Fatalf("TransferOwnershipAll() = %+v, want both items transferred", result)
+	}
+	perms, err = fs.PermList(file.ID)
+	if err != nil {
+		t.Fatalf("PermList() error = %v", err)
+	}
+	for _, perm := range perms {
+		if perm.Role() == drivefs.RoleOwner && perm.Grantee() != drivefs.User("alice@example.com") || perm.PendingOwner() {
+			t.Fatalf("PermList() = %v, want alice as the only owner", perms)
+		}
+	}
+}
+
+func TestServer_Query(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	dir, err := fs.MkdirAll(srv.RootID(), `/it's\dir`)
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if _, err := fs.Create(dir.ID, `a'b\c.txt`); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	found, err := fs.FindByPath(srv.RootID(), `/it's\dir/a'b\c.txt`)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath() = %v, %v, want one file", found, err)
+	}
+	results, err := fs.Query(drivefs.And(drivefs.InParents(dir.ID), drivefs.NameContains("a'b")).String())
+	if err != nil || len(results) != 1 || results[0].ID != found[0].ID {
+		t.Fatalf("Query() = %v, %v, want the file", results, err)
+	}
+}
+
+func TestServer_Snapshot(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{MaxPageSize: 2})
+	sharedDrive, err := fs.CreateSharedDrive("Team", "")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	dir, err := fs.MkdirAll(sharedDrive.RootID, "/a/b")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	for _, name := range []string{"x.txt", "y.txt", "z.txt"} {
+		if _, err := fs.Create(dir.ID, name); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if _, err := fs.Create(srv.RootID(), "mine.txt"); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	snapshot, err := fs.Snapshot(sharedDrive.RootID)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot.Len() != 6 {
+		t.Fatalf("Snapshot() has %d items, want 6", snapshot.Len())
+	}
+	found, err := snapshot.FindByPath("/a/b/z.txt")
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Snapshot.FindByPath() = %v, %v, want z.txt", found, err)
+	}
+	if p, err := snapshot.ResolvePath(found[0].ID); err != nil || p != "/a/b/z.txt" {
+		t.Fatalf("Snapshot.ResolvePath() = %q, %v, want /a/b/z.txt", p, err)
+	}
+}
+
+func TestServer_Cache(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	fs = fs.WithCache(drivefs.CacheOptions{})
+	dir, err := fs.MkdirAll(srv.RootID(), "/a/b")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	file, err := fs.Create(dir.ID, "x.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if p, err := fs.ResolvePath(file.ID); err != nil || p != "/a/b/x.txt" {
+		t.Fatalf("ResolvePath() = %q, %v, want /a/b/x.txt", p, err)
+	}
+	if found, err := fs.FindByPath(srv.RootID(), "/a/y.txt"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath() = %v, %v, want nothing", found, err)
+	}
+
+	if _, err := fs.Rename(file.ID, "y.txt"); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	a, err := fs.FindByPath(srv.RootID(), "/a")
+	if err != nil || len(a) != 1 {
+		t.Fatalf("FindByPath() = %v, %v, want a", a, err)
+	}
+	if err := fs.Move(file.ID, a[0].ID); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if found, err := fs.FindByPath(srv.RootID(), "/a/y.txt"); err != nil || len(found) != 1 {
+		t.Fatalf("FindByPath() = %v, %v, want y.txt", found, err)
+	}
+	if p, err := fs.ResolvePath(file.ID); err != nil || p != "/a/y.txt" {
+		t.Fatalf("ResolvePath() = %q, %v, want /a/y.txt", p, err)
+	}
+}
+
+func TestServer_ResumableUpload(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	file, err := fs.Create(srv.RootID(), "large.bin")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	srv.InjectFault(drivefstest.Fault{Method: http.MethodPut, Path: "/upload/drive/v3/files", Code: http.StatusServiceUnavailable})
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 48*1024)
+	err = fs.WriteFrom(file.ID, bytes.NewReader(data), drivefs.WriteOptions{ChunkSize: 256 * 1024, ContentType: "application/octet-stream"})
+	if err != nil {
+		t.Fatalf("WriteFrom() error = %v", err)
+	}
+	got, err := fs.ReadFile(file.ID)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("ReadFile() returned %d bytes, want %d bytes", len(got), len(data))
+	}
+
+	var chunks int
+	for _, r := range srv.Requests() {
+		if r.Query.Get("upload_id") != "" {
+			chunks++
+		}
+	}
+	if chunks < 4 {
+		t.Fatalf("resumable upload sent %d chunk requests, want at least 4", chunks)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	srv, fs := newDriveFS(t, drivefstest.Options{})
+	doc, err := srv.Drive().CreateFile(context.Background(), drivefs.CreateFileRequest{
+		File: &drive.File{Name: "doc", MimeType: "application/vnd.google-apps.document"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+
+	wantCode := func(t *testing.T, err error, code int, reason string) {
+		t.Helper()
+		var gerr *googleapi.Error
+		if !errors.As(err, &gerr) || gerr.Code != code {
+			t.Fatalf("error = %v, want googleapi.Error with code %d", err, code)
+		}
+		if len(gerr.Errors) == 0 || gerr.Errors[0].Reason != reason {
+			t.Fatalf("error items = %+v, want reason %q", gerr.Errors, reason)
+		}
+	}
+
+	t.Run("404", func(t *testing.T) {
+		_, err := fs.Info("missing")
+		if !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("Info() error = %v, want ErrNotFound", err)
+		}
+		err = fs.Move("missing", srv.RootID())
+		if !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("Move() error = %v, want ErrNotFound", err)
+		}
+	})
+	t.Run("403", func(t *testing.T) {
+		_, err := fs.ReadFile(drivefs.FileID(doc.Id))
+		if !errors.Is(err, drivefs.ErrNotReadable) {
+			t.Fatalf("ReadFile() error = %v, want ErrNotReadable", err)
+		}
+		_, err = fs.Copy(srv.RootID(), srv.RootID(), "copy")
+		wantCode(t, err, http.StatusForbidden, "cannotCopyFile")
+	})
+	t.Run("429", func(t *testing.T) {
+		srv.InjectFault(drivefstest.Fault{Method: http.MethodGet, Path: "/drive/v3/files/", Code: http.StatusTooManyRequests, RetryAfter: 2 * time.Second})
+		_, err := fs.Info(drivefs.FileID(doc.Id))
+		wantCode(t, err, http.StatusTooManyRequests, "rateLimitExceeded")
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Header.Get("Retry-After") != "2" {
+			t.Fatalf("Retry-After = %q, want %q", gerr.Header.Get("Retry-After"), "2")
+		}
+		if _, err := fs.Info(drivefs.FileID(doc.Id)); err != nil {
+			t.Fatalf("Info() after the fault error = %v", err)
+		}
+	})
+	t.Run("invalid query", func(t *testing.T) {
+		_, err := fs.Query("name ~ 'x'")
+		wantCode(t, err, http.StatusBadRequest, "invalid")
+	})
+	t.Run("range request", func(t *testing.T) {
+		f, err := fs.Create(srv.RootID(), "small.txt")
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := fs.WriteFile(f.ID, []byte("abc")); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		r, err := fs.Open(f.ID)
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		defer r.Close()
+		if _, err := r.Seek(1, io.SeekStart); err != nil {
+			t.Fatalf("Seek() error = %v", err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil || string(data) != "bc" {
+			t.Fatalf("ReadAll() = %q, %v, want %q", data, err, "bc")
+		}
+	})
+}
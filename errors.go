@@ -2,6 +2,8 @@ package drivefs
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 )
 
 // Common errors returned by DriveFS operations.
@@ -18,7 +20,7 @@ var (
 	// ErrNotFound is returned when a requested file or directory does not exist.
 	ErrNotFound = errors.New("not found")
 
-	// ErrAlreadyExists is returned when attempting to create a file or directory that already exists.
+	// ErrAlreadyExists is returned when attempting to create a file, directory or shared drive that already exists.
 	ErrAlreadyExists = errors.New("already exists")
 
 	// ErrMultiParentsNotSupported is returned when an operation encounters a file with multiple parents.
@@ -27,8 +29,21 @@ var (
 	// ErrNotReadable is returned when attempting to read a file that cannot be downloaded (e.g., Google Apps files).
 	ErrNotReadable = errors.New("not readable")
 
-	// ErrNotRemovable is returned when attempting to remove a non-empty directory.
+	// ErrNotRemovable is returned when attempting to remove a non-empty directory or shared drive.
 	ErrNotRemovable = errors.New("not removable")
+
+	// ErrAmbiguousPath is returned when a path that must identify a single file matches multiple files with duplicate names.
+	ErrAmbiguousPath = errors.New("ambiguous path")
+
+	// ErrNotExportable is returned when attempting to export a file that is not a Google Apps file
+	// or to a format that is not supported for its type.
+	ErrNotExportable = errors.New("not exportable")
+
+	// ErrNotImportable is returned when attempting to import data that cannot be converted into the requested Google Apps file type.
+	ErrNotImportable = errors.New("not importable")
+
+	// ErrInvalidDestination is returned when attempting to copy a directory into itself or one of its descendants.
+	ErrInvalidDestination = errors.New("invalid destination")
 )
 
 type wrapError struct {
@@ -47,6 +62,15 @@ func newDriveError(msg string, cause error) error {
 	}
 }
 
+// resourceError returns an error wrapping ErrNotFound that names target if err is 404 Not Found,
+// or a drive error with msg otherwise.
+func resourceError(target, msg string, err error) error {
+	if isStatus(err, http.StatusNotFound) {
+		return fmt.Errorf("%s not found: %w", target, ErrNotFound)
+	}
+	return newDriveError(msg, err)
+}
+
 func newIOError(msg string, cause error) error {
 	return &wrapError{
 		underlying: ErrIOError,
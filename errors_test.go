@@ -25,6 +25,10 @@ func TestErrVars_IsAndMessage(t *testing.T) {
 		{"ErrMultiParentsNotSupported", drivefs.ErrMultiParentsNotSupported, "multi parents not supported"},
 		{"ErrNotReadable", drivefs.ErrNotReadable, "not readable"},
 		{"ErrNotRemovable", drivefs.ErrNotRemovable, "not removable"},
+		{"ErrAmbiguousPath", drivefs.ErrAmbiguousPath, "ambiguous path"},
+		{"ErrNotExportable", drivefs.ErrNotExportable, "not exportable"},
+		{"ErrNotImportable", drivefs.ErrNotImportable, "not importable"},
+		{"ErrInvalidDestination", drivefs.ErrInvalidDestination, "invalid destination"},
 	}
 
 	for _, c := range cases {
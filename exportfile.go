@@ -0,0 +1,152 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// exportFormats lists the MIME types to which each type of Google Apps file can be exported.
+// See https://developers.google.com/workspace/drive/api/guides/ref-export-formats.
+var exportFormats = map[string][]string{
+	"application/vnd.google-apps.document": {
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.oasis.opendocument.text",
+		"application/rtf",
+		"application/pdf",
+		"text/plain",
+		"application/zip",
+		"application/epub+zip",
+		"text/markdown",
+	},
+	"application/vnd.google-apps.spreadsheet": {
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/x-vnd.oasis.opendocument.spreadsheet",
+		"application/pdf",
+		"application/zip",
+		"text/csv",
+		"text/tab-separated-values",
+	},
+	"application/vnd.google-apps.presentation": {
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.presentation",
+		"application/pdf",
+		"text/plain",
+		"image/jpeg",
+		"image/png",
+		"image/svg+xml",
+	},
+	"application/vnd.google-apps.drawing": {
+		"application/pdf",
+		"image/jpeg",
+		"image/png",
+		"image/svg+xml",
+	},
+	"application/vnd.google-apps.script": {
+		"application/vnd.google-apps.script+json",
+	},
+}
+
+// exportExtensions maps the MIME types of export formats to the file name extensions of exported files.
+var exportExtensions = map[string]string{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.oasis.opendocument.text":                                 ".odt",
+	"application/rtf":      ".rtf",
+	"application/pdf":      ".pdf",
+	"text/plain":           ".txt",
+	"application/zip":      ".zip",
+	"application/epub+zip": ".epub",
+	"text/markdown":        ".md",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": ".xlsx",
+	"application/x-vnd.oasis.opendocument.spreadsheet":                  ".ods",
+	"text/csv":                  ".csv",
+	"text/tab-separated-values": ".tsv",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.oasis.opendocument.presentation":                           ".odp",
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/svg+xml": ".svg",
+	"application/vnd.google-apps.script+json": ".json",
+}
+
+// ExportFormats returns the MIME types to which a Google Apps file of the given MIME type can be exported,
+// such as "application/pdf" and "text/markdown" for Google Docs.
+// Returns nil if files of the MIME type cannot be exported, including files that are not Google Apps files.
+func ExportFormats(mimeType string) []string {
+	return slices.Clone(exportFormats[mimeType])
+}
+
+// Export exports the Google Apps file with the given fileID to the given MIME type and returns the exported data.
+// The available MIME types are listed by FileInfo.ExportFormats and ExportFormats.
+// Returns ErrNotFound if the file does not exist, and ErrNotExportable if the file is not a Google Apps file
+// or cannot be exported to the MIME type.
+// Google Drive limits the size of exported content to 10 MB.
+func (s *DriveFS) Export(fileID FileID, mimeType string) (data []byte, err error) {
+	return s.ExportContext(context.Background(), fileID, mimeType)
+}
+
+// ExportContext is like Export but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ExportContext(ctx context.Context, fileID FileID, mimeType string) (data []byte, err error) {
+	body, err := s.OpenExportContext(ctx, fileID, mimeType)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := body.Close(); closeErr != nil && err == nil {
+			err = newIOError("failed to close export body", closeErr)
+		}
+	}()
+	data, err = io.ReadAll(body)
+	if err != nil {
+		return nil, newIOError("failed to read export body", err)
+	}
+	return data, nil
+}
+
+// OpenExport exports the Google Apps file with the given fileID to the given MIME type
+// and returns a reader that streams the exported data.
+// The caller must close the returned reader.
+// It fails in the same cases as Export.
+func (s *DriveFS) OpenExport(fileID FileID, mimeType string) (r io.ReadCloser, err error) {
+	return s.OpenExportContext(context.Background(), fileID, mimeType)
+}
+
+// OpenExportContext is like OpenExport but uses ctx for all Google Drive API calls made by the returned reader.
+func (s *DriveFS) OpenExportContext(ctx context.Context, fileID FileID, mimeType string) (r io.ReadCloser, err error) {
+	file, found, err := findByID(ctx, s.backend, string(fileID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("file '%s' not found: %w", fileID, ErrNotFound)
+	}
+	if !strings.HasPrefix(file.MimeType, mimeTypePrefixGoogleApp) {
+		return nil, fmt.Errorf("cannot export non-google-apps file '%s': %w", fileID, ErrNotExportable)
+	}
+	formats := exportFormatsOf(file.ExportLinks, file.MimeType)
+	if !slices.Contains(formats, mimeType) {
+		return nil, fmt.Errorf("cannot export '%s' of type '%s' to '%s': %w", fileID, file.MimeType, mimeType, ErrNotExportable)
+	}
+
+	body, err := s.backend.ExportFile(ctx, string(fileID), mimeType)
+	if err != nil {
+		return nil, newDriveError("failed to export file", err)
+	}
+	return body, nil
+}
+
+// exportFormatsOf returns the sorted MIME types of the export links of a file,
+// falling back to the known formats of its MIME type if the links are not available.
+func exportFormatsOf(exportLinks map[string]string, mimeType string) []string {
+	if len(exportLinks) == 0 {
+		return ExportFormats(mimeType)
+	}
+	formats := make([]string, 0, len(exportLinks))
+	for format := range exportLinks {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
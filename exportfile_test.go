@@ -0,0 +1,112 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefstest"
+	"google.golang.org/api/drive/v3"
+)
+
+func TestExportFormats(t *testing.T) {
+	cases := []struct {
+		mime string
+		want string
+	}{
+		{"application/vnd.google-apps.document", "text/markdown"},
+		{"application/vnd.google-apps.document", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{"application/vnd.google-apps.spreadsheet", "text/csv"},
+		{"application/vnd.google-apps.spreadsheet", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"application/vnd.google-apps.presentation", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		{"application/vnd.google-apps.drawing", "image/svg+xml"},
+	}
+	for _, c := range cases {
+		if got := drivefs.ExportFormats(c.mime); !slices.Contains(got, c.want) {
+			t.Fatalf("ExportFormats(%q) = %v, want to contain %q", c.mime, got, c.want)
+		}
+	}
+	for _, mime := range []string{"application/vnd.google-apps.folder", "text/plain", ""} {
+		if got := drivefs.ExportFormats(mime); got != nil {
+			t.Fatalf("ExportFormats(%q) = %v, want nil", mime, got)
+		}
+	}
+}
+
+func TestDriveFS_Export(t *testing.T) {
+	srv := drivefstest.NewServer(drivefstest.Options{})
+	t.Cleanup(srv.Close)
+	service, err := srv.NewService(context.Background())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	s := drivefs.New(service)
+
+	doc, err := srv.Drive().CreateFile(context.Background(), drivefs.CreateFileRequest{
+		File: &drive.File{Name: "doc", MimeType: "application/vnd.google-apps.document"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+	if err := srv.Drive().SetExport(doc.Id, "text/markdown", []byte("# Title\n")); err != nil {
+		t.Fatalf("SetExport() error = %v", err)
+	}
+	plain, err := s.Create(srv.RootID(), "plain.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	info, err := s.Info(drivefs.FileID(doc.Id))
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	want := drivefs.ExportFormats(doc.MimeType)
+	slices.Sort(want)
+	if !slices.Equal(info.ExportFormats, want) {
+		t.Fatalf("Info().ExportFormats = %v, want %v", info.ExportFormats, want)
+	}
+	if len(plain.ExportFormats) != 0 {
+		t.Fatalf("Create().ExportFormats = %v, want empty", plain.ExportFormats)
+	}
+
+	t.Run("Export", func(t *testing.T) {
+		data, err := s.Export(info.ID, "text/markdown")
+		if err != nil || string(data) != "# Title\n" {
+			t.Fatalf("Export() = %q, %v, want %q", data, err, "# Title\n")
+		}
+	})
+	t.Run("OpenExport", func(t *testing.T) {
+		r, err := s.OpenExport(info.ID, "text/markdown")
+		if err != nil {
+			t.Fatalf("OpenExport() error = %v", err)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil || string(data) != "# Title\n" {
+			t.Fatalf("ReadAll() = %q, %v, want %q", data, err, "# Title\n")
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			fileID drivefs.FileID
+			mime   string
+			want   error
+		}{
+			{"not_google_apps_file", plain.ID, "application/pdf", drivefs.ErrNotExportable},
+			{"unsupported_format", info.ID, "text/csv", drivefs.ErrNotExportable},
+			{"not_found", "missing", "application/pdf", drivefs.ErrNotFound},
+		}
+		for _, c := range cases {
+			if _, err := s.Export(c.fileID, c.mime); !errors.Is(err, c.want) {
+				t.Fatalf("%s: Export() error = %v, want %v", c.name, err, c.want)
+			}
+		}
+		if _, err := s.ReadFile(info.ID); !errors.Is(err, drivefs.ErrNotReadable) {
+			t.Fatalf("ReadFile() error = %v, want ErrNotReadable", err)
+		}
+	})
+}
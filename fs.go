@@ -0,0 +1,308 @@
+package drivefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS provides a read-only io/fs view of the Google Drive folder tree rooted at a given folder.
+// It implements fs.FS, fs.ReadDirFS, fs.StatFS, fs.ReadFileFS and fs.SubFS.
+//
+// Names passed to FS methods are unrooted, slash-separated paths as described in fs.ValidPath,
+// and are resolved relative to the root folder.
+// If a name resolves to more than one file because of duplicate names, the operation fails with ErrAmbiguousPath.
+// Shortcuts are reported as symbolic links by ReadDir and are followed by Open, Stat and ReadFile.
+type FS struct {
+	ctx     context.Context
+	driveFS *DriveFS
+	rootID  FileID
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.SubFS      = (*FS)(nil)
+)
+
+// NewFS creates a new FS rooted at the folder with the given rootID.
+func NewFS(driveFS *DriveFS, rootID FileID) *FS {
+	return NewFSContext(context.Background(), driveFS, rootID)
+}
+
+// NewFSContext is like NewFS but uses ctx for all Google Drive API calls made by the returned FS.
+func NewFSContext(ctx context.Context, driveFS *DriveFS, rootID FileID) *FS {
+	return &FS{ctx: ctx, driveFS: driveFS, rootID: rootID}
+}
+
+// Open opens the named file or directory.
+// The returned fs.File of a regular file also implements io.Seeker and io.ReaderAt,
+// and the one of a directory implements fs.ReadDirFile.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	info, err := fsys.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return &fsFile{fsys: fsys, name: name, info: info}, nil
+}
+
+// Stat returns the fs.FileInfo of the named file or directory.
+func (fsys *FS) Stat(name string) (fs.FileInfo, error) {
+	info, err := fsys.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fsFileInfo{name: path.Base(name), info: info}, nil
+}
+
+// ReadFile reads the named file and returns its contents.
+func (fsys *FS) ReadFile(name string) ([]byte, error) {
+	info, err := fsys.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsFolder() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDirectory}
+	}
+	data, err := fsys.driveFS.ReadFileContext(fsys.ctx, info.ID)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: toFSError(err)}
+	}
+	return data, nil
+}
+
+// ReadDir reads the named directory and returns its entries sorted by name.
+// Entries with duplicate names are all returned.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, err := fsys.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.readDir(name, info)
+}
+
+// Sub returns an FS rooted at the named directory.
+func (fsys *FS) Sub(dir string) (fs.FS, error) {
+	info, err := fsys.resolve("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsFolder() {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: errNotDirectory}
+	}
+	return NewFSContext(fsys.ctx, fsys.driveFS, info.ID), nil
+}
+
+func (fsys *FS) readDir(name string, info FileInfo) ([]fs.DirEntry, error) {
+	if !info.IsFolder() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDirectory}
+	}
+	children, err := fsys.driveFS.ReadDirContext(fsys.ctx, info.ID)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: toFSError(err)}
+	}
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(fsFileInfo{name: child.Name, info: child}))
+	}
+	slices.SortStableFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+func (fsys *FS) resolve(op, name string) (FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return FileInfo{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	p := Path("/")
+	if name != "." {
+		p = Path("/" + name)
+	}
+	found, err := fsys.driveFS.FindByPathContext(fsys.ctx, fsys.rootID, p)
+	if err != nil {
+		return FileInfo{}, &fs.PathError{Op: op, Path: name, Err: toFSError(err)}
+	}
+	switch len(found) {
+	case 0:
+		return FileInfo{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case 1:
+	default:
+		return FileInfo{}, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("%d files found: %w", len(found), ErrAmbiguousPath)}
+	}
+	info := found[0]
+	if info.IsShortcut() {
+		target, err := fsys.driveFS.InfoContext(fsys.ctx, info.ShortcutTarget)
+		if err != nil {
+			return FileInfo{}, &fs.PathError{Op: op, Path: name, Err: toFSError(err)}
+		}
+		info = target
+	}
+	return info, nil
+}
+
+var (
+	errIsDirectory  = errors.New("is a directory")
+	errNotDirectory = errors.New("not a directory")
+)
+
+func toFSError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return fs.ErrNotExist
+	case errors.Is(err, ErrInvalidPath):
+		return fs.ErrInvalid
+	default:
+		return err
+	}
+}
+
+type fsFileInfo struct {
+	name string
+	info FileInfo
+}
+
+var _ fs.FileInfo = fsFileInfo{}
+
+func (i fsFileInfo) Name() string {
+	return i.name
+}
+
+func (i fsFileInfo) Size() int64 {
+	return i.info.Size
+}
+
+func (i fsFileInfo) Mode() fs.FileMode {
+	switch {
+	case i.info.IsFolder():
+		return fs.ModeDir | 0o555
+	case i.info.IsShortcut():
+		return fs.ModeSymlink | 0o444
+	default:
+		return 0o444
+	}
+}
+
+func (i fsFileInfo) ModTime() time.Time {
+	return i.info.ModTime
+}
+
+func (i fsFileInfo) IsDir() bool {
+	return i.info.IsFolder()
+}
+
+// Sys returns the underlying FileInfo.
+func (i fsFileInfo) Sys() any {
+	return i.info
+}
+
+type fsFile struct {
+	fsys *FS
+	name string
+	info FileInfo
+
+	// content is opened on the first read of a regular file.
+	content *FileReader
+	// entries is loaded on the first ReadDir of a directory.
+	entries []fs.DirEntry
+	closed  bool
+}
+
+var (
+	_ fs.ReadDirFile = (*fsFile)(nil)
+	_ io.Seeker      = (*fsFile)(nil)
+	_ io.ReaderAt    = (*fsFile)(nil)
+)
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrClosed}
+	}
+	return fsFileInfo{name: path.Base(f.name), info: f.info}, nil
+}
+
+func (f *fsFile) Read(p []byte) (int, error) {
+	if err := f.load("read"); err != nil {
+		return 0, err
+	}
+	return f.content.Read(p)
+}
+
+func (f *fsFile) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.load("read"); err != nil {
+		return 0, err
+	}
+	return f.content.ReadAt(p, off)
+}
+
+func (f *fsFile) Seek(offset int64, whence int) (int64, error) {
+	if err := f.load("seek"); err != nil {
+		return 0, err
+	}
+	return f.content.Seek(offset, whence)
+}
+
+func (f *fsFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.entries == nil {
+		entries, err := f.fsys.readDir(f.name, f.info)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+	}
+	if n <= 0 {
+		entries := f.entries
+		f.entries = []fs.DirEntry{}
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *fsFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	f.entries = nil
+	if f.content != nil {
+		if err := f.content.Close(); err != nil {
+			return &fs.PathError{Op: "close", Path: f.name, Err: err}
+		}
+	}
+	return nil
+}
+
+func (f *fsFile) load(op string) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.info.IsFolder() {
+		return &fs.PathError{Op: op, Path: f.name, Err: errIsDirectory}
+	}
+	if f.content != nil {
+		return nil
+	}
+	content, err := newFileReader(f.fsys.ctx, f.fsys.driveFS.backend, f.info)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: f.name, Err: toFSError(err)}
+	}
+	f.content = content
+	return nil
+}
@@ -0,0 +1,123 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func TestFS_InvalidPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	service, err := drive.NewService(context.Background(),
+		option.WithEndpoint(srv.URL),
+		option.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	fsys := drivefs.NewFS(drivefs.New(service), "root")
+
+	names := []string{"/abs", "a/../b", "./a", "a/", ""}
+	for _, name := range names {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			if _, err := fsys.Open(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Fatalf("Open(%q) error = %v, want fs.ErrInvalid", name, err)
+			}
+			if _, err := fsys.Stat(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Fatalf("Stat(%q) error = %v, want fs.ErrInvalid", name, err)
+			}
+			if _, err := fsys.ReadFile(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Fatalf("ReadFile(%q) error = %v, want fs.ErrInvalid", name, err)
+			}
+			if _, err := fsys.ReadDir(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Fatalf("ReadDir(%q) error = %v, want fs.ErrInvalid", name, err)
+			}
+			if _, err := fsys.Sub(name); !errors.Is(err, fs.ErrInvalid) {
+				t.Fatalf("Sub(%q) error = %v, want fs.ErrInvalid", name, err)
+			}
+		})
+	}
+}
+
+func TestFS_TestFS(t *testing.T) {
+	d := drivefsmem.New(drivefsmem.Options{})
+	dfs := drivefs.NewWithBackend(d)
+
+	files := map[drivefs.Path]string{
+		"/a.txt":       "a",
+		"/dir/b.txt":   "bb",
+		"/dir/sub/c":   "ccc",
+		"/empty/.keep": "",
+	}
+	for p, content := range files {
+		dir, name := path.Split(string(p))
+		parent, err := dfs.MkdirAll(d.RootID(), drivefs.Path(path.Clean(dir)))
+		if err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		f, err := dfs.Create(parent.ID, name)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if err := dfs.WriteFile(f.ID, []byte(content)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	fsys := drivefs.NewFS(dfs, d.RootID())
+	if err := fstest.TestFS(fsys, "a.txt", "dir/b.txt", "dir/sub/c", "empty/.keep"); err != nil {
+		t.Fatalf("fstest.TestFS() error = %v", err)
+	}
+}
+
+func TestFS_AmbiguousPath(t *testing.T) {
+	d := drivefsmem.New(drivefsmem.Options{})
+	dfs := drivefs.NewWithBackend(d)
+	for range 2 {
+		if _, err := dfs.Create(d.RootID(), "dup.txt"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		dir, err := dfs.Mkdir(d.RootID(), "dup")
+		if err != nil {
+			t.Fatalf("Mkdir() error = %v", err)
+		}
+		if _, err := dfs.Create(dir.ID, "a.txt"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	fsys := drivefs.NewFS(dfs, d.RootID())
+	for _, name := range []string{"dup.txt", "dup/a.txt"} {
+		t.Run(name, func(t *testing.T) {
+			assertAmbiguous := func(op string, err error) {
+				t.Helper()
+				var pathErr *fs.PathError
+				if !errors.As(err, &pathErr) || !errors.Is(err, drivefs.ErrAmbiguousPath) {
+					t.Fatalf("%s(%q) error = %v, want *fs.PathError wrapping ErrAmbiguousPath", op, name, err)
+				}
+			}
+			_, err := fsys.Open(name)
+			assertAmbiguous("Open", err)
+			_, err = fsys.Stat(name)
+			assertAmbiguous("Stat", err)
+			_, err = fsys.ReadFile(name)
+			assertAmbiguous("ReadFile", err)
+		})
+	}
+}
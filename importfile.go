@@ -0,0 +1,156 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	mimeTypeGoogleAppDocument     = "application/vnd.google-apps.document"
+	mimeTypeGoogleAppSpreadsheet  = "application/vnd.google-apps.spreadsheet"
+	mimeTypeGoogleAppPresentation = "application/vnd.google-apps.presentation"
+)
+
+// importFormats lists the Google Apps MIME types into which files of each MIME type can be converted on upload.
+var importFormats = map[string][]string{
+	"text/csv":                  {mimeTypeGoogleAppSpreadsheet},
+	"text/tab-separated-values": {mimeTypeGoogleAppSpreadsheet},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": {mimeTypeGoogleAppSpreadsheet},
+	"application/vnd.ms-excel":                         {mimeTypeGoogleAppSpreadsheet},
+	"application/vnd.oasis.opendocument.spreadsheet":   {mimeTypeGoogleAppSpreadsheet},
+	"application/x-vnd.oasis.opendocument.spreadsheet": {mimeTypeGoogleAppSpreadsheet},
+
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": {mimeTypeGoogleAppDocument},
+	"application/msword":                      {mimeTypeGoogleAppDocument},
+	"application/vnd.oasis.opendocument.text": {mimeTypeGoogleAppDocument},
+	"application/rtf":                         {mimeTypeGoogleAppDocument},
+	"text/plain":                              {mimeTypeGoogleAppDocument},
+	"text/html":                               {mimeTypeGoogleAppDocument},
+	"text/markdown":                           {mimeTypeGoogleAppDocument},
+	"application/pdf":                         {mimeTypeGoogleAppDocument},
+	"image/jpeg":                              {mimeTypeGoogleAppDocument},
+	"image/png":                               {mimeTypeGoogleAppDocument},
+	"image/gif":                               {mimeTypeGoogleAppDocument},
+	"image/bmp":                               {mimeTypeGoogleAppDocument},
+
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": {mimeTypeGoogleAppPresentation},
+	"application/vnd.ms-powerpoint":                                             {mimeTypeGoogleAppPresentation},
+	"application/vnd.oasis.opendocument.presentation":                           {mimeTypeGoogleAppPresentation},
+}
+
+// importExtensions maps file name extensions to the MIME types of importable files.
+var importExtensions = map[string]string{
+	".csv":      "text/csv",
+	".tsv":      "text/tab-separated-values",
+	".tab":      "text/tab-separated-values",
+	".xlsx":     "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".xls":      "application/vnd.ms-excel",
+	".ods":      "application/vnd.oasis.opendocument.spreadsheet",
+	".docx":     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".doc":      "application/msword",
+	".odt":      "application/vnd.oasis.opendocument.text",
+	".rtf":      "application/rtf",
+	".txt":      "text/plain",
+	".html":     "text/html",
+	".htm":      "text/html",
+	".md":       "text/markdown",
+	".markdown": "text/markdown",
+	".pdf":      "application/pdf",
+	".jpg":      "image/jpeg",
+	".jpeg":     "image/jpeg",
+	".png":      "image/png",
+	".gif":      "image/gif",
+	".bmp":      "image/bmp",
+	".pptx":     "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".ppt":      "application/vnd.ms-powerpoint",
+	".odp":      "application/vnd.oasis.opendocument.presentation",
+}
+
+// ImportFormats returns the Google Apps MIME types into which a file of the given MIME type can be converted by Import,
+// such as "application/vnd.google-apps.spreadsheet" for "text/csv".
+// Returns nil if files of the MIME type cannot be converted.
+func ImportFormats(mimeType string) []string {
+	return slices.Clone(importFormats[mimeType])
+}
+
+// ImportOptions configures the conversion performed by Import.
+type ImportOptions struct {
+	// ContentType is the MIME type of the uploaded data, such as "text/csv".
+	// If empty, it is inferred from the extension of the name of the file.
+	ContentType string
+
+	// TargetMimeType is the Google Apps MIME type into which the data is converted.
+	// If empty, it is the first of ImportFormats(ContentType).
+	TargetMimeType string
+
+	// OCRLanguage is an ISO 639-1 language code, such as "en" or "ja",
+	// that hints the language of text recognized in images and PDFs.
+	OCRLanguage string
+
+	// ChunkSize is the number of bytes sent in each request of the upload.
+	// It is rounded up to a multiple of 256 KiB. Zero means 16 MiB.
+	ChunkSize int
+
+	// Progress, if not nil, is called with the total number of bytes committed so far
+	// each time a chunk has been uploaded. Data that fits in a single chunk is uploaded without progress reports.
+	Progress func(uploaded int64)
+}
+
+// Import uploads the data read from r until EOF as a new file with the given name in the specified parent directory,
+// converting it into a Google Apps file such as a Google Doc, Sheet or Slides presentation.
+// For example, a CSV file is converted into a Google Sheet and a DOCX, Markdown or PDF file into a Google Doc.
+// Returns the FileInfo of the converted file.
+// Returns ErrNotImportable if the MIME type of the data is unknown or cannot be converted into opts.TargetMimeType.
+func (s *DriveFS) Import(parentID FileID, name string, r io.Reader, opts ImportOptions) (info FileInfo, err error) {
+	return s.ImportContext(context.Background(), parentID, name, r, opts)
+}
+
+// ImportContext is like Import but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ImportContext(ctx context.Context, parentID FileID, name string, r io.Reader, opts ImportOptions) (info FileInfo, err error) {
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = importExtensions[strings.ToLower(path.Ext(name))]
+	}
+	if contentType == "" {
+		contentType, _, _ = strings.Cut(mime.TypeByExtension(path.Ext(name)), ";")
+	}
+	if contentType == "" {
+		return FileInfo{}, fmt.Errorf("cannot infer the MIME type of '%s': %w", name, ErrNotImportable)
+	}
+	formats := importFormats[contentType]
+	targetMimeType := opts.TargetMimeType
+	if targetMimeType == "" && len(formats) > 0 {
+		targetMimeType = formats[0]
+	}
+	if !slices.Contains(formats, targetMimeType) {
+		return FileInfo{}, fmt.Errorf("cannot convert '%s' into '%s': %w", contentType, targetMimeType, ErrNotImportable)
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = googleapi.DefaultUploadChunkSize
+	}
+	file, err := s.backend.CreateFile(ctx, CreateFileRequest{
+		File: &drive.File{
+			Name:     name,
+			MimeType: targetMimeType,
+			Parents:  []string{string(parentID)},
+		},
+		Media:        r,
+		MediaOptions: []googleapi.MediaOption{googleapi.ContentType(contentType), googleapi.ChunkSize(chunkSize)},
+		OCRLanguage:  opts.OCRLanguage,
+		Progress:     opts.Progress,
+	})
+	if err != nil {
+		return FileInfo{}, newDriveError("failed to import file", err)
+	}
+	return newFileInfo(file)
+}
@@ -0,0 +1,97 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefstest"
+)
+
+func TestImportFormats(t *testing.T) {
+	cases := []struct {
+		mime string
+		want string
+	}{
+		{"text/csv", "application/vnd.google-apps.spreadsheet"},
+		{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "application/vnd.google-apps.spreadsheet"},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/vnd.google-apps.document"},
+		{"text/markdown", "application/vnd.google-apps.document"},
+		{"text/html", "application/vnd.google-apps.document"},
+		{"application/pdf", "application/vnd.google-apps.document"},
+		{"application/vnd.openxmlformats-officedocument.presentationml.presentation", "application/vnd.google-apps.presentation"},
+	}
+	for _, c := range cases {
+		if got := drivefs.ImportFormats(c.mime); len(got) == 0 || got[0] != c.want {
+			t.Fatalf("ImportFormats(%q) = %v, want %q first", c.mime, got, c.want)
+		}
+	}
+	if got := drivefs.ImportFormats("application/zip"); got != nil {
+		t.Fatalf("ImportFormats(%q) = %v, want nil", "application/zip", got)
+	}
+}
+
+func TestDriveFS_Import(t *testing.T) {
+	srv := drivefstest.NewServer(drivefstest.Options{})
+	t.Cleanup(srv.Close)
+	service, err := srv.NewService(context.Background())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	s := drivefs.New(service)
+
+	t.Run("CSV", func(t *testing.T) {
+		const csv = "name,amount\nfoo,1\n"
+		info, err := s.Import(srv.RootID(), "report.csv", strings.NewReader(csv), drivefs.ImportOptions{})
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		if info.Mime != "application/vnd.google-apps.spreadsheet" || info.Name != "report.csv" {
+			t.Fatalf("Import() = %+v, want a Google Sheet named report.csv", info)
+		}
+		data, err := s.Export(info.ID, "text/csv")
+		if err != nil || string(data) != csv {
+			t.Fatalf("Export() = %q, %v, want %q", data, err, csv)
+		}
+	})
+
+	t.Run("OCR", func(t *testing.T) {
+		info, err := s.Import(srv.RootID(), "scan", strings.NewReader("%PDF-1.4"), drivefs.ImportOptions{
+			ContentType: "application/pdf",
+			OCRLanguage: "ja",
+		})
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		if info.Mime != "application/vnd.google-apps.document" {
+			t.Fatalf("Import().Mime = %q, want a Google Doc", info.Mime)
+		}
+		var ocrLanguage string
+		for _, r := range srv.Requests() {
+			if r.Path == "/upload/drive/v3/files" {
+				ocrLanguage = r.Query.Get("ocrLanguage")
+			}
+		}
+		if ocrLanguage != "ja" {
+			t.Fatalf("ocrLanguage = %q, want %q", ocrLanguage, "ja")
+		}
+	})
+
+	t.Run("not importable", func(t *testing.T) {
+		cases := []struct {
+			name string
+			opts drivefs.ImportOptions
+		}{
+			{"unknown.bin", drivefs.ImportOptions{}},
+			{"archive.zip", drivefs.ImportOptions{ContentType: "application/zip"}},
+			{"table.csv", drivefs.ImportOptions{TargetMimeType: "application/vnd.google-apps.presentation"}},
+		}
+		for _, c := range cases {
+			if _, err := s.Import(srv.RootID(), c.name, strings.NewReader("x"), c.opts); !errors.Is(err, drivefs.ErrNotImportable) {
+				t.Fatalf("Import(%q) error = %v, want ErrNotImportable", c.name, err)
+			}
+		}
+	})
+}
@@ -31,11 +31,22 @@ type FileInfo struct {
 	// ModTime is the last modification time.
 	ModTime time.Time
 
+	// MD5Checksum is the hex-encoded MD5 checksum of the content.
+	// For directories and Google Apps files, this is empty.
+	MD5Checksum string
+
+	// Trashed is true if the file is in the trash, explicitly or because one of its ancestors is.
+	Trashed bool
+
 	// ShortcutTarget is the ID of the target file if this is a shortcut, empty otherwise.
 	ShortcutTarget FileID
 
 	// WebViewLink is the URL to view the file in a web browser.
 	WebViewLink string
+
+	// ExportFormats is the list of MIME types to which the file can be exported by Export.
+	// It is empty unless the file is a Google Apps file that can be exported.
+	ExportFormats []string
 }
 
 // IsFolder returns true if this FileInfo represents a directory.
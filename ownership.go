@@ -0,0 +1,177 @@
+package drivefs
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// TransferOptions configures TransferOwnership and TransferOwnershipAll.
+type TransferOptions struct {
+	// Pending requests the transfer instead of transferring the ownership immediately,
+	// which is required for files owned by consumer accounts.
+	// The new owner becomes a writer and a pending owner, reported by Permission.PendingOwner,
+	// and becomes the owner when they accept the transfer.
+	Pending bool
+
+	// EmailMessage, if not empty, is a plain text message included in the notification email sent to the new owner.
+	EmailMessage string
+}
+
+// TransferResult is the result of TransferOwnershipAll.
+// Paths are relative to the root of the tree, which is "/".
+type TransferResult struct {
+	// Transferred lists the items whose ownership has been transferred, or requested with TransferOptions.Pending.
+	Transferred []Path
+
+	// Unchanged lists the items that are already owned by the new owner,
+	// or whose transfer to the new owner is already pending with TransferOptions.Pending.
+	Unchanged []Path
+
+	// Errors lists the items that could not be transferred, ordered by path.
+	// The contents of a directory that could not be listed are not transferred.
+	Errors []TransferError
+}
+
+// TransferError is a failure to transfer the ownership of an item of the tree transferred by TransferOwnershipAll.
+type TransferError struct {
+	// FileID is the ID of the item.
+	FileID FileID
+
+	// Path is the path of the item relative to the root, such as "/dir/file.txt".
+	Path Path
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+// TransferOwnership transfers the ownership of the file or directory with the given fileID
+// to the user with the given newOwnerEmail, and the previous owner becomes a writer.
+// The ownership of the contents of a directory is not transferred; use TransferOwnershipAll for them.
+// Only the owner can transfer the ownership, and items in shared drives, which are owned by the shared drive, cannot be transferred.
+// Returns all permissions after the operation.
+func (s *DriveFS) TransferOwnership(fileID FileID, newOwnerEmail string, opts TransferOptions) (permissions []Permission, err error) {
+	return s.TransferOwnershipContext(context.Background(), fileID, newOwnerEmail, opts)
+}
+
+// TransferOwnershipContext is like TransferOwnership but uses ctx for all Google Drive API calls.
+func (s *DriveFS) TransferOwnershipContext(ctx context.Context, fileID FileID, newOwnerEmail string, opts TransferOptions) (permissions []Permission, err error) {
+	if _, err := transferOwnership(ctx, s.backend, string(fileID), newOwnerEmail, opts); err != nil {
+		return nil, err
+	}
+	perms, err := listPermissions(ctx, s.backend, string(fileID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer ownership: %w", err)
+	}
+	return newPermissions(perms), nil
+}
+
+// TransferOwnershipAll transfers the ownership of the file or directory with the given rootID and all of its descendants
+// to the user with the given newOwnerEmail, as TransferOwnership does for a single item. Trashed items are ignored.
+//
+// A failure to transfer an item, for example because it is owned by another user, does not stop the transfer
+// but is reported in TransferResult.Errors.
+// Returns an error if ctx is done, and ErrNotFound if the root does not exist.
+func (s *DriveFS) TransferOwnershipAll(rootID FileID, newOwnerEmail string, opts TransferOptions) (result TransferResult, err error) {
+	return s.TransferOwnershipAllContext(context.Background(), rootID, newOwnerEmail, opts)
+}
+
+// TransferOwnershipAllContext is like TransferOwnershipAll but uses ctx for all Google Drive API calls.
+func (s *DriveFS) TransferOwnershipAllContext(ctx context.Context, rootID FileID, newOwnerEmail string, opts TransferOptions) (result TransferResult, err error) {
+	root, found, err := findByID(ctx, s.backend, string(rootID))
+	if err != nil {
+		return TransferResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return TransferResult{}, fmt.Errorf("file not found: %s: %w", rootID, ErrNotFound)
+	}
+	err = s.transferTree(ctx, root, nil, newOwnerEmail, opts, &result)
+	slices.SortStableFunc(result.Errors, func(a, b TransferError) int { return cmp.Compare(a.Path, b.Path) })
+	return result, err
+}
+
+// transferTree transfers the ownership of file and its descendants, recording the results in result.
+// Returns an error only if ctx is done.
+func (s *DriveFS) transferTree(ctx context.Context, file *drive.File, rel []string, newOwnerEmail string, opts TransferOptions, result *TransferResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	fail := func(err error) {
+		result.Errors = append(result.Errors, TransferError{FileID: FileID(file.Id), Path: syncPath(rel), Err: err})
+	}
+	changed, err := transferOwnership(ctx, s.backend, file.Id, newOwnerEmail, opts)
+	switch {
+	case err != nil:
+		fail(err)
+	case changed:
+		result.Transferred = append(result.Transferred, syncPath(rel))
+	default:
+		result.Unchanged = append(result.Unchanged, syncPath(rel))
+	}
+	if file.MimeType != mimeTypeGoogleAppFolder {
+		return nil
+	}
+	children, err := findAllIn(ctx, s.backend, file.Id)
+	if err != nil {
+		fail(fmt.Errorf("failed to list files: %w", err))
+		return ctx.Err()
+	}
+	for _, child := range children {
+		if err := s.transferTree(ctx, child, append(slices.Clone(rel), child.Name), newOwnerEmail, opts, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transferOwnership transfers the ownership of the file with the given fileID to the user with the given newOwnerEmail,
+// or requests the transfer with opts.Pending, and reports whether the permissions of the file have been changed.
+func transferOwnership(ctx context.Context, b Backend, fileID, newOwnerEmail string, opts TransferOptions) (changed bool, err error) {
+	perms, err := listPermissions(ctx, b, fileID)
+	if err != nil {
+		return false, fmt.Errorf("failed to transfer ownership: %w", err)
+	}
+	var current *drive.Permission
+	for _, perm := range perms {
+		if !granteeMatch(perm, User(newOwnerEmail)) {
+			continue
+		}
+		if perm.Role == string(RoleOwner) || opts.Pending && perm.PendingOwner {
+			return false, nil
+		}
+		if !inheritedOnly(perm) {
+			current = perm
+		}
+	}
+
+	role := RoleOwner
+	if opts.Pending {
+		role = RoleWriter
+	}
+	if current != nil {
+		_, err = b.UpdatePermission(ctx, UpdatePermissionRequest{
+			FileID:            fileID,
+			Permission:        &drive.Permission{Id: current.Id, Role: string(role), PendingOwner: opts.Pending},
+			TransferOwnership: !opts.Pending,
+		})
+	} else {
+		_, err = b.CreatePermission(ctx, CreatePermissionRequest{
+			FileID: fileID,
+			Permission: &drive.Permission{
+				Type:         granteeTypeUser,
+				EmailAddress: newOwnerEmail,
+				Role:         string(role),
+				PendingOwner: opts.Pending,
+			},
+			EmailMessage:      opts.EmailMessage,
+			TransferOwnership: !opts.Pending,
+		})
+	}
+	if err != nil {
+		return false, newDriveError("failed to transfer ownership", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,116 @@
+package drivefs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func TestDriveFS_TransferOwnership(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{UserEmail: "owner@example.com"})
+	s := drivefs.NewWithBackend(mem)
+	owner, bob, carol := drivefs.User("owner@example.com"), drivefs.User("bob@example.com"), drivefs.User("carol@example.com")
+
+	t.Run("transfer", func(t *testing.T) {
+		file := writeRemoteFile(t, s, mem.RootID(), "transfer.txt", "data")
+		if _, err := s.PermSet(file.ID, drivefs.UserPermission("bob@example.com", drivefs.RoleReader)); err != nil {
+			t.Fatalf("PermSet() error = %v", err)
+		}
+		if _, err := s.TransferOwnership(file.ID, "bob@example.com", drivefs.TransferOptions{EmailMessage: "Handing over"}); err != nil {
+			t.Fatalf("TransferOwnership() error = %v", err)
+		}
+		if perm := findPermission(t, s, file.ID, bob); perm == nil || perm.Role() != drivefs.RoleOwner {
+			t.Fatalf("permission of bob = %+v, want owner", perm)
+		}
+		if perm := findPermission(t, s, file.ID, owner); perm == nil || perm.Role() != drivefs.RoleWriter {
+			t.Fatalf("permission of the previous owner = %+v, want writer", perm)
+		}
+		if _, err := s.TransferOwnership(file.ID, "bob@example.com", drivefs.TransferOptions{}); err != nil {
+			t.Fatalf("TransferOwnership() to the owner error = %v", err)
+		}
+		if _, err := s.TransferOwnership(file.ID, "carol@example.com", drivefs.TransferOptions{}); !errors.Is(err, drivefs.ErrDriveError) {
+			t.Fatalf("TransferOwnership() by a writer error = %v, want ErrDriveError", err)
+		}
+		if perm := findPermission(t, s, file.ID, carol); perm != nil {
+			t.Fatalf("permission of carol = %+v, want none", perm)
+		}
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		file := writeRemoteFile(t, s, mem.RootID(), "pending.txt", "data")
+		perms, err := s.TransferOwnership(file.ID, "bob@example.com", drivefs.TransferOptions{Pending: true})
+		if err != nil {
+			t.Fatalf("TransferOwnership() error = %v", err)
+		}
+		if len(perms) != 2 {
+			t.Fatalf("TransferOwnership() = %+v, want the owner and bob", perms)
+		}
+		if perm := findPermission(t, s, file.ID, bob); perm == nil || perm.Role() != drivefs.RoleWriter || !perm.PendingOwner() {
+			t.Fatalf("permission of bob = %+v, want a pending owner", perm)
+		}
+		if perm := findPermission(t, s, file.ID, owner); perm == nil || perm.Role() != drivefs.RoleOwner || perm.PendingOwner() {
+			t.Fatalf("permission of the owner = %+v, want owner", perm)
+		}
+
+		if _, err := s.TransferOwnership(file.ID, "bob@example.com", drivefs.TransferOptions{}); err != nil {
+			t.Fatalf("TransferOwnership() to the pending owner error = %v", err)
+		}
+		if perm := findPermission(t, s, file.ID, bob); perm == nil || perm.Role() != drivefs.RoleOwner || perm.PendingOwner() {
+			t.Fatalf("permission of bob = %+v, want owner", perm)
+		}
+	})
+
+	t.Run("shared drive", func(t *testing.T) {
+		team, err := s.CreateSharedDrive("Team", "")
+		if err != nil {
+			t.Fatalf("CreateSharedDrive() error = %v", err)
+		}
+		file := writeRemoteFile(t, s, team.RootID, "file.txt", "data")
+		if _, err := s.TransferOwnership(file.ID, "bob@example.com", drivefs.TransferOptions{}); !errors.Is(err, drivefs.ErrDriveError) {
+			t.Fatalf("TransferOwnership() in a shared drive error = %v, want ErrDriveError", err)
+		}
+	})
+
+	t.Run("not a user", func(t *testing.T) {
+		file := writeRemoteFile(t, s, mem.RootID(), "invalid.txt", "data")
+		if _, err := s.TransferOwnership(file.ID, "", drivefs.TransferOptions{}); !errors.Is(err, drivefs.ErrDriveError) {
+			t.Fatalf("TransferOwnership() without email error = %v, want ErrDriveError", err)
+		}
+	})
+}
+
+func TestDriveFS_TransferOwnershipAll(t *testing.T) {
+	s, ids := newPermTree(t)
+	if _, err := s.TransferOwnership(ids["/docs/b.txt"], "carol@example.com", drivefs.TransferOptions{}); err != nil {
+		t.Fatalf("TransferOwnership() error = %v", err)
+	}
+	if _, err := s.TransferOwnership(ids["/a.txt"], "bob@example.com", drivefs.TransferOptions{}); err != nil {
+		t.Fatalf("TransferOwnership() error = %v", err)
+	}
+
+	result, err := s.TransferOwnershipAll(ids["/"], "bob@example.com", drivefs.TransferOptions{})
+	if err != nil {
+		t.Fatalf("TransferOwnershipAll() error = %v", err)
+	}
+	assertPaths(t, "Transferred", result.Transferred, "/", "/docs", "/docs/c.txt")
+	assertPaths(t, "Unchanged", result.Unchanged, "/a.txt")
+	if len(result.Errors) != 1 || result.Errors[0].Path != "/docs/b.txt" || result.Errors[0].FileID != ids["/docs/b.txt"] ||
+		!errors.Is(result.Errors[0].Err, drivefs.ErrDriveError) {
+		t.Fatalf("TransferOwnershipAll() errors = %+v, want a drive error for /docs/b.txt", result.Errors)
+	}
+	for p, id := range ids {
+		want := "bob@example.com"
+		if p == "/docs/b.txt" {
+			want = "carol@example.com"
+		}
+		if perm := findPermission(t, s, id, drivefs.User(want)); perm == nil || perm.Role() != drivefs.RoleOwner {
+			t.Fatalf("permission of %s on %s = %+v, want owner", want, p, perm)
+		}
+	}
+
+	if _, err := s.TransferOwnershipAll("unknown", "bob@example.com", drivefs.TransferOptions{}); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("TransferOwnershipAll() error = %v, want ErrNotFound", err)
+	}
+}
@@ -1,5 +1,7 @@
 package drivefs
 
+import "time"
+
 // PermissionID represents a unique identifier for a permission.
 type PermissionID string
 
@@ -20,14 +22,63 @@ type Permission interface {
 	// by users who have this permission (applicable to domain and anyone permissions).
 	AllowFileDiscovery() bool
 
+	// ExpirationTime returns the time at which the permission expires and is removed,
+	// or the zero time if it does not expire.
+	ExpirationTime() time.Time
+
+	// PendingOwner returns true if the ownership of the file has been requested to be transferred to the grantee,
+	// who becomes the owner by accepting the transfer. See TransferOptions.Pending.
+	PendingOwner() bool
+
+	// Inherited returns true if the permission is only inherited from a parent directory or a shared drive,
+	// rather than set on the file itself, which means that it cannot be removed from the file.
+	Inherited() bool
+
+	// InheritedFrom returns the ID of the item from which the permission is inherited, or an empty FileID if it is not inherited.
+	InheritedFrom() FileID
+
+	// Details returns where the roles granted by this permission come from.
+	// The Role of the permission is the highest role among them.
+	// Details are empty for permissions that have not been listed from Google Drive.
+	Details() []PermissionDetail
+
 	doNotImplement(Permission)
 }
 
+// PermissionType describes how a role granted by a permission is given.
+type PermissionType string
+
+const (
+	// PermissionTypeFile means the role is granted by a permission on a file or directory.
+	PermissionTypeFile PermissionType = "file"
+
+	// PermissionTypeMember means the role is granted by the membership of a shared drive.
+	PermissionTypeMember PermissionType = "member"
+)
+
+// PermissionDetail describes a role granted by a permission and where it comes from.
+type PermissionDetail struct {
+	// Type describes how the role is given.
+	Type PermissionType
+
+	// Role is the role granted.
+	Role Role
+
+	// Inherited is true if the role is inherited from a parent directory or a shared drive.
+	Inherited bool
+
+	// InheritedFrom is the ID of the item from which the role is inherited, or empty if it is not inherited.
+	InheritedFrom FileID
+}
+
 type permission struct {
 	grantee            Grantee
 	role               Role
 	id                 PermissionID
 	allowFileDiscovery bool
+	expirationTime     time.Time
+	pendingOwner       bool
+	details            []PermissionDetail
 }
 
 // UserPermission creates a Permission for a specific user identified by email.
@@ -52,6 +103,15 @@ func AnyonePermission(role Role, allowFileDiscovery bool) Permission {
 	return permission{grantee: Anyone(), role: role, allowFileDiscovery: allowFileDiscovery}
 }
 
+// WithExpiration returns a Permission that is the same as the given permission
+// but expires at expirationTime, or never if expirationTime is zero.
+// Only permissions for users and groups can expire.
+func WithExpiration(p Permission, expirationTime time.Time) Permission {
+	perm := p.(permission)
+	perm.expirationTime = expirationTime
+	return perm
+}
+
 func (p permission) Grantee() Grantee {
 	return p.grantee
 }
@@ -68,4 +128,34 @@ func (p permission) AllowFileDiscovery() bool {
 	return p.allowFileDiscovery
 }
 
+func (p permission) ExpirationTime() time.Time {
+	return p.expirationTime
+}
+
+func (p permission) PendingOwner() bool {
+	return p.pendingOwner
+}
+
+func (p permission) Inherited() bool {
+	for _, detail := range p.details {
+		if !detail.Inherited {
+			return false
+		}
+	}
+	return len(p.details) > 0
+}
+
+func (p permission) InheritedFrom() FileID {
+	for _, detail := range p.details {
+		if detail.Inherited {
+			return detail.InheritedFrom
+		}
+	}
+	return ""
+}
+
+func (p permission) Details() []PermissionDetail {
+	return p.details
+}
+
 func (p permission) doNotImplement(Permission) {}
@@ -3,6 +3,7 @@ package drivefs_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jumpaku/go-drivefs"
 )
@@ -50,3 +51,17 @@ func TestPermission_ConstructorsAndAccessors(t *testing.T) {
 		})
 	}
 }
+
+func TestWithExpiration(t *testing.T) {
+	expiration := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := drivefs.WithExpiration(drivefs.UserPermission("alice@example.com", drivefs.RoleReader), expiration)
+	if !p.ExpirationTime().Equal(expiration) || p.Role() != drivefs.RoleReader || p.Grantee() != drivefs.User("alice@example.com") {
+		t.Fatalf("WithExpiration() = %+v, want a reader permission for alice expiring at %v", p, expiration)
+	}
+	if p := drivefs.WithExpiration(p, time.Time{}); !p.ExpirationTime().IsZero() {
+		t.Fatalf("WithExpiration() with zero time = %+v, want no expiration", p)
+	}
+	if p := drivefs.UserPermission("alice@example.com", drivefs.RoleReader); !p.ExpirationTime().IsZero() {
+		t.Fatalf("UserPermission().ExpirationTime() = %v, want zero", p.ExpirationTime())
+	}
+}
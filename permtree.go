@@ -0,0 +1,214 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// PermTreeOptions configures PermSetAll and PermDelAll.
+type PermTreeOptions struct {
+	// DryRun computes the PermTreeResult without changing any permission.
+	DryRun bool
+
+	// FilesOnly restricts the changes to files and leaves the permissions of directories unchanged,
+	// so that items added to the directories later are not affected. Directories are not listed in the PermTreeResult.
+	FilesOnly bool
+
+	// PermSetOptions configures the notification emails sent for each item on which PermSetAll creates a permission.
+	// It is ignored by PermDelAll.
+	PermSetOptions
+}
+
+// PermTreeResult is the result of PermSetAll and PermDelAll.
+// Paths are relative to the root of the tree, which is "/".
+type PermTreeResult struct {
+	// Changed lists the items whose permissions have been changed, or would be changed in a dry run.
+	Changed []Path
+
+	// Inherited lists the items on which the grantee inherits a permission from outside the changed items.
+	// PermSetAll skips the items that inherit a permission with at least the role to be set,
+	// including the descendants of changed directories.
+	// PermDelAll cannot remove inherited permissions, so the grantee keeps access to these items,
+	// which may also be listed in Changed.
+	Inherited []Path
+
+	// Unchanged lists the items that already have the permission set by PermSetAll,
+	// or on which the grantee has no permission left to delete by PermDelAll.
+	Unchanged []Path
+}
+
+// PermSetAll sets a permission for the file or directory with the given rootID and all of its descendants,
+// as PermSet does for a single item.
+// Items that inherit a permission for the same grantee with at least the same role are skipped,
+// and so are the descendants of the directories on which the permission is set,
+// since they inherit it. Shortcuts and trashed items are ignored.
+// Google Drive notifies the grantee of each new permission by email unless opts.SuppressNotificationEmail is set.
+// Returns ErrNotFound if the root does not exist.
+func (s *DriveFS) PermSetAll(rootID FileID, permission Permission, opts PermTreeOptions) (result PermTreeResult, err error) {
+	return s.PermSetAllContext(context.Background(), rootID, permission, opts)
+}
+
+// PermSetAllContext is like PermSetAll but uses ctx for all Google Drive API calls.
+func (s *DriveFS) PermSetAllContext(ctx context.Context, rootID FileID, permission Permission, opts PermTreeOptions) (result PermTreeResult, err error) {
+	root, found, err := findByID(ctx, s.backend, string(rootID))
+	if err != nil {
+		return PermTreeResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return PermTreeResult{}, fmt.Errorf("file not found: %s: %w", rootID, ErrNotFound)
+	}
+	t := &permTree{s: s, opts: opts, result: &result}
+	err = t.walk(ctx, root, nil, false, func(file *drive.File, rel []string, inherited bool) (changed bool, err error) {
+		if inherited {
+			t.result.Inherited = append(t.result.Inherited, syncPath(rel))
+			return false, nil
+		}
+		perms, err := listPermissions(ctx, s.backend, file.Id)
+		if err != nil {
+			return false, fmt.Errorf("failed to set permissions: %w", err)
+		}
+		switch {
+		case inheritedRole(perms, permission.Grantee()).rank() >= permission.Role().rank():
+			t.result.Inherited = append(t.result.Inherited, syncPath(rel))
+			return false, nil
+		case slices.ContainsFunc(perms, func(perm *drive.Permission) bool {
+			expirationTime, _ := time.Parse(time.RFC3339, perm.ExpirationTime)
+			return granteeMatch(perm, permission.Grantee()) && !inheritedOnly(perm) &&
+				perm.Role == string(permission.Role()) && perm.AllowFileDiscovery == permission.AllowFileDiscovery() &&
+				expirationTime.Equal(permission.ExpirationTime())
+		}):
+			t.result.Unchanged = append(t.result.Unchanged, syncPath(rel))
+			return false, nil
+		}
+		if !opts.DryRun {
+			if _, err := setPermissionIn(ctx, s.backend, file.Id, perms, permission, opts.PermSetOptions); err != nil {
+				return false, err
+			}
+		}
+		t.result.Changed = append(t.result.Changed, syncPath(rel))
+		return true, nil
+	})
+	return result, err
+}
+
+// PermDelAll deletes all permissions matching the given grantee for the file or directory with the given rootID
+// and all of its descendants, as PermDel does for a single item.
+// Permissions inherited from outside the tree, or from directories that are left unchanged with opts.FilesOnly,
+// cannot be deleted, and the items that inherit them are listed in PermTreeResult.Inherited.
+// Shortcuts and trashed items are ignored.
+// Returns ErrNotFound if the root does not exist.
+func (s *DriveFS) PermDelAll(rootID FileID, grantee Grantee, opts PermTreeOptions) (result PermTreeResult, err error) {
+	return s.PermDelAllContext(context.Background(), rootID, grantee, opts)
+}
+
+// PermDelAllContext is like PermDelAll but uses ctx for all Google Drive API calls.
+func (s *DriveFS) PermDelAllContext(ctx context.Context, rootID FileID, grantee Grantee, opts PermTreeOptions) (result PermTreeResult, err error) {
+	root, found, err := findByID(ctx, s.backend, string(rootID))
+	if err != nil {
+		return PermTreeResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return PermTreeResult{}, fmt.Errorf("file not found: %s: %w", rootID, ErrNotFound)
+	}
+	// changed holds the IDs of the directories whose permissions for grantee have been deleted,
+	// which are no longer inherited by their descendants.
+	changed := map[string]bool{}
+	t := &permTree{s: s, opts: opts, result: &result}
+	err = t.walk(ctx, root, nil, false, func(file *drive.File, rel []string, _ bool) (bool, error) {
+		perms, err := listPermissions(ctx, s.backend, file.Id)
+		if err != nil {
+			return false, fmt.Errorf("failed to delete permissions: %w", err)
+		}
+		var direct, inherited bool
+		for _, perm := range perms {
+			if !granteeMatch(perm, grantee) {
+				continue
+			}
+			direct = direct || len(perm.PermissionDetails) == 0
+			for _, detail := range perm.PermissionDetails {
+				if !detail.Inherited {
+					direct = true
+				} else if !changed[detail.InheritedFrom] {
+					inherited = true
+				}
+			}
+		}
+		if direct {
+			if !opts.DryRun {
+				if _, err := deletePermissionsIn(ctx, s.backend, file.Id, perms, grantee); err != nil {
+					return false, err
+				}
+			}
+			t.result.Changed = append(t.result.Changed, syncPath(rel))
+			if file.MimeType == mimeTypeGoogleAppFolder {
+				changed[file.Id] = true
+			}
+		}
+		if inherited {
+			t.result.Inherited = append(t.result.Inherited, syncPath(rel))
+		}
+		if !direct && !inherited {
+			t.result.Unchanged = append(t.result.Unchanged, syncPath(rel))
+		}
+		return direct, nil
+	})
+	return result, err
+}
+
+type permTree struct {
+	s      *DriveFS
+	opts   PermTreeOptions
+	result *PermTreeResult
+}
+
+// walk calls f with file and then with each of its descendants, except shortcuts, trashed items
+// and directories with opts.FilesOnly, whose descendants are still visited.
+// inherited is true if f has returned changed as true for an ancestor of the item.
+func (t *permTree) walk(ctx context.Context, file *drive.File, rel []string, inherited bool, f func(file *drive.File, rel []string, inherited bool) (changed bool, err error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	isDir := file.MimeType == mimeTypeGoogleAppFolder
+	if file.MimeType == mimeTypeGoogleAppShortcut {
+		return nil
+	}
+	if !isDir || !t.opts.FilesOnly {
+		changed, err := f(file, rel, inherited)
+		if err != nil {
+			return err
+		}
+		inherited = inherited || changed
+	}
+	if !isDir {
+		return nil
+	}
+	children, err := findAllIn(ctx, t.s.backend, file.Id)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	for _, child := range children {
+		if err := t.walk(ctx, child, append(slices.Clone(rel), child.Name), inherited, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inheritedRole returns the highest role that grantee inherits among perms, or an empty Role if there is none.
+func inheritedRole(perms []*drive.Permission, grantee Grantee) (role Role) {
+	for _, perm := range perms {
+		if !granteeMatch(perm, grantee) {
+			continue
+		}
+		for _, detail := range perm.PermissionDetails {
+			if detail.Inherited && Role(detail.Role).rank() > role.rank() {
+				role = Role(detail.Role)
+			}
+		}
+	}
+	return role
+}
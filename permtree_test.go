@@ -0,0 +1,220 @@
+package drivefs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func findPermission(t *testing.T, s *drivefs.DriveFS, fileID drivefs.FileID, grantee drivefs.Grantee) drivefs.Permission {
+	t.Helper()
+	perms, err := s.PermList(fileID)
+	if err != nil {
+		t.Fatalf("PermList() error = %v", err)
+	}
+	for _, perm := range perms {
+		if perm.Grantee() == grantee {
+			return perm
+		}
+	}
+	return nil
+}
+
+// newPermTree creates "/project" containing "a.txt", "docs/b.txt" and "docs/c.txt", and returns the IDs by path.
+func newPermTree(t *testing.T) (*drivefs.DriveFS, map[drivefs.Path]drivefs.FileID) {
+	t.Helper()
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	docs, err := s.MkdirAll(mem.RootID(), "/project/docs")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	project := findRemote(t, s, mem.RootID(), "/project")
+	return s, map[drivefs.Path]drivefs.FileID{
+		"/":           project.ID,
+		"/docs":       docs.ID,
+		"/a.txt":      writeRemoteFile(t, s, project.ID, "a.txt", "a").ID,
+		"/docs/b.txt": writeRemoteFile(t, s, docs.ID, "b.txt", "b").ID,
+		"/docs/c.txt": writeRemoteFile(t, s, docs.ID, "c.txt", "c").ID,
+	}
+}
+
+func TestDriveFS_PermList_Inherited(t *testing.T) {
+	s, ids := newPermTree(t)
+	alice := drivefs.User("alice@example.com")
+	if _, err := s.PermSet(ids["/docs"], drivefs.UserPermission("alice@example.com", drivefs.RoleReader)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+
+	perm := findPermission(t, s, ids["/docs/b.txt"], alice)
+	if perm == nil || !perm.Inherited() || perm.InheritedFrom() != ids["/docs"] || perm.Role() != drivefs.RoleReader {
+		t.Fatalf("permission on b.txt = %+v, want reader inherited from docs", perm)
+	}
+	if details := perm.Details(); len(details) != 1 || details[0].Type != drivefs.PermissionTypeFile {
+		t.Fatalf("details = %+v, want one file permission", details)
+	}
+	if perm := findPermission(t, s, ids["/docs"], alice); perm == nil || perm.Inherited() || perm.InheritedFrom() != "" {
+		t.Fatalf("permission on docs = %+v, want a direct permission", perm)
+	}
+	if perm := findPermission(t, s, ids["/a.txt"], alice); perm != nil {
+		t.Fatalf("permission on a.txt = %+v, want none", perm)
+	}
+
+	if _, err := s.PermSet(ids["/docs/b.txt"], drivefs.UserPermission("alice@example.com", drivefs.RoleWriter)); err != nil {
+		t.Fatalf("PermSet() error = %v", err)
+	}
+	perm = findPermission(t, s, ids["/docs/b.txt"], alice)
+	if perm.Inherited() || perm.Role() != drivefs.RoleWriter || len(perm.Details()) != 2 {
+		t.Fatalf("permission on b.txt = %+v, want a direct writer permission that also inherits reader", perm)
+	}
+
+	if _, err := s.PermDel(ids["/docs/c.txt"], alice); err != nil {
+		t.Fatalf("PermDel() of an inherited permission error = %v", err)
+	}
+	if perm := findPermission(t, s, ids["/docs/c.txt"], alice); perm == nil || !perm.Inherited() {
+		t.Fatalf("permission on c.txt = %+v, want the inherited permission kept", perm)
+	}
+}
+
+func TestDriveFS_PermSetAll(t *testing.T) {
+	alice := drivefs.UserPermission("alice@example.com", drivefs.RoleReader)
+
+	t.Run("directories", func(t *testing.T) {
+		s, ids := newPermTree(t)
+		result, err := s.PermSetAll(ids["/"], alice, drivefs.PermTreeOptions{})
+		if err != nil {
+			t.Fatalf("PermSetAll() error = %v", err)
+		}
+		assertPaths(t, "Changed", result.Changed, "/")
+		assertPaths(t, "Inherited", result.Inherited, "/a.txt", "/docs", "/docs/b.txt", "/docs/c.txt")
+		if perm := findPermission(t, s, ids["/docs/c.txt"], alice.Grantee()); perm == nil || perm.InheritedFrom() != ids["/"] {
+			t.Fatalf("permission on c.txt = %+v, want inherited from the root", perm)
+		}
+	})
+
+	t.Run("files only", func(t *testing.T) {
+		s, ids := newPermTree(t)
+		if _, err := s.PermSet(ids["/docs"], drivefs.UserPermission("alice@example.com", drivefs.RoleWriter)); err != nil {
+			t.Fatalf("PermSet() error = %v", err)
+		}
+		if _, err := s.PermSet(ids["/a.txt"], alice); err != nil {
+			t.Fatalf("PermSet() error = %v", err)
+		}
+		dryRun, err := s.PermSetAll(ids["/"], alice, drivefs.PermTreeOptions{FilesOnly: true, DryRun: true})
+		if err != nil {
+			t.Fatalf("PermSetAll() dry run error = %v", err)
+		}
+		result, err := s.PermSetAll(ids["/"], alice, drivefs.PermTreeOptions{FilesOnly: true})
+		if err != nil {
+			t.Fatalf("PermSetAll() error = %v", err)
+		}
+		for name, r := range map[string]drivefs.PermTreeResult{"dry run": dryRun, "run": result} {
+			t.Run(name, func(t *testing.T) {
+				assertPaths(t, "Changed", r.Changed)
+				assertPaths(t, "Inherited", r.Inherited, "/docs/b.txt", "/docs/c.txt")
+				assertPaths(t, "Unchanged", r.Unchanged, "/a.txt")
+			})
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		s, ids := newPermTree(t)
+		result, err := s.PermSetAll(ids["/"], alice, drivefs.PermTreeOptions{FilesOnly: true, DryRun: true})
+		if err != nil {
+			t.Fatalf("PermSetAll() error = %v", err)
+		}
+		assertPaths(t, "Changed", result.Changed, "/a.txt", "/docs/b.txt", "/docs/c.txt")
+		for _, id := range ids {
+			if perm := findPermission(t, s, id, alice.Grantee()); perm != nil {
+				t.Fatalf("permission after dry run = %+v, want none", perm)
+			}
+		}
+		if _, err := s.PermSetAll(ids["/"], alice, drivefs.PermTreeOptions{FilesOnly: true}); err != nil {
+			t.Fatalf("PermSetAll() error = %v", err)
+		}
+		if perm := findPermission(t, s, ids["/docs/b.txt"], alice.Grantee()); perm == nil || perm.Inherited() {
+			t.Fatalf("permission on b.txt = %+v, want a direct permission", perm)
+		}
+		if perm := findPermission(t, s, ids["/docs"], alice.Grantee()); perm != nil {
+			t.Fatalf("permission on docs = %+v, want none", perm)
+		}
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		permRequests := &permRequestBackend{Backend: mem}
+		s := drivefs.NewWithBackend(permRequests)
+		dir, err := s.Mkdir(mem.RootID(), "dir")
+		if err != nil {
+			t.Fatalf("Mkdir() error = %v", err)
+		}
+		writeRemoteFile(t, s, dir.ID, "a.txt", "a")
+		writeRemoteFile(t, s, dir.ID, "b.txt", "b")
+
+		opts := drivefs.PermTreeOptions{FilesOnly: true, PermSetOptions: drivefs.PermSetOptions{EmailMessage: "hi"}}
+		if _, err := s.PermSetAll(dir.ID, alice, opts); err != nil {
+			t.Fatalf("PermSetAll() error = %v", err)
+		}
+		bob := drivefs.UserPermission("bob@example.com", drivefs.RoleReader)
+		opts = drivefs.PermTreeOptions{FilesOnly: true, PermSetOptions: drivefs.PermSetOptions{SuppressNotificationEmail: true}}
+		if _, err := s.PermSetAll(dir.ID, bob, opts); err != nil {
+			t.Fatalf("PermSetAll() error = %v", err)
+		}
+		if len(permRequests.requests) != 4 {
+			t.Fatalf("PermSetAll() permission requests = %+v, want 4", permRequests.requests)
+		}
+		for i, req := range permRequests.requests {
+			if suppressed := i >= 2; req.SuppressNotificationEmail != suppressed || (!suppressed && req.EmailMessage != "hi") {
+				t.Fatalf("permission request %d = %+v, want SuppressNotificationEmail %v", i, req, suppressed)
+			}
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		s, _ := newPermTree(t)
+		if _, err := s.PermSetAll("unknown", alice, drivefs.PermTreeOptions{}); !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("PermSetAll() error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDriveFS_PermDelAll(t *testing.T) {
+	s, ids := newPermTree(t)
+	alice := drivefs.User("alice@example.com")
+	for _, p := range []drivefs.Path{"/docs", "/docs/b.txt", "/a.txt"} {
+		if _, err := s.PermSet(ids[p], drivefs.UserPermission("alice@example.com", drivefs.RoleReader)); err != nil {
+			t.Fatalf("PermSet() error = %v", err)
+		}
+	}
+
+	result, err := s.PermDelAll(ids["/"], alice, drivefs.PermTreeOptions{FilesOnly: true})
+	if err != nil {
+		t.Fatalf("PermDelAll() error = %v", err)
+	}
+	assertPaths(t, "Changed", result.Changed, "/a.txt", "/docs/b.txt")
+	assertPaths(t, "Inherited", result.Inherited, "/docs/b.txt", "/docs/c.txt")
+	if perm := findPermission(t, s, ids["/docs/b.txt"], alice); perm == nil || !perm.Inherited() {
+		t.Fatalf("permission on b.txt = %+v, want the inherited permission kept", perm)
+	}
+
+	dryRun, err := s.PermDelAll(ids["/"], alice, drivefs.PermTreeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("PermDelAll() dry run error = %v", err)
+	}
+	result, err = s.PermDelAll(ids["/"], alice, drivefs.PermTreeOptions{})
+	if err != nil {
+		t.Fatalf("PermDelAll() error = %v", err)
+	}
+	for name, r := range map[string]drivefs.PermTreeResult{"dry run": dryRun, "run": result} {
+		assertPaths(t, name+" Changed", r.Changed, "/docs")
+		assertPaths(t, name+" Inherited", r.Inherited)
+		assertPaths(t, name+" Unchanged", r.Unchanged, "/", "/a.txt", "/docs/b.txt", "/docs/c.txt")
+	}
+	for p, id := range ids {
+		if perm := findPermission(t, s, id, alice); perm != nil {
+			t.Fatalf("permission on %s = %+v, want none", p, perm)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package drivefs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Query is a search query for files in the Google Drive query syntax, built with functions such as NameEquals,
+// InParents and And, which escape their arguments so that they cannot change the structure of the query.
+// The zero Query matches all files.
+// Its String method renders the query for Query and it can be used in ListOptions.
+// See https://developers.google.com/drive/api/guides/search-files for the semantics of the terms.
+type Query struct {
+	expr string
+	// compound is true if expr combines terms with and or or, so that it needs parentheses as an operand.
+	compound bool
+}
+
+// String returns the query in the Google Drive query syntax, or an empty string for the zero Query.
+func (q Query) String() string {
+	return q.expr
+}
+
+// IsZero reports whether q is the zero Query, which matches all files.
+func (q Query) IsZero() bool {
+	return q.expr == ""
+}
+
+// NameEquals matches the files whose name is name.
+func NameEquals(name string) Query {
+	return term("name = %s", name)
+}
+
+// NameContains matches the files whose name contains a word starting with prefix.
+func NameContains(prefix string) Query {
+	return term("name contains %s", prefix)
+}
+
+// MimeTypeEquals matches the files whose MIME type is mimeType.
+func MimeTypeEquals(mimeType string) Query {
+	return term("mimeType = %s", mimeType)
+}
+
+// InParents matches the files whose parents include the directory with the given parentID.
+func InParents(parentID FileID) Query {
+	return term("%s in parents", string(parentID))
+}
+
+// ModifiedBefore matches the files last modified before t.
+func ModifiedBefore(t time.Time) Query {
+	return term("modifiedTime < %s", t.UTC().Format(time.RFC3339Nano))
+}
+
+// ModifiedAfter matches the files last modified after t.
+func ModifiedAfter(t time.Time) Query {
+	return term("modifiedTime > %s", t.UTC().Format(time.RFC3339Nano))
+}
+
+// OwnedBy matches the files owned by the user with the given email.
+func OwnedBy(email string) Query {
+	return term("%s in owners", email)
+}
+
+// WritableBy matches the files that the user or group with the given email can modify.
+func WritableBy(email string) Query {
+	return term("%s in writers", email)
+}
+
+// Starred matches the files that are starred if starred is true, or not starred otherwise.
+func Starred(starred bool) Query {
+	return Query{expr: fmt.Sprintf("starred = %t", starred)}
+}
+
+// Trashed matches the files that are in the trash if trashed is true, or not in the trash otherwise.
+func Trashed(trashed bool) Query {
+	return Query{expr: fmt.Sprintf("trashed = %t", trashed)}
+}
+
+// HasProperty matches the files that have the custom property key with the given value.
+func HasProperty(key, value string) Query {
+	return Query{expr: fmt.Sprintf("properties has { key = %s and value = %s }", quoteQuery(key), quoteQuery(value))}
+}
+
+// FullTextContains matches the files whose name, description or content contain text.
+func FullTextContains(text string) Query {
+	return term("fullText contains %s", text)
+}
+
+// And matches the files that match all of the given queries. Zero queries among them are ignored.
+func And(queries ...Query) Query {
+	return join("and", queries, false)
+}
+
+// Or matches the files that match any of the given queries.
+// It matches all files, as the zero Query, if one of them is the zero Query.
+func Or(queries ...Query) Query {
+	return join("or", queries, true)
+}
+
+// Not matches the files that do not match q.
+// As the zero Query matches all files, Not of the zero Query matches no files.
+// The query syntax has no term that matches nothing, so it is rendered as "trashed = true and trashed = false".
+func Not(q Query) Query {
+	if q.IsZero() {
+		return Query{expr: "trashed = true and trashed = false", compound: true}
+	}
+	return Query{expr: "not " + q.operand()}
+}
+
+// term returns a Query of format in which the value is quoted.
+func term(format, value string) Query {
+	return Query{expr: fmt.Sprintf(format, quoteQuery(value))}
+}
+
+// join combines queries with op. If zeroMatchesAll is true, a zero Query among queries makes the result zero,
+// otherwise zero queries are skipped.
+func join(op string, queries []Query, zeroMatchesAll bool) Query {
+	var operands []Query
+	for _, q := range queries {
+		if !q.IsZero() {
+			operands = append(operands, q)
+		} else if zeroMatchesAll {
+			return Query{}
+		}
+	}
+	switch len(operands) {
+	case 0:
+		return Query{}
+	case 1:
+		return operands[0]
+	}
+	exprs := make([]string, len(operands))
+	for i, q := range operands {
+		exprs[i] = q.operand()
+	}
+	return Query{expr: strings.Join(exprs, " "+op+" "), compound: true}
+}
+
+// operand returns q as an operand of and, or and not.
+func (q Query) operand() string {
+	if q.compound {
+		return "(" + q.expr + ")"
+	}
+	return q.expr
+}
+
+// quoteQuery returns s as a string literal of the Google Drive query syntax.
+func quoteQuery(s string) string {
+	return "'" + escapeQuery(s) + "'"
+}
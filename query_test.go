@@ -0,0 +1,96 @@
+package drivefs_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func TestQuery_String(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 12, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	tests := []struct {
+		name  string
+		query drivefs.Query
+		want  string
+	}{
+		{name: "zero", query: drivefs.Query{}, want: ""},
+		{name: "name", query: drivefs.NameEquals("report.txt"), want: "name = 'report.txt'"},
+		{name: "escaped", query: drivefs.NameEquals(`it's a\b`), want: `name = 'it\'s a\\b'`},
+		{name: "escaped quote", query: drivefs.NameContains(`\'`), want: `name contains '\\\''`},
+		{name: "mime type", query: drivefs.MimeTypeEquals("text/plain"), want: "mimeType = 'text/plain'"},
+		{name: "parents", query: drivefs.InParents("dir' or 'x"), want: `'dir\' or \'x' in parents`},
+		{name: "modified before", query: drivefs.ModifiedBefore(modified), want: "modifiedTime < '2024-01-02T03:00:00Z'"},
+		{name: "modified after", query: drivefs.ModifiedAfter(modified), want: "modifiedTime > '2024-01-02T03:00:00Z'"},
+		{name: "owners", query: drivefs.OwnedBy("alice@example.com"), want: "'alice@example.com' in owners"},
+		{name: "writers", query: drivefs.WritableBy("bob@example.com"), want: "'bob@example.com' in writers"},
+		{name: "starred", query: drivefs.Starred(true), want: "starred = true"},
+		{name: "trashed", query: drivefs.Trashed(false), want: "trashed = false"},
+		{name: "property", query: drivefs.HasProperty("k'", "v"), want: `properties has { key = 'k\'' and value = 'v' }`},
+		{name: "full text", query: drivefs.FullTextContains("hello"), want: "fullText contains 'hello'"},
+		{name: "and", query: drivefs.And(drivefs.NameEquals("a"), drivefs.Query{}, drivefs.Starred(true)), want: "name = 'a' and starred = true"},
+		{name: "single", query: drivefs.And(drivefs.Query{}, drivefs.NameEquals("a")), want: "name = 'a'"},
+		{name: "nested", query: drivefs.And(drivefs.Or(drivefs.NameEquals("a"), drivefs.NameEquals("b")), drivefs.Trashed(false)),
+			want: "(name = 'a' or name = 'b') and trashed = false"},
+		{name: "or with zero", query: drivefs.Or(drivefs.NameEquals("a"), drivefs.Query{}), want: ""},
+		{name: "not", query: drivefs.Not(drivefs.And(drivefs.Starred(true), drivefs.Trashed(true))), want: "not (starred = true and trashed = true)"},
+		{name: "not term", query: drivefs.Not(drivefs.InParents("root")), want: "not 'root' in parents"},
+		{name: "not zero", query: drivefs.Not(drivefs.Query{}), want: "trashed = true and trashed = false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.String(); got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriveFS_Query_Builder(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	dir, err := s.Mkdir(mem.RootID(), `it's\here`)
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	writeRemoteFile(t, s, dir.ID, "notes.txt", "hello world")
+	writeRemoteFile(t, s, dir.ID, `x' or name contains '`, "data")
+	writeRemoteFile(t, s, mem.RootID(), "other.txt", "hello")
+
+	if found := findRemote(t, s, mem.RootID(), `/it's\here/notes.txt`); found.Name != "notes.txt" {
+		t.Fatalf("FindByPath() = %+v, want notes.txt", found)
+	}
+
+	names := func(files []drivefs.FileInfo) []string {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	results, err := s.Query(drivefs.NameEquals(`x' or name contains '`).String())
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := names(results); !slices.Equal(got, []string{`x' or name contains '`}) {
+		t.Fatalf("Query() = %v, want only the file with the quoted name", got)
+	}
+	results, err = s.Query(drivefs.And(drivefs.FullTextContains("hello"), drivefs.Not(drivefs.InParents(dir.ID))).String())
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if got := names(results); !slices.Equal(got, []string{"other.txt"}) {
+		t.Fatalf("Query() = %v, want other.txt", got)
+	}
+
+	children, err := s.ReadDirWithOptions(dir.ID, drivefs.ListOptions{Query: drivefs.NameContains("notes")})
+	if err != nil {
+		t.Fatalf("ReadDirWithOptions() error = %v", err)
+	}
+	if got := names(children); !slices.Equal(got, []string{"notes.txt"}) {
+		t.Fatalf("ReadDirWithOptions() = %v, want notes.txt", got)
+	}
+}
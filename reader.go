@@ -0,0 +1,190 @@
+package drivefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// FileReader is a streaming handle to the contents of a file in Google Drive.
+// It implements io.ReadCloser, io.Seeker and io.ReaderAt by issuing HTTP Range requests on the media download,
+// so that the contents are never buffered entirely in memory.
+//
+// Read and Seek share the current offset and must not be called concurrently.
+// ReadAt does not use or change the current offset and may be called concurrently.
+type FileReader struct {
+	ctx     context.Context
+	backend Backend
+	info    FileInfo
+
+	offset int64
+	body   io.ReadCloser
+	closed bool
+}
+
+var (
+	_ io.ReadCloser = (*FileReader)(nil)
+	_ io.Seeker     = (*FileReader)(nil)
+	_ io.ReaderAt   = (*FileReader)(nil)
+)
+
+// Open opens the file with the given fileID for streaming reads.
+// The returned FileReader must be closed by the caller.
+// Returns ErrNotFound if the file does not exist.
+// Returns ErrNotReadable for directories and Google Apps files (Docs, Sheets, etc.) that cannot be directly downloaded.
+func (s *DriveFS) Open(fileID FileID) (r *FileReader, err error) {
+	return s.OpenContext(context.Background(), fileID)
+}
+
+// OpenContext is like Open but uses ctx for all Google Drive API calls, including those made by the returned FileReader.
+func (s *DriveFS) OpenContext(ctx context.Context, fileID FileID) (r *FileReader, err error) {
+	f, found, err := findByID(ctx, s.backend, string(fileID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("file '%s' not found: %w", fileID, ErrNotFound)
+	}
+	info, err := newFileInfo(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create FileInfo: %w", err)
+	}
+	return newFileReader(ctx, s.backend, info)
+}
+
+func newFileReader(ctx context.Context, backend Backend, info FileInfo) (*FileReader, error) {
+	if info.IsAppFile() {
+		return nil, fmt.Errorf("cannot download google-apps file: %w", ErrNotReadable)
+	}
+	return &FileReader{ctx: ctx, backend: backend, info: info}, nil
+}
+
+// Info returns the metadata of the file as of when it was opened.
+func (r *FileReader) Info() FileInfo {
+	return r.info
+}
+
+// Read reads up to len(p) bytes from the current offset.
+// The download is started lazily and continued across calls until Seek is called.
+func (r *FileReader) Read(p []byte) (n int, err error) {
+	if r.closed {
+		return 0, fmt.Errorf("failed to read closed file: %w", ErrIOError)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.offset >= r.info.Size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := downloadRange(r.ctx, r.backend, string(r.info.ID), r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+	n, err = r.body.Read(p)
+	r.offset += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, newIOError("failed to read file body", err)
+	}
+	if errors.Is(err, io.EOF) && r.offset < r.info.Size {
+		return n, newIOError("failed to read file body", io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+// Seek sets the offset for the next Read according to whence as described in io.Seeker.
+// Seeking does not issue any request; the download is restarted from the new offset on the next Read.
+func (r *FileReader) Seek(offset int64, whence int) (int64, error) {
+	if r.closed {
+		return 0, fmt.Errorf("failed to seek closed file: %w", ErrIOError)
+	}
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.offset + offset
+	case io.SeekEnd:
+		abs = r.info.Size + offset
+	default:
+		return 0, fmt.Errorf("invalid whence %d: %w", whence, ErrIOError)
+	}
+	if abs < 0 {
+		return 0, fmt.Errorf("negative offset %d: %w", abs, ErrIOError)
+	}
+	if abs != r.offset {
+		if err := r.closeBody(); err != nil {
+			return 0, err
+		}
+		r.offset = abs
+	}
+	return abs, nil
+}
+
+// ReadAt reads len(p) bytes starting at offset off with a single Range request.
+func (r *FileReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if r.closed {
+		return 0, fmt.Errorf("failed to read closed file: %w", ErrIOError)
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d: %w", off, ErrIOError)
+	}
+	if off >= r.info.Size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	end := min(off+int64(len(p)), r.info.Size)
+	body, err := downloadRange(r.ctx, r.backend, string(r.info.ID), off, end-off)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		// io.EOF must be returned as is, so the error is joined only if closing fails.
+		if closeErr := body.Close(); closeErr != nil {
+			err = errors.Join(err, newIOError("failed to close file body", closeErr))
+		}
+	}()
+	n, err = io.ReadFull(body, p[:end-off])
+	if err != nil {
+		return n, newIOError("failed to read file body", err)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close releases the underlying download, if any.
+func (r *FileReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.closeBody()
+}
+
+func (r *FileReader) closeBody() error {
+	if r.body == nil {
+		return nil
+	}
+	body := r.body
+	r.body = nil
+	if err := body.Close(); err != nil {
+		return newIOError("failed to close file body", err)
+	}
+	return nil
+}
+
+// downloadRange downloads length bytes from offset, or up to the end of the file if length is negative.
+func downloadRange(ctx context.Context, b Backend, fileID string, offset, length int64) (body io.ReadCloser, err error) {
+	body, err = b.DownloadFile(ctx, DownloadFileRequest{FileID: fileID, Offset: offset, Length: length})
+	if err != nil {
+		return nil, newDriveError("failed to download file", err)
+	}
+	return body, nil
+}
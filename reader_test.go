@@ -0,0 +1,186 @@
+package drivefs_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+func newRangeTestServer(t *testing.T, files map[string]*drive.File, contents map[string][]byte) *drivefs.DriveFS {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/files/")
+		f, ok := files[id]
+		if !ok {
+			http.Error(w, `{"error":{"code":404,"message":"not found"}}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			http.ServeContent(w, r, f.Name, time.Time{}, bytes.NewReader(contents[id]))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(f)
+	}))
+	t.Cleanup(srv.Close)
+
+	service, err := drive.NewService(context.Background(),
+		option.WithEndpoint(srv.URL+"/"),
+		option.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	return drivefs.New(service)
+}
+
+func TestFileReader(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	s := newRangeTestServer(t, map[string]*drive.File{
+		"file": {Id: "file", Name: "file.txt", MimeType: "text/plain", Size: int64(len(content))},
+		"doc":  {Id: "doc", Name: "doc", MimeType: "application/vnd.google-apps.document"},
+	}, map[string][]byte{"file": content})
+
+	t.Run("ReadAll", func(t *testing.T) {
+		r, err := s.Open("file")
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		defer r.Close()
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("ReadAll() = %q, want %q", got, content)
+		}
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		r, err := s.Open("file")
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		defer r.Close()
+		if _, err := r.Seek(-6, io.SeekEnd); err != nil {
+			t.Fatalf("Seek() error = %v", err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() error = %v", err)
+		}
+		if want := content[len(content)-6:]; !bytes.Equal(got, want) {
+			t.Fatalf("ReadAll() after Seek = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("ReadAt", func(t *testing.T) {
+		r, err := s.Open("file")
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		defer r.Close()
+		p := make([]byte, 5)
+		if n, err := r.ReadAt(p, 10); err != nil || n != 5 || string(p) != "abcde" {
+			t.Fatalf("ReadAt(10) = %d, %q, %v, want 5, %q, nil", n, p[:n], err, "abcde")
+		}
+		p = make([]byte, 10)
+		if n, err := r.ReadAt(p, int64(len(content)-3)); !errors.Is(err, io.EOF) || n != 3 || string(p[:n]) != "xyz" {
+			t.Fatalf("ReadAt(end-3) = %d, %q, %v, want 3, %q, io.EOF", n, p[:n], err, "xyz")
+		}
+	})
+
+	t.Run("AppFile", func(t *testing.T) {
+		if _, err := s.Open("doc"); !errors.Is(err, drivefs.ErrNotReadable) {
+			t.Fatalf("Open() error = %v, want ErrNotReadable", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, err := s.Open("missing"); !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("Open() error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestServiceBackend_DownloadFile(t *testing.T) {
+	content := []byte("0123456789")
+	var ignoreRange bool
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("alt") != "media" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(&drive.File{Id: "file"})
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		if ignoreRange {
+			_, _ = w.Write(content)
+			return
+		}
+		http.ServeContent(w, r, "file.txt", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	service, err := drive.NewService(context.Background(),
+		option.WithEndpoint(srv.URL+"/"),
+		option.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	b := drivefs.NewServiceBackend(service)
+
+	download := func(offset, length int64) (string, error) {
+		body, err := b.DownloadFile(context.Background(), drivefs.DownloadFileRequest{FileID: "file", Offset: offset, Length: length})
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+		got, err := io.ReadAll(body)
+		return string(got), err
+	}
+
+	cases := []struct {
+		offset, length int64
+		want           string
+		wantRange      string
+	}{
+		{offset: 0, length: -1, want: "0123456789", wantRange: ""},
+		{offset: 0, length: 3, want: "012", wantRange: "bytes=0-2"},
+		{offset: 7, length: -1, want: "789", wantRange: "bytes=7-"},
+	}
+	for _, c := range cases {
+		ranges = nil
+		got, err := download(c.offset, c.length)
+		if err != nil || got != c.want {
+			t.Fatalf("DownloadFile(%d, %d) = %q, %v, want %q", c.offset, c.length, got, err, c.want)
+		}
+		if len(ranges) != 1 || ranges[0] != c.wantRange {
+			t.Fatalf("DownloadFile(%d, %d) sent Range %q, want %q", c.offset, c.length, ranges, c.wantRange)
+		}
+	}
+
+	ranges = nil
+	if got, err := download(5, 0); err != nil || got != "" {
+		t.Fatalf("DownloadFile(5, 0) = %q, %v, want empty content", got, err)
+	}
+	if len(ranges) != 0 {
+		t.Fatalf("DownloadFile(5, 0) sent Range %q, want no download", ranges)
+	}
+
+	ignoreRange = true
+	if got, err := download(0, 3); err == nil {
+		t.Fatalf("DownloadFile(0, 3) = %q, want an error when the Range header is ignored", got)
+	}
+}
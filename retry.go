@@ -0,0 +1,498 @@
+package drivefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy configures how Google Drive API calls are retried after rate-limit and transient errors.
+// The zero value is a usable policy with the defaults described on each field.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first one.
+	// Zero means 5, and 1 disables retries.
+	MaxAttempts int
+
+	// InitialBackoff is the base delay before the first retry. Zero means 1 second.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the base delay between retries. Zero means 32 seconds.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor by which the base delay grows after each retry. Zero means 2.
+	Multiplier float64
+
+	// Retryable reports whether a failed call should be retried. Nil means IsRetryable.
+	Retryable func(err error) bool
+
+	// OnRetry, if not nil, is called before waiting for each retry.
+	OnRetry func(event RetryEvent)
+}
+
+// RetryEvent describes a retry of a Google Drive API call. It is passed to RetryPolicy.OnRetry.
+type RetryEvent struct {
+	// Operation is the name of the Google Drive API method, such as "files.list".
+	Operation string
+
+	// Attempt is the number of the attempt that has failed, starting from 1.
+	Attempt int
+
+	// Delay is the time to wait before the next attempt.
+	Delay time.Duration
+
+	// Err is the error of the failed attempt.
+	Err error
+}
+
+// IsRetryable reports whether err is a rate-limit or transient error after which a call may succeed if retried:
+// 429 Too Many Requests, 5xx server errors, 403 Forbidden with a rate-limit reason,
+// and network errors such as timeouts and connection resets.
+// Errors caused by a canceled or expired context are not retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		switch {
+		case gerr.Code == http.StatusTooManyRequests, gerr.Code >= 500:
+			return true
+		case gerr.Code == http.StatusForbidden:
+			for _, item := range gerr.Errors {
+				switch item.Reason {
+				case "userRateLimitExceeded", "rateLimitExceeded", "backendError":
+					return true
+				}
+			}
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// WithRetry returns a DriveFS that performs the same operations as s
+// but retries every Google Drive API call according to policy.
+// Files and copies are created with IDs generated in advance, so a retried creation never produces a duplicate.
+// The cache of s, if any, is shared with the returned DriveFS.
+func (s *DriveFS) WithRetry(policy RetryPolicy) *DriveFS {
+	return &DriveFS{backend: NewRetryBackend(s.backend, policy), cache: s.cache}
+}
+
+// NewRetryBackend returns a Backend that calls backend and retries failed calls according to policy.
+//
+// Calls are retried after waiting for an exponentially growing delay with random jitter,
+// or for the duration requested by the Retry-After header of the response if it is longer.
+// CreateFile and CopyFile requests without File.Id are given an ID from GenerateIDs before the first attempt,
+// and a retry that fails with 409 Conflict returns the file created by an earlier attempt.
+// Likewise, a retry of CreateDrive that fails with 409 Conflict because of the reused request ID
+// returns the shared drive created by an earlier attempt if it is the only one with the requested name.
+// Deletions that fail with 404 Not Found on a retry are regarded as done by an earlier attempt.
+// Requests with Media are retried only if Media implements io.Seeker, which is used to rewind it.
+func NewRetryBackend(backend Backend, policy RetryPolicy) Backend {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 32 * time.Second
+	}
+	if policy.Multiplier <= 0 {
+		policy.Multiplier = 2
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+	return &retryBackend{backend: backend, policy: policy}
+}
+
+type retryBackend struct {
+	backend Backend
+	policy  RetryPolicy
+}
+
+var _ Backend = (*retryBackend)(nil)
+
+// do calls f until it succeeds, fails with a non-retryable error, or the attempts are exhausted.
+// f is given the number of the attempt, starting from 1.
+func (b *retryBackend) do(ctx context.Context, op string, f func(attempt int) error) error {
+	backoff := b.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f(attempt)
+		if err == nil || attempt >= b.policy.MaxAttempts || !b.policy.Retryable(err) {
+			return err
+		}
+
+		// Equal jitter: half of the delay is fixed and the other half is random.
+		delay := backoff/2 + rand.N(backoff/2+1)
+		if retryAfter := retryAfter(err); retryAfter > delay {
+			delay = retryAfter
+		}
+		backoff = min(time.Duration(float64(backoff)*b.policy.Multiplier), b.policy.MaxBackoff)
+
+		if b.policy.OnRetry != nil {
+			b.policy.OnRetry(RetryEvent{Operation: op, Attempt: attempt, Delay: delay, Err: err})
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the response that caused err, if any.
+func retryAfter(err error) time.Duration {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) || gerr.Header == nil {
+		return 0
+	}
+	v := gerr.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+func isStatus(err error, code int) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == code
+}
+
+// doMedia is like do but for a request that uploads media.
+// The media is rewound to its offset at the start of the request before each retry,
+// or the request is not retried if it cannot be rewound.
+func (b *retryBackend) doMedia(ctx context.Context, op string, media io.Reader, f func(attempt int) error) error {
+	if media == nil {
+		return b.do(ctx, op, f)
+	}
+	seeker, ok := media.(io.Seeker)
+	if !ok {
+		return f(1)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return f(1)
+	}
+	return b.do(ctx, op, func(attempt int) error {
+		if attempt > 1 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		return f(attempt)
+	})
+}
+
+// generateID returns a new ID for a file to be created, unless file already has one.
+func (b *retryBackend) generateID(ctx context.Context, file *drive.File) (*drive.File, error) {
+	if b.policy.MaxAttempts <= 1 || (file != nil && file.Id != "") {
+		return file, nil
+	}
+	var ids []string
+	err := b.do(ctx, "files.generateIds", func(int) (err error) {
+		ids, err = b.backend.GenerateIDs(ctx, 1)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	withID := &drive.File{}
+	if file != nil {
+		copied := *file
+		withID = &copied
+	}
+	withID.Id = ids[0]
+	return withID, nil
+}
+
+func (b *retryBackend) GetFile(ctx context.Context, fileID string) (file *drive.File, err error) {
+	err = b.do(ctx, "files.get", func(int) (err error) {
+		file, err = b.backend.GetFile(ctx, fileID)
+		return err
+	})
+	return file, err
+}
+
+func (b *retryBackend) ListFiles(ctx context.Context, req ListFilesRequest) (list *drive.FileList, err error) {
+	err = b.do(ctx, "files.list", func(int) (err error) {
+		list, err = b.backend.ListFiles(ctx, req)
+		return err
+	})
+	return list, err
+}
+
+func (b *retryBackend) GenerateIDs(ctx context.Context, count int) (ids []string, err error) {
+	err = b.do(ctx, "files.generateIds", func(int) (err error) {
+		ids, err = b.backend.GenerateIDs(ctx, count)
+		return err
+	})
+	return ids, err
+}
+
+func (b *retryBackend) CreateFile(ctx context.Context, req CreateFileRequest) (file *drive.File, err error) {
+	req.File, err = b.generateID(ctx, req.File)
+	if err != nil {
+		return nil, err
+	}
+	err = b.doMedia(ctx, "files.create", req.Media, func(attempt int) (err error) {
+		file, err = b.backend.CreateFile(ctx, req)
+		if attempt > 1 && req.File != nil && req.File.Id != "" && isStatus(err, http.StatusConflict) {
+			// An earlier attempt has created the file although it failed.
+			file, err = b.backend.GetFile(ctx, req.File.Id)
+		}
+		return err
+	})
+	return file, err
+}
+
+func (b *retryBackend) UpdateFile(ctx context.Context, req UpdateFileRequest) (file *drive.File, err error) {
+	err = b.doMedia(ctx, "files.update", req.Media, func(int) (err error) {
+		file, err = b.backend.UpdateFile(ctx, req)
+		return err
+	})
+	return file, err
+}
+
+func (b *retryBackend) CopyFile(ctx context.Context, req CopyFileRequest) (file *drive.File, err error) {
+	req.File, err = b.generateID(ctx, req.File)
+	if err != nil {
+		return nil, err
+	}
+	err = b.do(ctx, "files.copy", func(attempt int) (err error) {
+		file, err = b.backend.CopyFile(ctx, req)
+		if attempt > 1 && req.File != nil && req.File.Id != "" && isStatus(err, http.StatusConflict) {
+			// An earlier attempt has created the copy although it failed.
+			file, err = b.backend.GetFile(ctx, req.File.Id)
+		}
+		return err
+	})
+	return file, err
+}
+
+func (b *retryBackend) DeleteFile(ctx context.Context, fileID string) error {
+	return b.do(ctx, "files.delete", func(attempt int) error {
+		err := b.backend.DeleteFile(ctx, fileID)
+		if attempt > 1 && isStatus(err, http.StatusNotFound) {
+			// An earlier attempt has deleted the file although it failed.
+			return nil
+		}
+		return err
+	})
+}
+
+func (b *retryBackend) EmptyTrash(ctx context.Context, driveID string) error {
+	// Emptying the trash again after an earlier attempt has succeeded has no effect.
+	return b.do(ctx, "files.emptyTrash", func(int) error {
+		return b.backend.EmptyTrash(ctx, driveID)
+	})
+}
+
+func (b *retryBackend) DownloadFile(ctx context.Context, req DownloadFileRequest) (body io.ReadCloser, err error) {
+	err = b.do(ctx, "files.get", func(int) (err error) {
+		body, err = b.backend.DownloadFile(ctx, req)
+		return err
+	})
+	return body, err
+}
+
+func (b *retryBackend) ExportFile(ctx context.Context, fileID, mimeType string) (body io.ReadCloser, err error) {
+	err = b.do(ctx, "files.export", func(int) (err error) {
+		body, err = b.backend.ExportFile(ctx, fileID, mimeType)
+		return err
+	})
+	return body, err
+}
+
+func (b *retryBackend) ListPermissions(ctx context.Context, fileID string, pageToken string) (list *drive.PermissionList, err error) {
+	err = b.do(ctx, "permissions.list", func(int) (err error) {
+		list, err = b.backend.ListPermissions(ctx, fileID, pageToken)
+		return err
+	})
+	return list, err
+}
+
+func (b *retryBackend) CreatePermission(ctx context.Context, req CreatePermissionRequest) (perm *drive.Permission, err error) {
+	// Creating a permission for a grantee that already has one updates it, so retries do not duplicate permissions.
+	err = b.do(ctx, "permissions.create", func(int) (err error) {
+		perm, err = b.backend.CreatePermission(ctx, req)
+		return err
+	})
+	return perm, err
+}
+
+func (b *retryBackend) UpdatePermission(ctx context.Context, req UpdatePermissionRequest) (perm *drive.Permission, err error) {
+	err = b.do(ctx, "permissions.update", func(int) (err error) {
+		perm, err = b.backend.UpdatePermission(ctx, req)
+		return err
+	})
+	return perm, err
+}
+
+func (b *retryBackend) DeletePermission(ctx context.Context, fileID, permissionID string) error {
+	return b.do(ctx, "permissions.delete", func(attempt int) error {
+		err := b.backend.DeletePermission(ctx, fileID, permissionID)
+		if attempt > 1 && isStatus(err, http.StatusNotFound) {
+			// An earlier attempt has deleted the permission although it failed.
+			return nil
+		}
+		return err
+	})
+}
+
+func (b *retryBackend) ListRevisions(ctx context.Context, fileID string, pageToken string) (list *drive.RevisionList, err error) {
+	err = b.do(ctx, "revisions.list", func(int) (err error) {
+		list, err = b.backend.ListRevisions(ctx, fileID, pageToken)
+		return err
+	})
+	return list, err
+}
+
+func (b *retryBackend) DownloadRevision(ctx context.Context, fileID, revisionID string) (body io.ReadCloser, err error) {
+	err = b.do(ctx, "revisions.get", func(int) (err error) {
+		body, err = b.backend.DownloadRevision(ctx, fileID, revisionID)
+		return err
+	})
+	return body, err
+}
+
+func (b *retryBackend) UpdateRevision(ctx context.Context, req UpdateRevisionRequest) (rev *drive.Revision, err error) {
+	err = b.do(ctx, "revisions.update", func(int) (err error) {
+		rev, err = b.backend.UpdateRevision(ctx, req)
+		return err
+	})
+	return rev, err
+}
+
+func (b *retryBackend) DeleteRevision(ctx context.Context, fileID, revisionID string) error {
+	return b.do(ctx, "revisions.delete", func(attempt int) error {
+		err := b.backend.DeleteRevision(ctx, fileID, revisionID)
+		if attempt > 1 && isStatus(err, http.StatusNotFound) {
+			// An earlier attempt has deleted the revision although it failed.
+			return nil
+		}
+		return err
+	})
+}
+
+func (b *retryBackend) GetStartPageToken(ctx context.Context, driveID string) (token string, err error) {
+	err = b.do(ctx, "changes.getStartPageToken", func(int) (err error) {
+		token, err = b.backend.GetStartPageToken(ctx, driveID)
+		return err
+	})
+	return token, err
+}
+
+func (b *retryBackend) ListChanges(ctx context.Context, req ListChangesRequest) (list *drive.ChangeList, err error) {
+	err = b.do(ctx, "changes.list", func(int) (err error) {
+		list, err = b.backend.ListChanges(ctx, req)
+		return err
+	})
+	return list, err
+}
+
+func (b *retryBackend) ListDrives(ctx context.Context, req ListDrivesRequest) (list *drive.DriveList, err error) {
+	err = b.do(ctx, "drives.list", func(int) (err error) {
+		list, err = b.backend.ListDrives(ctx, req)
+		return err
+	})
+	return list, err
+}
+
+func (b *retryBackend) GetDrive(ctx context.Context, driveID string) (drv *drive.Drive, err error) {
+	err = b.do(ctx, "drives.get", func(int) (err error) {
+		drv, err = b.backend.GetDrive(ctx, driveID)
+		return err
+	})
+	return drv, err
+}
+
+func (b *retryBackend) CreateDrive(ctx context.Context, req CreateDriveRequest) (drv *drive.Drive, err error) {
+	err = b.do(ctx, "drives.create", func(attempt int) (err error) {
+		drv, err = b.backend.CreateDrive(ctx, req)
+		if attempt > 1 && req.Drive != nil && isStatus(err, http.StatusConflict) {
+			// An earlier attempt has created the shared drive although it failed.
+			drv, err = b.findDrive(ctx, req.Drive.Name, err)
+		}
+		return err
+	})
+	return drv, err
+}
+
+// findDrive returns the only shared drive with the given name, or conflict if there is none or more than one.
+func (b *retryBackend) findDrive(ctx context.Context, name string, conflict error) (*drive.Drive, error) {
+	var found []*drive.Drive
+	var pageToken string
+	for {
+		list, err := b.ListDrives(ctx, ListDrivesRequest{PageToken: pageToken})
+		if err != nil {
+			return nil, err
+		}
+		for _, drv := range list.Drives {
+			if drv.Name == name {
+				found = append(found, drv)
+			}
+		}
+		if list.NextPageToken == "" {
+			break
+		}
+		pageToken = list.NextPageToken
+	}
+	if len(found) != 1 {
+		return nil, conflict
+	}
+	return found[0], nil
+}
+
+func (b *retryBackend) UpdateDrive(ctx context.Context, req UpdateDriveRequest) (drv *drive.Drive, err error) {
+	err = b.do(ctx, "drives.update", func(int) (err error) {
+		drv, err = b.backend.UpdateDrive(ctx, req)
+		return err
+	})
+	return drv, err
+}
+
+func (b *retryBackend) HideDrive(ctx context.Context, driveID string) (drv *drive.Drive, err error) {
+	err = b.do(ctx, "drives.hide", func(int) (err error) {
+		drv, err = b.backend.HideDrive(ctx, driveID)
+		return err
+	})
+	return drv, err
+}
+
+func (b *retryBackend) UnhideDrive(ctx context.Context, driveID string) (drv *drive.Drive, err error) {
+	err = b.do(ctx, "drives.unhide", func(int) (err error) {
+		drv, err = b.backend.UnhideDrive(ctx, driveID)
+		return err
+	})
+	return drv, err
+}
+
+func (b *retryBackend) DeleteDrive(ctx context.Context, driveID string) error {
+	return b.do(ctx, "drives.delete", func(attempt int) error {
+		err := b.backend.DeleteDrive(ctx, driveID)
+		if attempt > 1 && isStatus(err, http.StatusNotFound) {
+			// An earlier attempt has deleted the shared drive although it failed.
+			return nil
+		}
+		return err
+	})
+}
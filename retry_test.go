@@ -0,0 +1,254 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"github.com/Jumpaku/go-drivefs/drivefstest"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"429", &googleapi.Error{Code: http.StatusTooManyRequests}, true},
+		{"500", &googleapi.Error{Code: http.StatusInternalServerError}, true},
+		{"503", &googleapi.Error{Code: http.StatusServiceUnavailable}, true},
+		{"403_userRateLimitExceeded", &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}}, true},
+		{"403_rateLimitExceeded", &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, true},
+		{"403_forbidden", &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, false},
+		{"404", &googleapi.Error{Code: http.StatusNotFound}, false},
+		{"wrapped_429", fmt.Errorf("wrapped: %w", &googleapi.Error{Code: http.StatusTooManyRequests}), true},
+		{"unexpected_EOF", io.ErrUnexpectedEOF, true},
+		{"canceled", context.Canceled, false},
+		{"other", errors.New("other"), false},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			if got := drivefs.IsRetryable(c.err); got != c.want {
+				t.Fatalf("IsRetryable(%v) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+}
+
+func newRetryTestServer(t *testing.T) (*drivefstest.Server, *drivefs.DriveFS) {
+	t.Helper()
+	srv := drivefstest.NewServer(drivefstest.Options{})
+	t.Cleanup(srv.Close)
+	service, err := srv.NewService(context.Background())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return srv, drivefs.New(service)
+}
+
+func TestDriveFS_WithRetry(t *testing.T) {
+	t.Run("transient errors", func(t *testing.T) {
+		srv, s := newRetryTestServer(t)
+		srv.InjectFault(drivefstest.Fault{Method: http.MethodGet, Path: "/drive/v3/files", Code: http.StatusTooManyRequests})
+		srv.InjectFault(drivefstest.Fault{Method: http.MethodGet, Path: "/drive/v3/files", Code: http.StatusServiceUnavailable})
+		srv.InjectFault(drivefstest.Fault{Method: http.MethodGet, Path: "/drive/v3/files", Code: http.StatusForbidden, Reason: "userRateLimitExceeded"})
+
+		var events []drivefs.RetryEvent
+		s = s.WithRetry(drivefs.RetryPolicy{
+			InitialBackoff: time.Millisecond,
+			OnRetry:        func(e drivefs.RetryEvent) { events = append(events, e) },
+		})
+		if _, err := s.MkdirAll(srv.RootID(), "/a/b"); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("OnRetry called %d times, want 3", len(events))
+		}
+		for i, e := range events {
+			if e.Operation != "files.get" && e.Operation != "files.list" {
+				t.Fatalf("events[%d].Operation = %q, want files.get or files.list", i, e.Operation)
+			}
+			if !drivefs.IsRetryable(e.Err) {
+				t.Fatalf("events[%d].Err = %v, want a retryable error", i, e.Err)
+			}
+		}
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		srv, s := newRetryTestServer(t)
+		srv.InjectFault(drivefstest.Fault{Method: http.MethodGet, Code: http.StatusInternalServerError, Times: 10})
+		s = s.WithRetry(drivefs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+		_, err := s.Info(srv.RootID())
+		var gerr *googleapi.Error
+		if !errors.As(err, &gerr) || gerr.Code != http.StatusInternalServerError {
+			t.Fatalf("Info() error = %v, want 500", err)
+		}
+		if n := len(srv.Requests()); n != 3 {
+			t.Fatalf("server received %d requests, want 3", n)
+		}
+	})
+
+	t.Run("non-retryable", func(t *testing.T) {
+		srv, s := newRetryTestServer(t)
+		s = s.WithRetry(drivefs.RetryPolicy{InitialBackoff: time.Millisecond})
+
+		if _, err := s.Info("missing"); !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("Info() error = %v, want ErrNotFound", err)
+		}
+		if n := len(srv.Requests()); n != 1 {
+			t.Fatalf("server received %d requests, want 1", n)
+		}
+	})
+
+	t.Run("Retry-After", func(t *testing.T) {
+		srv, s := newRetryTestServer(t)
+		srv.InjectFault(drivefstest.Fault{Method: http.MethodGet, Code: http.StatusTooManyRequests, RetryAfter: 30 * time.Second})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var delay time.Duration
+		s = s.WithRetry(drivefs.RetryPolicy{
+			InitialBackoff: time.Millisecond,
+			OnRetry: func(e drivefs.RetryEvent) {
+				delay = e.Delay
+				cancel()
+			},
+		})
+		if _, err := s.InfoContext(ctx, srv.RootID()); !errors.Is(err, context.Canceled) {
+			t.Fatalf("InfoContext() error = %v, want context.Canceled", err)
+		}
+		if delay < 30*time.Second {
+			t.Fatalf("retry delay = %v, want at least the Retry-After of 30s", delay)
+		}
+	})
+}
+
+// lostResponseBackend performs the first CreateFile, CopyFile and CreateDrive calls but reports them as failed,
+// as if their responses were lost.
+type lostResponseBackend struct {
+	drivefs.Backend
+	mu   sync.Mutex
+	lost map[string]bool
+}
+
+func (b *lostResponseBackend) lose(op string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.lost[op] {
+		return false
+	}
+	b.lost[op] = true
+	return true
+}
+
+func (b *lostResponseBackend) CreateFile(ctx context.Context, req drivefs.CreateFileRequest) (*drive.File, error) {
+	f, err := b.Backend.CreateFile(ctx, req)
+	if err == nil && b.lose("create") {
+		return nil, &googleapi.Error{Code: http.StatusServiceUnavailable}
+	}
+	return f, err
+}
+
+func (b *lostResponseBackend) CopyFile(ctx context.Context, req drivefs.CopyFileRequest) (*drive.File, error) {
+	f, err := b.Backend.CopyFile(ctx, req)
+	if err == nil && b.lose("copy") {
+		return nil, &googleapi.Error{Code: http.StatusServiceUnavailable}
+	}
+	return f, err
+}
+
+func (b *lostResponseBackend) CreateDrive(ctx context.Context, req drivefs.CreateDriveRequest) (*drive.Drive, error) {
+	d, err := b.Backend.CreateDrive(ctx, req)
+	if err == nil && b.lose("createDrive") {
+		return nil, &googleapi.Error{Code: http.StatusServiceUnavailable}
+	}
+	return d, err
+}
+
+func TestDriveFS_WithRetry_NoDuplicates(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(&lostResponseBackend{Backend: mem, lost: map[string]bool{}}).
+		WithRetry(drivefs.RetryPolicy{InitialBackoff: time.Millisecond})
+
+	created, err := s.Create(mem.RootID(), "file.txt")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	copied, err := s.Copy(created.ID, mem.RootID(), "copy.txt")
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if copied.ID == created.ID {
+		t.Fatalf("Copy() returned the source file")
+	}
+
+	children, err := s.ReadDir(mem.RootID())
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(children) != 2 {
+		t.Fatalf("ReadDir() returned %d files, want 2: %v", len(children), children)
+	}
+
+	team, err := s.CreateSharedDrive("Team", "")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	drives, err := s.ListSharedDrives(drivefs.SharedDriveListOptions{})
+	if err != nil {
+		t.Fatalf("ListSharedDrives() error = %v", err)
+	}
+	if len(drives) != 1 || drives[0].ID != team.ID {
+		t.Fatalf("ListSharedDrives() = %+v, want only %+v", drives, team)
+	}
+}
+
+// interruptedUploadBackend consumes the media of the first UpdateFile call and fails it, as if the upload was interrupted.
+type interruptedUploadBackend struct {
+	drivefs.Backend
+	interrupted bool
+}
+
+func (b *interruptedUploadBackend) UpdateFile(ctx context.Context, req drivefs.UpdateFileRequest) (*drive.File, error) {
+	if req.Media != nil && !b.interrupted {
+		b.interrupted = true
+		_, _ = io.Copy(io.Discard, req.Media)
+		return nil, &googleapi.Error{Code: http.StatusServiceUnavailable}
+	}
+	return b.Backend.UpdateFile(ctx, req)
+}
+
+func TestDriveFS_WithRetry_MediaOffset(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	b := &interruptedUploadBackend{Backend: mem}
+	s := drivefs.NewWithBackend(b).WithRetry(drivefs.RetryPolicy{InitialBackoff: time.Millisecond})
+	file := writeRemoteFile(t, drivefs.NewWithBackend(mem), mem.RootID(), "file.txt", "old")
+
+	r := strings.NewReader("header:body")
+	if _, err := io.ReadFull(r, make([]byte, len("header:"))); err != nil {
+		t.Fatalf("ReadFull() error = %v", err)
+	}
+	if err := s.WriteFrom(file.ID, r, drivefs.WriteOptions{}); err != nil {
+		t.Fatalf("WriteFrom() error = %v", err)
+	}
+	if !b.interrupted {
+		t.Fatalf("WriteFrom() was not retried")
+	}
+	if data, err := s.ReadFile(file.ID); err != nil || string(data) != "body" {
+		t.Fatalf("ReadFile() = %q, %v, want %q", data, err, "body")
+	}
+}
@@ -0,0 +1,211 @@
+package drivefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// RevisionID represents a unique identifier for a revision of a file.
+type RevisionID string
+
+// Revision contains metadata about a revision of a file in Google Drive.
+type Revision struct {
+	// ID is the unique identifier for this revision within the file.
+	ID RevisionID
+
+	// Mime is the MIME type of the revision.
+	Mime string
+
+	// ModTime is the time at which the revision was made.
+	ModTime time.Time
+
+	// Size is the size of the content of the revision in bytes.
+	Size int64
+
+	// MD5Checksum is the hex-encoded MD5 checksum of the content of the revision.
+	MD5Checksum string
+
+	// LastModifyingUserName is the display name of the user who made the revision.
+	LastModifyingUserName string
+
+	// LastModifyingUserEmail is the email address of the user who made the revision.
+	LastModifyingUserEmail string
+
+	// KeepForever is true if the revision is pinned, so that it is not purged automatically.
+	KeepForever bool
+}
+
+// Revisions lists the revisions of the file with the given fileID, from the oldest to the latest.
+// The latest revision is the current content of the file.
+// Google Drive purges old revisions that are not pinned by KeepRevision automatically.
+// Returns an error wrapping ErrNotFound if the file does not exist.
+func (s *DriveFS) Revisions(fileID FileID) (revisions []Revision, err error) {
+	return s.RevisionsContext(context.Background(), fileID)
+}
+
+// RevisionsContext is like Revisions but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RevisionsContext(ctx context.Context, fileID FileID) (revisions []Revision, err error) {
+	var pageToken string
+	for {
+		list, err := s.backend.ListRevisions(ctx, string(fileID), pageToken)
+		if err != nil {
+			return nil, resourceError(fmt.Sprintf("file '%s'", fileID), "failed to list revisions", err)
+		}
+		for _, rev := range list.Revisions {
+			revisions = append(revisions, newRevision(rev))
+		}
+		if list.NextPageToken == "" {
+			return revisions, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+// ReadRevision reads the content of the revision with the given revisionID of the file with the given fileID.
+// Returns an error wrapping ErrNotReadable for Google Apps files,
+// and an error wrapping ErrNotFound if the file or the revision does not exist.
+func (s *DriveFS) ReadRevision(fileID FileID, revisionID RevisionID) (data []byte, err error) {
+	return s.ReadRevisionContext(context.Background(), fileID, revisionID)
+}
+
+// ReadRevisionContext is like ReadRevision but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ReadRevisionContext(ctx context.Context, fileID FileID, revisionID RevisionID) (data []byte, err error) {
+	if _, err := getRevisionFile(ctx, s.backend, fileID); err != nil {
+		return nil, err
+	}
+	body, err := s.backend.DownloadRevision(ctx, string(fileID), string(revisionID))
+	if err != nil {
+		return nil, resourceError(fmt.Sprintf("revision '%s' of file '%s'", revisionID, fileID), "failed to download revision", err)
+	}
+	defer func() {
+		closeErr := body.Close()
+		if closeErr != nil {
+			closeErr = newIOError("failed to close revision body", closeErr)
+		}
+		err = errors.Join(err, closeErr)
+	}()
+
+	data, err = io.ReadAll(body)
+	if err != nil {
+		return nil, newIOError("failed to read revision body", err)
+	}
+	return data, nil
+}
+
+// KeepRevision pins the revision with the given revisionID of the file with the given fileID
+// so that it is kept forever if keepForever is true, or unpins it otherwise.
+// Returns the Revision after the update.
+// Returns an error wrapping ErrNotFound if the file or the revision does not exist.
+func (s *DriveFS) KeepRevision(fileID FileID, revisionID RevisionID, keepForever bool) (revision Revision, err error) {
+	return s.KeepRevisionContext(context.Background(), fileID, revisionID, keepForever)
+}
+
+// KeepRevisionContext is like KeepRevision but uses ctx for all Google Drive API calls.
+func (s *DriveFS) KeepRevisionContext(ctx context.Context, fileID FileID, revisionID RevisionID, keepForever bool) (revision Revision, err error) {
+	rev, err := s.backend.UpdateRevision(ctx, UpdateRevisionRequest{
+		FileID: string(fileID),
+		Revision: &drive.Revision{
+			Id:              string(revisionID),
+			KeepForever:     keepForever,
+			ForceSendFields: []string{"KeepForever"},
+		},
+	})
+	if err != nil {
+		return Revision{}, resourceError(fmt.Sprintf("revision '%s' of file '%s'", revisionID, fileID), "failed to update revision", err)
+	}
+	return newRevision(rev), nil
+}
+
+// DeleteRevision permanently deletes the revision with the given revisionID of the file with the given fileID.
+// The latest revision, which is the current content of the file, cannot be deleted.
+// Returns an error wrapping ErrNotFound if the file or the revision does not exist.
+func (s *DriveFS) DeleteRevision(fileID FileID, revisionID RevisionID) (err error) {
+	return s.DeleteRevisionContext(context.Background(), fileID, revisionID)
+}
+
+// DeleteRevisionContext is like DeleteRevision but uses ctx for all Google Drive API calls.
+func (s *DriveFS) DeleteRevisionContext(ctx context.Context, fileID FileID, revisionID RevisionID) (err error) {
+	err = s.backend.DeleteRevision(ctx, string(fileID), string(revisionID))
+	if err != nil {
+		return resourceError(fmt.Sprintf("revision '%s' of file '%s'", revisionID, fileID), "failed to delete revision", err)
+	}
+	return nil
+}
+
+// RestoreRevision makes the content of the revision with the given revisionID the current content
+// of the file with the given fileID by uploading it again, which adds a new revision.
+// Returns the FileInfo of the file after the restoration.
+// Returns an error wrapping ErrNotReadable for Google Apps files,
+// and an error wrapping ErrNotFound if the file or the revision does not exist.
+func (s *DriveFS) RestoreRevision(fileID FileID, revisionID RevisionID) (info FileInfo, err error) {
+	return s.RestoreRevisionContext(context.Background(), fileID, revisionID)
+}
+
+// RestoreRevisionContext is like RestoreRevision but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RestoreRevisionContext(ctx context.Context, fileID FileID, revisionID RevisionID) (info FileInfo, err error) {
+	file, err := getRevisionFile(ctx, s.backend, fileID)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	body, err := s.backend.DownloadRevision(ctx, string(fileID), string(revisionID))
+	if err != nil {
+		return FileInfo{}, resourceError(fmt.Sprintf("revision '%s' of file '%s'", revisionID, fileID), "failed to download revision", err)
+	}
+	defer func() {
+		closeErr := body.Close()
+		if closeErr != nil {
+			closeErr = newIOError("failed to close revision body", closeErr)
+		}
+		err = errors.Join(err, closeErr)
+	}()
+
+	file, err = s.backend.UpdateFile(ctx, UpdateFileRequest{
+		FileID:       string(fileID),
+		File:         &drive.File{},
+		Media:        body,
+		MediaOptions: []googleapi.MediaOption{googleapi.ContentType(file.MimeType)},
+	})
+	if err != nil {
+		return FileInfo{}, newDriveError("failed to upload revision", err)
+	}
+	return newFileInfo(file)
+}
+
+// getRevisionFile returns the file with the given fileID, whose revisions must have binary content.
+func getRevisionFile(ctx context.Context, b Backend, fileID FileID) (*drive.File, error) {
+	file, found, err := findByID(ctx, b, string(fileID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("file '%s' not found: %w", fileID, ErrNotFound)
+	}
+	if strings.HasPrefix(file.MimeType, mimeTypePrefixGoogleApp) {
+		return nil, fmt.Errorf("cannot download revisions of google-apps file: %w", ErrNotReadable)
+	}
+	return file, nil
+}
+
+func newRevision(r *drive.Revision) Revision {
+	modTime, _ := time.Parse(time.RFC3339, r.ModifiedTime)
+	rev := Revision{
+		ID:          RevisionID(r.Id),
+		Mime:        r.MimeType,
+		ModTime:     modTime,
+		Size:        r.Size,
+		MD5Checksum: r.Md5Checksum,
+		KeepForever: r.KeepForever,
+	}
+	if r.LastModifyingUser != nil {
+		rev.LastModifyingUserName = r.LastModifyingUser.DisplayName
+		rev.LastModifyingUserEmail = r.LastModifyingUser.EmailAddress
+	}
+	return rev
+}
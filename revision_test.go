@@ -0,0 +1,108 @@
+package drivefs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func TestDriveFS_Revisions(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{UserEmail: "alice@example.com"})
+	s := drivefs.NewWithBackend(mem)
+	file := writeRemoteFile(t, s, mem.RootID(), "config.yaml", "v1")
+	for _, content := range []string{"v2", "v3"} {
+		if err := s.WriteFile(file.ID, []byte(content)); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	revisions, err := s.Revisions(file.ID)
+	if err != nil {
+		t.Fatalf("Revisions() error = %v", err)
+	}
+	// The first revision is the empty content of the created file.
+	if len(revisions) != 4 {
+		t.Fatalf("Revisions() = %+v, want 4 revisions", revisions)
+	}
+	v1, latest := revisions[1], revisions[3]
+	if v1.Size != 2 || v1.MD5Checksum == "" || v1.ModTime.IsZero() || v1.LastModifyingUserEmail != "alice@example.com" || v1.KeepForever {
+		t.Fatalf("revision of v1 = %+v, want the metadata of v1", v1)
+	}
+	if info, err := s.Info(file.ID); err != nil || latest.MD5Checksum != info.MD5Checksum {
+		t.Fatalf("latest revision = %+v, want the current content %+v (error %v)", latest, info, err)
+	}
+	if data, err := s.ReadRevision(file.ID, v1.ID); err != nil || string(data) != "v1" {
+		t.Fatalf("ReadRevision() = %q, %v, want %q", data, err, "v1")
+	}
+
+	if kept, err := s.KeepRevision(file.ID, v1.ID, true); err != nil || !kept.KeepForever {
+		t.Fatalf("KeepRevision(true) = %+v, %v, want a pinned revision", kept, err)
+	}
+	if kept, err := s.KeepRevision(file.ID, v1.ID, false); err != nil || kept.KeepForever {
+		t.Fatalf("KeepRevision(false) = %+v, %v, want an unpinned revision", kept, err)
+	}
+
+	info, err := s.RestoreRevision(file.ID, v1.ID)
+	if err != nil {
+		t.Fatalf("RestoreRevision() error = %v", err)
+	}
+	if info.MD5Checksum != v1.MD5Checksum {
+		t.Fatalf("RestoreRevision() = %+v, want the checksum of v1", info)
+	}
+	if data, err := s.ReadFile(file.ID); err != nil || string(data) != "v1" {
+		t.Fatalf("ReadFile() after restoring = %q, %v, want %q", data, err, "v1")
+	}
+
+	if err := s.DeleteRevision(file.ID, revisions[2].ID); err != nil {
+		t.Fatalf("DeleteRevision() error = %v", err)
+	}
+	if revisions, err := s.Revisions(file.ID); err != nil || len(revisions) != 4 {
+		t.Fatalf("Revisions() after deleting = %+v, %v, want 4 revisions", revisions, err)
+	}
+	if _, err := s.ReadRevision(file.ID, revisions[2].ID); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("ReadRevision() of a deleted revision error = %v, want ErrNotFound", err)
+	}
+	revisions, err = s.Revisions(file.ID)
+	if err != nil {
+		t.Fatalf("Revisions() error = %v", err)
+	}
+	if err := s.DeleteRevision(file.ID, revisions[len(revisions)-1].ID); !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("DeleteRevision() of the latest revision error = %v, want ErrDriveError", err)
+	}
+}
+
+func TestDriveFS_Revisions_Errors(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	dir, err := s.Mkdir(mem.RootID(), "dir")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Revisions of missing file", second(s.Revisions("missing")), drivefs.ErrNotFound},
+		{"ReadRevision of missing file", second(s.ReadRevision("missing", "1")), drivefs.ErrNotFound},
+		{"ReadRevision of missing revision", second(s.ReadRevision(writeRemoteFile(t, s, mem.RootID(), "a.txt", "a").ID, "missing")), drivefs.ErrNotFound},
+		{"ReadRevision of folder", second(s.ReadRevision(dir.ID, "1")), drivefs.ErrNotReadable},
+		{"KeepRevision of missing revision", second(s.KeepRevision(dir.ID, "missing", true)), drivefs.ErrNotFound},
+		{"DeleteRevision of missing revision", s.DeleteRevision(dir.ID, "missing"), drivefs.ErrNotFound},
+		{"RestoreRevision of folder", second(s.RestoreRevision(dir.ID, "1")), drivefs.ErrNotReadable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !errors.Is(c.err, c.want) {
+				t.Fatalf("error = %v, want %v", c.err, c.want)
+			}
+		})
+	}
+}
+
+func second[T any](_ T, err error) error {
+	return err
+}
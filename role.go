@@ -1,5 +1,7 @@
 package drivefs
 
+import "slices"
+
 // Role represents the level of access granted by a permission.
 type Role string
 
@@ -22,3 +24,8 @@ const (
 	// RoleReader grants read-only access to the file.
 	RoleReader Role = "reader"
 )
+
+// rank orders the roles by the access they grant, from -1 for unknown roles to 5 for RoleOwner.
+func (r Role) rank() int {
+	return slices.Index([]Role{RoleReader, RoleCommenter, RoleWriter, RoleFileOrganizer, RoleOrganizer, RoleOwner}, r)
+}
@@ -0,0 +1,81 @@
+package drivefs
+
+import (
+	"context"
+	"iter"
+)
+
+// PageOptions configures how ReadDirSeq and QuerySeq fetch the pages of files from Google Drive.
+type PageOptions struct {
+	// PageSize is the maximum number of files fetched by a request. Zero means the default of Google Drive.
+	PageSize int64
+
+	// OrderBy is a comma-separated list of sort keys such as "folder,name" or "modifiedTime desc".
+	// Empty means the default order of Google Drive.
+	OrderBy string
+
+	// PageToken, if not empty, resumes a listing from a token passed to OnPage by a previous listing
+	// with the same arguments and options. Page tokens are opaque.
+	PageToken string
+
+	// OnPage, if not nil, is called after all files of a page have been yielded,
+	// with the token of the next page, which is empty after the last page.
+	// Saving the token as a checkpoint allows a long listing to be resumed with PageToken
+	// without yielding the files of the previous pages again.
+	OnPage func(nextPageToken string)
+}
+
+// ReadDirSeq is like ReadDirWithOptions but returns an iterator that fetches the pages of children lazily,
+// as the loop proceeds, and stops requesting pages when the loop is stopped.
+// The iteration ends after yielding an error with a zero FileInfo.
+func (s *DriveFS) ReadDirSeq(fileID FileID, opts ListOptions, pageOpts PageOptions) iter.Seq2[FileInfo, error] {
+	return s.ReadDirSeqContext(context.Background(), fileID, opts, pageOpts)
+}
+
+// ReadDirSeqContext is like ReadDirSeq but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ReadDirSeqContext(ctx context.Context, fileID FileID, opts ListOptions, pageOpts PageOptions) iter.Seq2[FileInfo, error] {
+	return listSeq(ctx, s.backend, childrenQuery(string(fileID), opts).String(), pageOpts)
+}
+
+// QuerySeq is like Query but returns an iterator that fetches the pages of matching files lazily,
+// as the loop proceeds, and stops requesting pages when the loop is stopped.
+// The iteration ends after yielding an error with a zero FileInfo.
+func (s *DriveFS) QuerySeq(query string, pageOpts PageOptions) iter.Seq2[FileInfo, error] {
+	return s.QuerySeqContext(context.Background(), query, pageOpts)
+}
+
+// QuerySeqContext is like QuerySeq but uses ctx for all Google Drive API calls.
+func (s *DriveFS) QuerySeqContext(ctx context.Context, query string, pageOpts PageOptions) iter.Seq2[FileInfo, error] {
+	return listSeq(ctx, s.backend, query, pageOpts)
+}
+
+// listSeq returns an iterator over the files matching query, fetching a page whenever the previous one has been yielded.
+func listSeq(ctx context.Context, b Backend, query string, opts PageOptions) iter.Seq2[FileInfo, error] {
+	return func(yield func(FileInfo, error) bool) {
+		req := ListFilesRequest{Query: query, PageSize: opts.PageSize, OrderBy: opts.OrderBy, PageToken: opts.PageToken}
+		for {
+			list, err := b.ListFiles(ctx, req)
+			if err != nil {
+				yield(FileInfo{}, newDriveError("failed to list files", err))
+				return
+			}
+			for _, f := range list.Files {
+				info, err := newFileInfo(f)
+				if err != nil {
+					yield(FileInfo{}, err)
+					return
+				}
+				if !yield(info, nil) {
+					return
+				}
+			}
+			if opts.OnPage != nil {
+				opts.OnPage(list.NextPageToken)
+			}
+			if list.NextPageToken == "" {
+				return
+			}
+			req.PageToken = list.NextPageToken
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+)
+
+// countingListBackend counts the requests to list files.
+type countingListBackend struct {
+	drivefs.Backend
+	requests int
+}
+
+func (b *countingListBackend) ListFiles(ctx context.Context, req drivefs.ListFilesRequest) (*drive.FileList, error) {
+	b.requests++
+	return b.Backend.ListFiles(ctx, req)
+}
+
+func TestDriveFS_ReadDirSeq(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	dir, err := drivefs.NewWithBackend(mem).Mkdir(mem.RootID(), "dir")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+	var want []string
+	for i := range 25 {
+		name := fmt.Sprintf("file%02d.txt", i)
+		writeRemoteFile(t, drivefs.NewWithBackend(mem), dir.ID, name, "data")
+		want = append(want, name)
+	}
+	b := &countingListBackend{Backend: mem}
+	s := drivefs.NewWithBackend(b)
+
+	t.Run("early termination", func(t *testing.T) {
+		b.requests = 0
+		var names []string
+		for info, err := range s.ReadDirSeq(dir.ID, drivefs.ListOptions{}, drivefs.PageOptions{PageSize: 10}) {
+			if err != nil {
+				t.Fatalf("ReadDirSeq() error = %v", err)
+			}
+			names = append(names, info.Name)
+			if len(names) == 3 {
+				break
+			}
+		}
+		if len(names) != 3 || b.requests != 1 {
+			t.Fatalf("ReadDirSeq() yielded %v with %d requests, want 3 files with 1 request", names, b.requests)
+		}
+	})
+
+	t.Run("order", func(t *testing.T) {
+		b.requests = 0
+		var names []string
+		for info, err := range s.ReadDirSeq(dir.ID, drivefs.ListOptions{}, drivefs.PageOptions{PageSize: 10, OrderBy: "name desc"}) {
+			if err != nil {
+				t.Fatalf("ReadDirSeq() error = %v", err)
+			}
+			names = append(names, info.Name)
+		}
+		reversed := slices.Clone(want)
+		slices.Reverse(reversed)
+		if !slices.Equal(names, reversed) || b.requests != 3 {
+			t.Fatalf("ReadDirSeq() yielded %v with %d requests, want %v with 3 requests", names, b.requests, reversed)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		var checkpoint string
+		var names []string
+		opts := drivefs.PageOptions{PageSize: 10, OrderBy: "name", OnPage: func(next string) { checkpoint = next }}
+		for info, err := range s.ReadDirSeq(dir.ID, drivefs.ListOptions{}, opts) {
+			if err != nil {
+				t.Fatalf("ReadDirSeq() error = %v", err)
+			}
+			names = append(names, info.Name)
+			if len(names) == 15 {
+				break
+			}
+		}
+		if checkpoint == "" {
+			t.Fatalf("OnPage() was not called with a page token")
+		}
+
+		names = names[:10]
+		var tokens []string
+		opts = drivefs.PageOptions{PageSize: 10, OrderBy: "name", PageToken: checkpoint, OnPage: func(next string) { tokens = append(tokens, next) }}
+		for info, err := range s.ReadDirSeq(dir.ID, drivefs.ListOptions{}, opts) {
+			if err != nil {
+				t.Fatalf("ReadDirSeq() error = %v", err)
+			}
+			names = append(names, info.Name)
+		}
+		if !slices.Equal(names, want) {
+			t.Fatalf("resumed ReadDirSeq() yielded %v, want %v", names, want)
+		}
+		if len(tokens) != 2 || tokens[1] != "" {
+			t.Fatalf("OnPage() tokens = %q, want two pages ending with an empty token", tokens)
+		}
+	})
+}
+
+func TestDriveFS_QuerySeq(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	writeRemoteFile(t, s, mem.RootID(), "a.txt", "a")
+	writeRemoteFile(t, s, mem.RootID(), "b.txt", "b")
+
+	var names []string
+	for info, err := range s.QuerySeq(drivefs.NameContains("a").String(), drivefs.PageOptions{PageSize: 1}) {
+		if err != nil {
+			t.Fatalf("QuerySeq() error = %v", err)
+		}
+		names = append(names, info.Name)
+	}
+	if !slices.Equal(names, []string{"a.txt"}) {
+		t.Fatalf("QuerySeq() = %v, want a.txt", names)
+	}
+
+	var errs int
+	for info, err := range s.QuerySeq("name = ", drivefs.PageOptions{}) {
+		if !errors.Is(err, drivefs.ErrDriveError) || info.ID != "" {
+			t.Fatalf("QuerySeq() with an invalid query = %+v, %v, want ErrDriveError", info, err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Fatalf("QuerySeq() yielded %d errors, want 1", errs)
+	}
+}
@@ -0,0 +1,362 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type serviceBackend struct {
+	service *drive.Service
+}
+
+var _ Backend = (*serviceBackend)(nil)
+
+// NewServiceBackend returns a Backend that calls the Google Drive API through the given drive.Service.
+// All calls support both My Drive and shared drives.
+func NewServiceBackend(service *drive.Service) Backend {
+	return &serviceBackend{service: service}
+}
+
+func (b *serviceBackend) GetFile(ctx context.Context, fileID string) (*drive.File, error) {
+	return b.service.Files.Get(fileID).
+		SupportsAllDrives(true).
+		Fields(driveFileFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) ListFiles(ctx context.Context, req ListFilesRequest) (*drive.FileList, error) {
+	call := b.service.Files.List().
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Fields(driveFilesFields)
+	if req.Query != "" {
+		call = call.Q(req.Query)
+	}
+	if req.PageSize > 0 {
+		call = call.PageSize(req.PageSize)
+	}
+	if req.PageToken != "" {
+		call = call.PageToken(req.PageToken)
+	}
+	if req.OrderBy != "" {
+		call = call.OrderBy(req.OrderBy)
+	}
+	if req.DriveID != "" {
+		call = call.Corpora("drive").DriveId(req.DriveID)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) GenerateIDs(ctx context.Context, count int) ([]string, error) {
+	res, err := b.service.Files.GenerateIds().
+		Count(int64(count)).
+		Space("drive").
+		Type("files").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	return res.Ids, nil
+}
+
+func (b *serviceBackend) CreateFile(ctx context.Context, req CreateFileRequest) (*drive.File, error) {
+	call := b.service.Files.Create(req.File).
+		SupportsAllDrives(true).
+		Fields(driveFileFields)
+	if req.OCRLanguage != "" {
+		call = call.OcrLanguage(req.OCRLanguage)
+	}
+	if req.Media != nil {
+		call = call.Media(req.Media, req.MediaOptions...)
+		if req.Progress != nil {
+			call = call.ProgressUpdater(func(current, _ int64) { req.Progress(current) })
+		}
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) UpdateFile(ctx context.Context, req UpdateFileRequest) (*drive.File, error) {
+	call := b.service.Files.Update(req.FileID, req.File).
+		SupportsAllDrives(true).
+		Fields(driveFileFields)
+	if len(req.AddParents) > 0 {
+		call = call.AddParents(strings.Join(req.AddParents, ","))
+	}
+	if len(req.RemoveParents) > 0 {
+		call = call.RemoveParents(strings.Join(req.RemoveParents, ","))
+	}
+	if req.Media != nil {
+		call = call.Media(req.Media, req.MediaOptions...)
+		if req.Progress != nil {
+			call = call.ProgressUpdater(func(current, _ int64) { req.Progress(current) })
+		}
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) CopyFile(ctx context.Context, req CopyFileRequest) (*drive.File, error) {
+	return b.service.Files.Copy(req.FileID, req.File).
+		SupportsAllDrives(true).
+		Fields(driveFileFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) DeleteFile(ctx context.Context, fileID string) error {
+	return b.service.Files.Delete(fileID).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) EmptyTrash(ctx context.Context, driveID string) error {
+	call := b.service.Files.EmptyTrash()
+	if driveID != "" {
+		call = call.DriveId(driveID)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) DownloadFile(ctx context.Context, req DownloadFileRequest) (io.ReadCloser, error) {
+	if req.Length == 0 {
+		// A Range header cannot request zero bytes, so only the existence of the file is checked.
+		_, err := b.service.Files.Get(req.FileID).
+			SupportsAllDrives(true).
+			Fields("id").
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	call := b.service.Files.Get(req.FileID).
+		SupportsAllDrives(true)
+	ranged := req.Offset > 0 || req.Length > 0
+	if ranged {
+		rangeHeader := fmt.Sprintf("bytes=%d-", req.Offset)
+		if req.Length > 0 {
+			rangeHeader += fmt.Sprint(req.Offset + req.Length - 1)
+		}
+		call.Header().Set("Range", rangeHeader)
+	}
+	resp, err := call.
+		Context(ctx).
+		Download()
+	if err != nil {
+		return nil, err
+	}
+	if ranged && resp.StatusCode != http.StatusPartialContent {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("range request not honored: %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (b *serviceBackend) ExportFile(ctx context.Context, fileID, mimeType string) (io.ReadCloser, error) {
+	resp, err := b.service.Files.Export(fileID, mimeType).
+		Context(ctx).
+		Download()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *serviceBackend) ListPermissions(ctx context.Context, fileID string, pageToken string) (*drive.PermissionList, error) {
+	call := b.service.Permissions.List(fileID).
+		SupportsAllDrives(true).
+		Fields(drivePermissionsFields)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) CreatePermission(ctx context.Context, req CreatePermissionRequest) (*drive.Permission, error) {
+	call := b.service.Permissions.Create(req.FileID, req.Permission).
+		SupportsAllDrives(true).
+		Fields(drivePermissionFields)
+	if req.SuppressNotificationEmail {
+		call = call.SendNotificationEmail(false)
+	}
+	if req.EmailMessage != "" {
+		call = call.EmailMessage(req.EmailMessage)
+	}
+	if req.TransferOwnership {
+		call = call.TransferOwnership(true)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) UpdatePermission(ctx context.Context, req UpdatePermissionRequest) (*drive.Permission, error) {
+	perm := *req.Permission
+	// The ID, the grantee and the details of a permission are not writable.
+	perm.Id, perm.Type, perm.EmailAddress, perm.Domain, perm.PermissionDetails = "", "", "", "", nil
+	call := b.service.Permissions.Update(req.FileID, req.Permission.Id, &perm).
+		SupportsAllDrives(true).
+		Fields(drivePermissionFields)
+	if req.RemoveExpiration {
+		call = call.RemoveExpiration(true)
+	}
+	if req.TransferOwnership {
+		call = call.TransferOwnership(true)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) DeletePermission(ctx context.Context, fileID, permissionID string) error {
+	return b.service.Permissions.Delete(fileID, permissionID).
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) ListRevisions(ctx context.Context, fileID string, pageToken string) (*drive.RevisionList, error) {
+	call := b.service.Revisions.List(fileID).
+		Fields(driveRevisionsFields)
+	if pageToken != "" {
+		call = call.PageToken(pageToken)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) DownloadRevision(ctx context.Context, fileID, revisionID string) (io.ReadCloser, error) {
+	resp, err := b.service.Revisions.Get(fileID, revisionID).
+		Context(ctx).
+		Download()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *serviceBackend) UpdateRevision(ctx context.Context, req UpdateRevisionRequest) (*drive.Revision, error) {
+	rev := *req.Revision
+	// The ID of a revision is not writable.
+	rev.Id = ""
+	return b.service.Revisions.Update(req.FileID, req.Revision.Id, &rev).
+		Fields(driveRevisionFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) DeleteRevision(ctx context.Context, fileID, revisionID string) error {
+	return b.service.Revisions.Delete(fileID, revisionID).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) GetStartPageToken(ctx context.Context, driveID string) (string, error) {
+	call := b.service.Changes.GetStartPageToken().
+		SupportsAllDrives(true)
+	if driveID != "" {
+		call = call.DriveId(driveID)
+	}
+	res, err := call.
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	return res.StartPageToken, nil
+}
+
+func (b *serviceBackend) ListChanges(ctx context.Context, req ListChangesRequest) (*drive.ChangeList, error) {
+	call := b.service.Changes.List(req.PageToken).
+		SupportsAllDrives(true).
+		Fields(driveChangesFields)
+	if req.DriveID != "" {
+		call = call.DriveId(req.DriveID).IncludeItemsFromAllDrives(true)
+	}
+	if req.PageSize > 0 {
+		call = call.PageSize(req.PageSize)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) ListDrives(ctx context.Context, req ListDrivesRequest) (*drive.DriveList, error) {
+	call := b.service.Drives.List().
+		Fields(driveDrivesFields)
+	if req.Query != "" {
+		call = call.Q(req.Query)
+	}
+	if req.PageSize > 0 {
+		call = call.PageSize(req.PageSize)
+	}
+	if req.PageToken != "" {
+		call = call.PageToken(req.PageToken)
+	}
+	return call.
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) GetDrive(ctx context.Context, driveID string) (*drive.Drive, error) {
+	return b.service.Drives.Get(driveID).
+		Fields(driveDriveFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) CreateDrive(ctx context.Context, req CreateDriveRequest) (*drive.Drive, error) {
+	return b.service.Drives.Create(req.RequestID, req.Drive).
+		Fields(driveDriveFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) UpdateDrive(ctx context.Context, req UpdateDriveRequest) (*drive.Drive, error) {
+	drv := *req.Drive
+	// The ID of a shared drive is not writable.
+	drv.Id = ""
+	return b.service.Drives.Update(req.Drive.Id, &drv).
+		Fields(driveDriveFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) HideDrive(ctx context.Context, driveID string) (*drive.Drive, error) {
+	return b.service.Drives.Hide(driveID).
+		Fields(driveDriveFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) UnhideDrive(ctx context.Context, driveID string) (*drive.Drive, error) {
+	return b.service.Drives.Unhide(driveID).
+		Fields(driveDriveFields).
+		Context(ctx).
+		Do()
+}
+
+func (b *serviceBackend) DeleteDrive(ctx context.Context, driveID string) error {
+	return b.service.Drives.Delete(driveID).
+		Context(ctx).
+		Do()
+}
@@ -0,0 +1,251 @@
+package drivefs
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// SharedDrive contains metadata about a shared drive in Google Drive.
+type SharedDrive struct {
+	// ID is the unique identifier for the shared drive.
+	ID string
+
+	// Name is the name of the shared drive.
+	Name string
+
+	// RootID is the ID of the root folder of the shared drive,
+	// which can be passed to methods that accept a rootID or parentID, such as MkdirAll and FindByPath.
+	RootID FileID
+
+	// CreatedTime is the time at which the shared drive was created.
+	CreatedTime time.Time
+
+	// Hidden is true if the shared drive is hidden from the default view.
+	Hidden bool
+
+	// Restrictions are the restrictions on access to the items in the shared drive.
+	Restrictions SharedDriveRestrictions
+}
+
+// SharedDriveRestrictions are the restrictions on access to the items in a shared drive.
+type SharedDriveRestrictions struct {
+	// DomainUsersOnly restricts access to the users of the domain to which the shared drive belongs.
+	DomainUsersOnly bool
+
+	// CopyRequiresWriterPermission disables copying, printing and downloading of files for readers and commenters.
+	CopyRequiresWriterPermission bool
+
+	// DriveMembersOnly restricts access to the members of the shared drive.
+	DriveMembersOnly bool
+}
+
+// SharedDriveListOptions configures which shared drives are listed by ListSharedDrives.
+type SharedDriveListOptions struct {
+	// Name, if not empty, restricts the shared drives to those with exactly this name.
+	Name string
+
+	// NameContains, if not empty, restricts the shared drives to those whose name contains it.
+	NameContains string
+}
+
+// ListSharedDrives lists the shared drives of which the user is a member, including hidden ones,
+// selected by opts.
+func (s *DriveFS) ListSharedDrives(opts SharedDriveListOptions) (sharedDrives []SharedDrive, err error) {
+	return s.ListSharedDrivesContext(context.Background(), opts)
+}
+
+// ListSharedDrivesContext is like ListSharedDrives but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ListSharedDrivesContext(ctx context.Context, opts SharedDriveListOptions) (sharedDrives []SharedDrive, err error) {
+	var query Query
+	if opts.Name != "" {
+		query = And(query, NameEquals(opts.Name))
+	}
+	if opts.NameContains != "" {
+		query = And(query, NameContains(opts.NameContains))
+	}
+	var pageToken string
+	for {
+		res, err := s.backend.ListDrives(ctx, ListDrivesRequest{Query: query.String(), PageToken: pageToken})
+		if err != nil {
+			return nil, newDriveError("failed to list shared drives", err)
+		}
+		for _, drv := range res.Drives {
+			sharedDrives = append(sharedDrives, newSharedDrive(drv))
+		}
+		if res.NextPageToken == "" {
+			return sharedDrives, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// SharedDriveInfo returns the SharedDrive with the given driveID.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) SharedDriveInfo(driveID string) (sharedDrive SharedDrive, err error) {
+	return s.SharedDriveInfoContext(context.Background(), driveID)
+}
+
+// SharedDriveInfoContext is like SharedDriveInfo but uses ctx for all Google Drive API calls.
+func (s *DriveFS) SharedDriveInfoContext(ctx context.Context, driveID string) (sharedDrive SharedDrive, err error) {
+	drv, err := s.backend.GetDrive(ctx, driveID)
+	if err != nil {
+		return SharedDrive{}, resourceError(fmt.Sprintf("shared drive '%s'", driveID), "failed to get shared drive", err)
+	}
+	return newSharedDrive(drv), nil
+}
+
+// SharedDriveRoot returns the ID of the root folder of the shared drive with the given driveID,
+// which can be passed to methods that accept a rootID or parentID, such as MkdirAll and FindByPath.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) SharedDriveRoot(driveID string) (rootID FileID, err error) {
+	return s.SharedDriveRootContext(context.Background(), driveID)
+}
+
+// SharedDriveRootContext is like SharedDriveRoot but uses ctx for all Google Drive API calls.
+func (s *DriveFS) SharedDriveRootContext(ctx context.Context, driveID string) (rootID FileID, err error) {
+	drv, err := s.SharedDriveInfoContext(ctx, driveID)
+	if err != nil {
+		return "", err
+	}
+	return drv.RootID, nil
+}
+
+// CreateSharedDrive creates a shared drive with the given name and returns its SharedDrive.
+// requestID identifies the request so that repeating it, for example after a network failure,
+// does not create another shared drive; a repeated request fails with an error wrapping ErrAlreadyExists.
+// An empty requestID is replaced with a random one.
+func (s *DriveFS) CreateSharedDrive(name, requestID string) (sharedDrive SharedDrive, err error) {
+	return s.CreateSharedDriveContext(context.Background(), name, requestID)
+}
+
+// CreateSharedDriveContext is like CreateSharedDrive but uses ctx for all Google Drive API calls.
+func (s *DriveFS) CreateSharedDriveContext(ctx context.Context, name, requestID string) (sharedDrive SharedDrive, err error) {
+	if requestID == "" {
+		requestID = rand.Text()
+	}
+	drv, err := s.backend.CreateDrive(ctx, CreateDriveRequest{RequestID: requestID, Drive: &drive.Drive{Name: name}})
+	if err != nil {
+		if isStatus(err, http.StatusConflict) {
+			return SharedDrive{}, fmt.Errorf("shared drive with request ID '%s' already created: %w", requestID, ErrAlreadyExists)
+		}
+		return SharedDrive{}, newDriveError("failed to create shared drive", err)
+	}
+	return newSharedDrive(drv), nil
+}
+
+// RenameSharedDrive renames the shared drive with the given driveID to newName and returns its SharedDrive.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) RenameSharedDrive(driveID, newName string) (sharedDrive SharedDrive, err error) {
+	return s.RenameSharedDriveContext(context.Background(), driveID, newName)
+}
+
+// RenameSharedDriveContext is like RenameSharedDrive but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RenameSharedDriveContext(ctx context.Context, driveID, newName string) (sharedDrive SharedDrive, err error) {
+	return s.updateSharedDrive(ctx, &drive.Drive{Id: driveID, Name: newName})
+}
+
+// SetSharedDriveRestrictions replaces the restrictions of the shared drive with the given driveID
+// and returns its SharedDrive.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) SetSharedDriveRestrictions(driveID string, restrictions SharedDriveRestrictions) (sharedDrive SharedDrive, err error) {
+	return s.SetSharedDriveRestrictionsContext(context.Background(), driveID, restrictions)
+}
+
+// SetSharedDriveRestrictionsContext is like SetSharedDriveRestrictions but uses ctx for all Google Drive API calls.
+func (s *DriveFS) SetSharedDriveRestrictionsContext(ctx context.Context, driveID string, restrictions SharedDriveRestrictions) (sharedDrive SharedDrive, err error) {
+	return s.updateSharedDrive(ctx, &drive.Drive{
+		Id: driveID,
+		Restrictions: &drive.DriveRestrictions{
+			DomainUsersOnly:              restrictions.DomainUsersOnly,
+			CopyRequiresWriterPermission: restrictions.CopyRequiresWriterPermission,
+			DriveMembersOnly:             restrictions.DriveMembersOnly,
+			ForceSendFields:              []string{"DomainUsersOnly", "CopyRequiresWriterPermission", "DriveMembersOnly"},
+		},
+	})
+}
+
+func (s *DriveFS) updateSharedDrive(ctx context.Context, update *drive.Drive) (SharedDrive, error) {
+	drv, err := s.backend.UpdateDrive(ctx, UpdateDriveRequest{Drive: update})
+	if err != nil {
+		return SharedDrive{}, resourceError(fmt.Sprintf("shared drive '%s'", update.Id), "failed to update shared drive", err)
+	}
+	return newSharedDrive(drv), nil
+}
+
+// HideSharedDrive hides the shared drive with the given driveID from the default view and returns its SharedDrive.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) HideSharedDrive(driveID string) (sharedDrive SharedDrive, err error) {
+	return s.HideSharedDriveContext(context.Background(), driveID)
+}
+
+// HideSharedDriveContext is like HideSharedDrive but uses ctx for all Google Drive API calls.
+func (s *DriveFS) HideSharedDriveContext(ctx context.Context, driveID string) (sharedDrive SharedDrive, err error) {
+	drv, err := s.backend.HideDrive(ctx, driveID)
+	if err != nil {
+		return SharedDrive{}, resourceError(fmt.Sprintf("shared drive '%s'", driveID), "failed to hide shared drive", err)
+	}
+	return newSharedDrive(drv), nil
+}
+
+// UnhideSharedDrive restores the hidden shared drive with the given driveID to the default view
+// and returns its SharedDrive.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) UnhideSharedDrive(driveID string) (sharedDrive SharedDrive, err error) {
+	return s.UnhideSharedDriveContext(context.Background(), driveID)
+}
+
+// UnhideSharedDriveContext is like UnhideSharedDrive but uses ctx for all Google Drive API calls.
+func (s *DriveFS) UnhideSharedDriveContext(ctx context.Context, driveID string) (sharedDrive SharedDrive, err error) {
+	drv, err := s.backend.UnhideDrive(ctx, driveID)
+	if err != nil {
+		return SharedDrive{}, resourceError(fmt.Sprintf("shared drive '%s'", driveID), "failed to unhide shared drive", err)
+	}
+	return newSharedDrive(drv), nil
+}
+
+// DeleteSharedDrive permanently deletes the shared drive with the given driveID.
+// Only empty shared drives can be deleted; otherwise returns ErrNotRemovable.
+// Items in the trash of the shared drive count, so it may need to be emptied by EmptyTrash first.
+// Returns an error wrapping ErrNotFound if the shared drive does not exist.
+func (s *DriveFS) DeleteSharedDrive(driveID string) (err error) {
+	return s.DeleteSharedDriveContext(context.Background(), driveID)
+}
+
+// DeleteSharedDriveContext is like DeleteSharedDrive but uses ctx for all Google Drive API calls.
+func (s *DriveFS) DeleteSharedDriveContext(ctx context.Context, driveID string) (err error) {
+	res, err := s.backend.ListFiles(ctx, ListFilesRequest{DriveID: driveID, PageSize: 1})
+	if err != nil {
+		return resourceError(fmt.Sprintf("shared drive '%s'", driveID), "failed to list files", err)
+	}
+	if len(res.Files) > 0 {
+		return fmt.Errorf("shared drive '%s' is not empty: %w", driveID, ErrNotRemovable)
+	}
+	if err := s.backend.DeleteDrive(ctx, driveID); err != nil {
+		return resourceError(fmt.Sprintf("shared drive '%s'", driveID), "failed to delete shared drive", err)
+	}
+	return nil
+}
+
+func newSharedDrive(d *drive.Drive) SharedDrive {
+	createdTime, _ := time.Parse(time.RFC3339, d.CreatedTime)
+	sd := SharedDrive{
+		ID:          d.Id,
+		Name:        d.Name,
+		RootID:      FileID(d.Id),
+		CreatedTime: createdTime,
+		Hidden:      d.Hidden,
+	}
+	if r := d.Restrictions; r != nil {
+		sd.Restrictions = SharedDriveRestrictions{
+			DomainUsersOnly:              r.DomainUsersOnly,
+			CopyRequiresWriterPermission: r.CopyRequiresWriterPermission,
+			DriveMembersOnly:             r.DriveMembersOnly,
+		}
+	}
+	return sd
+}
@@ -0,0 +1,151 @@
+package drivefs_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func sharedDriveNames(t *testing.T, s *drivefs.DriveFS, opts drivefs.SharedDriveListOptions) []string {
+	t.Helper()
+	drives, err := s.ListSharedDrives(opts)
+	if err != nil {
+		t.Fatalf("ListSharedDrives() error = %v", err)
+	}
+	var names []string
+	for _, d := range drives {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func TestDriveFS_SharedDrives(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	team, err := s.CreateSharedDrive("Team", "request-1")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	if team.ID == "" || team.RootID != drivefs.FileID(team.ID) || team.Name != "Team" || team.CreatedTime.IsZero() {
+		t.Fatalf("CreateSharedDrive() = %+v", team)
+	}
+	if _, err := s.CreateSharedDrive("Team", "request-1"); !errors.Is(err, drivefs.ErrAlreadyExists) {
+		t.Fatalf("CreateSharedDrive() with a used request ID error = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := s.CreateSharedDrive("Archive", ""); err != nil {
+		t.Fatalf("CreateSharedDrive() without request ID error = %v", err)
+	}
+	if _, err := s.CreateSharedDrive(`Bob's \ Team`, ""); err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+
+	if got, want := sharedDriveNames(t, s, drivefs.SharedDriveListOptions{}), []string{"Team", "Archive", `Bob's \ Team`}; !slices.Equal(got, want) {
+		t.Fatalf("ListSharedDrives() = %v, want %v", got, want)
+	}
+	if got, want := sharedDriveNames(t, s, drivefs.SharedDriveListOptions{NameContains: "arch"}), []string{"Archive"}; !slices.Equal(got, want) {
+		t.Fatalf("ListSharedDrives() containing 'arch' = %v, want %v", got, want)
+	}
+	if got, want := sharedDriveNames(t, s, drivefs.SharedDriveListOptions{Name: "Team"}), []string{"Team"}; !slices.Equal(got, want) {
+		t.Fatalf("ListSharedDrives() named 'Team' = %v, want %v", got, want)
+	}
+	opts := drivefs.SharedDriveListOptions{Name: `Bob's \ Team`, NameContains: "Bob's"}
+	if got, want := sharedDriveNames(t, s, opts), []string{`Bob's \ Team`}; !slices.Equal(got, want) {
+		t.Fatalf("ListSharedDrives() named and containing quotes = %v, want %v", got, want)
+	}
+
+	renamed, err := s.RenameSharedDrive(team.ID, "Team 2")
+	if err != nil || renamed.Name != "Team 2" {
+		t.Fatalf("RenameSharedDrive() = %+v, %v", renamed, err)
+	}
+	if hidden, err := s.HideSharedDrive(team.ID); err != nil || !hidden.Hidden {
+		t.Fatalf("HideSharedDrive() = %+v, %v", hidden, err)
+	}
+	restrictions := drivefs.SharedDriveRestrictions{DomainUsersOnly: true, DriveMembersOnly: true}
+	if updated, err := s.SetSharedDriveRestrictions(team.ID, restrictions); err != nil || updated.Restrictions != restrictions {
+		t.Fatalf("SetSharedDriveRestrictions() = %+v, %v", updated, err)
+	}
+	restrictions = drivefs.SharedDriveRestrictions{CopyRequiresWriterPermission: true}
+	if updated, err := s.SetSharedDriveRestrictions(team.ID, restrictions); err != nil || updated.Restrictions != restrictions {
+		t.Fatalf("SetSharedDriveRestrictions() = %+v, %v", updated, err)
+	}
+	if unhidden, err := s.UnhideSharedDrive(team.ID); err != nil || unhidden.Hidden {
+		t.Fatalf("UnhideSharedDrive() = %+v, %v", unhidden, err)
+	}
+	info, err := s.SharedDriveInfo(team.ID)
+	if err != nil || info.Name != "Team 2" || info.Hidden || info.Restrictions != restrictions {
+		t.Fatalf("SharedDriveInfo() = %+v, %v", info, err)
+	}
+
+	rootID, err := s.SharedDriveRoot(team.ID)
+	if err != nil {
+		t.Fatalf("SharedDriveRoot() error = %v", err)
+	}
+	dir, err := s.MkdirAll(rootID, "/docs")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if found, err := s.FindByPath(rootID, "/docs"); err != nil || len(found) != 1 || found[0].ID != dir.ID {
+		t.Fatalf("FindByPath() = %+v, %v, want the created directory", found, err)
+	}
+	if found, err := s.FindByPath(mem.RootID(), "/docs"); err != nil || len(found) != 0 {
+		t.Fatalf("FindByPath() in My Drive = %+v, %v, want none", found, err)
+	}
+
+	if err := s.DeleteSharedDrive(team.ID); !errors.Is(err, drivefs.ErrNotRemovable) {
+		t.Fatalf("DeleteSharedDrive() of a non-empty shared drive error = %v, want ErrNotRemovable", err)
+	}
+	if err := s.Remove(dir.ID, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := s.DeleteSharedDrive(team.ID); !errors.Is(err, drivefs.ErrNotRemovable) {
+		t.Fatalf("DeleteSharedDrive() with trashed items error = %v, want ErrNotRemovable", err)
+	}
+	if err := s.EmptyTrash(team.ID); err != nil {
+		t.Fatalf("EmptyTrash() error = %v", err)
+	}
+	if err := s.DeleteSharedDrive(team.ID); err != nil {
+		t.Fatalf("DeleteSharedDrive() error = %v", err)
+	}
+	if _, err := s.SharedDriveInfo(team.ID); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("SharedDriveInfo() of a deleted shared drive error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.SharedDriveRoot(team.ID); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("SharedDriveRoot() of a deleted shared drive error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDriveFS_SharedDriveIsolation(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	team, err := s.CreateSharedDrive("Team", "")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	mine := writeRemoteFile(t, s, mem.RootID(), "mine.txt", "mine")
+	shared := writeRemoteFile(t, s, team.RootID, "shared.txt", "shared")
+	for _, id := range []drivefs.FileID{mine.ID, shared.ID} {
+		if err := s.Remove(id, true); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+	}
+	if got, want := trashNames(t, s, drivefs.TrashOptions{DriveID: team.ID}), []string{"shared.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("ListTrash() in the shared drive = %v, want %v", got, want)
+	}
+	if err := s.EmptyTrash(""); err != nil {
+		t.Fatalf("EmptyTrash() error = %v", err)
+	}
+	if got, want := trashNames(t, s, drivefs.TrashOptions{}), []string{"shared.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("ListTrash() after emptying the trash of My Drive = %v, want %v", got, want)
+	}
+
+	feed := drivefs.NewChangeFeed(s, drivefs.ChangeFeedOptions{DriveIDs: []string{team.ID}})
+	poll(t, feed)
+	file := writeRemoteFile(t, s, team.RootID, "new.txt", "new")
+	changes := poll(t, feed)
+	if len(changes) != 1 || changes[0].File.ID != file.ID || changes[0].DriveID != team.ID {
+		t.Fatalf("Poll() = %+v, want the change in the shared drive", changes)
+	}
+}
@@ -0,0 +1,195 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// snapshotPageSize is the number of files requested per page by Snapshot, which is the maximum of Google Drive.
+const snapshotPageSize = 1000
+
+// Snapshot is an in-memory index of the tree of files and directories under a root,
+// taken by DriveFS.Snapshot at a point in time. Trashed items are not included.
+// The methods of a Snapshot make no Google Drive API calls and do not reflect later changes.
+// A Snapshot is safe for concurrent use.
+type Snapshot struct {
+	root     FileID
+	files    map[FileID]FileInfo
+	parents  map[FileID][]FileID
+	children map[FileID][]FileID
+}
+
+// Snapshot enumerates the file or directory with the given rootID and all of its descendants
+// and returns an in-memory index of them.
+// Instead of listing each directory, it lists all files of the drive containing the root page by page,
+// which is the shared drive of the root or My Drive together with the items shared with the user,
+// so that a large tree is indexed with a few requests.
+// Returns ErrNotFound if the root does not exist.
+func (s *DriveFS) Snapshot(rootID FileID) (snapshot *Snapshot, err error) {
+	return s.SnapshotContext(context.Background(), rootID)
+}
+
+// SnapshotContext is like Snapshot but uses ctx for all Google Drive API calls.
+func (s *DriveFS) SnapshotContext(ctx context.Context, rootID FileID) (snapshot *Snapshot, err error) {
+	root, found, err := findByID(ctx, s.backend, string(rootID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("file not found: %s: %w", rootID, ErrNotFound)
+	}
+	var files []*drive.File
+	if root.MimeType == mimeTypeGoogleAppFolder {
+		req := ListFilesRequest{Query: Trashed(false).String(), PageSize: snapshotPageSize, DriveID: root.DriveId}
+		for {
+			list, err := s.backend.ListFiles(ctx, req)
+			if err != nil {
+				return nil, newDriveError("failed to list files", err)
+			}
+			files = append(files, list.Files...)
+			if list.NextPageToken == "" {
+				break
+			}
+			req.PageToken = list.NextPageToken
+		}
+	}
+	return newSnapshot(root, files)
+}
+
+// newSnapshot indexes root and the descendants of root among files.
+func newSnapshot(root *drive.File, files []*drive.File) (*Snapshot, error) {
+	// byParent holds the files by the IDs of their parents in the order in which they are listed.
+	byParent := map[string][]*drive.File{}
+	for _, f := range files {
+		for _, parentID := range f.Parents {
+			byParent[parentID] = append(byParent[parentID], f)
+		}
+	}
+	snapshot := &Snapshot{
+		root:     FileID(root.Id),
+		files:    map[FileID]FileInfo{},
+		parents:  map[FileID][]FileID{},
+		children: map[FileID][]FileID{},
+	}
+	// Index the files reachable from root, visiting each of them once.
+	queue := []*drive.File{root}
+	queued := map[string]bool{root.Id: true}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		info, err := newFileInfo(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create FileInfo: %w", err)
+		}
+		snapshot.files[info.ID] = info
+		for _, child := range byParent[f.Id] {
+			snapshot.children[info.ID] = append(snapshot.children[info.ID], FileID(child.Id))
+			snapshot.parents[FileID(child.Id)] = append(snapshot.parents[FileID(child.Id)], info.ID)
+			if !queued[child.Id] {
+				queued[child.Id] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// Root returns the FileInfo of the root of the snapshot.
+func (s *Snapshot) Root() FileInfo {
+	return s.files[s.root]
+}
+
+// Len returns the number of files and directories in the snapshot, including the root.
+func (s *Snapshot) Len() int {
+	return len(s.files)
+}
+
+// Info returns the FileInfo of the file or directory with the given fileID,
+// and reports whether it is in the snapshot.
+func (s *Snapshot) Info(fileID FileID) (info FileInfo, found bool) {
+	info, found = s.files[fileID]
+	return info, found
+}
+
+// ReadDir returns the FileInfo of the files and subdirectories within the directory with the given fileID.
+// Returns ErrNotFound if the directory is not in the snapshot.
+func (s *Snapshot) ReadDir(fileID FileID) (children []FileInfo, err error) {
+	if _, found := s.files[fileID]; !found {
+		return nil, fmt.Errorf("file not found in snapshot: %s: %w", fileID, ErrNotFound)
+	}
+	for _, id := range s.children[fileID] {
+		children = append(children, s.files[id])
+	}
+	return children, nil
+}
+
+// FindByPath resolves the given absolute path from the root of the snapshot.
+// Returns all files matching the path (multiple results if duplicates exist at any level),
+// or an empty slice if the path does not exist.
+// The path must be absolute (starting with '/').
+func (s *Snapshot) FindByPath(path Path) (info []FileInfo, err error) {
+	parts, err := validateAndSplitPath(string(path))
+	if err != nil {
+		return nil, fmt.Errorf("path validation failed: %w", err)
+	}
+	ids := []FileID{s.root}
+	for _, part := range parts {
+		var next []FileID
+		for _, id := range ids {
+			for _, childID := range s.children[id] {
+				if s.files[childID].Name == part {
+					next = append(next, childID)
+				}
+			}
+		}
+		ids = next
+	}
+	for _, id := range ids {
+		info = append(info, s.files[id])
+	}
+	return info, nil
+}
+
+// ResolvePath returns the absolute path from the root of the snapshot to the file with the given fileID,
+// such as "/folder/subfolder/file".
+// Returns ErrNotFound if the file is not in the snapshot,
+// and ErrMultiParentsNotSupported if the file or one of its ancestors has multiple parents in the snapshot.
+func (s *Snapshot) ResolvePath(fileID FileID) (path Path, err error) {
+	if _, found := s.files[fileID]; !found {
+		return "", fmt.Errorf("file not found in snapshot: %s: %w", fileID, ErrNotFound)
+	}
+	var parts []string
+	for id := fileID; id != s.root; {
+		parents := s.parents[id]
+		if len(parents) > 1 {
+			return "", fmt.Errorf("failed to resolve path with multiple parents not supported: %w", ErrMultiParentsNotSupported)
+		}
+		parts = append(parts, s.files[id].Name)
+		id = parents[0]
+	}
+	slices.Reverse(parts)
+	return Path("/" + strings.Join(parts, "/")), nil
+}
+
+// Walk traverses the tree of the snapshot as DriveFS.Walk does.
+// For each file or directory (including the root), it calls the provided function with
+// the relative path and FileInfo. If the function returns an error, walking stops.
+func (s *Snapshot) Walk(f func(Path, FileInfo) error) (err error) {
+	return s.walk(nil, s.root, f)
+}
+
+func (s *Snapshot) walk(rel []string, fileID FileID, f func(Path, FileInfo) error) error {
+	if err := f(syncPath(rel), s.files[fileID]); err != nil {
+		return err
+	}
+	for _, childID := range s.children[fileID] {
+		if err := s.walk(append(slices.Clone(rel), s.files[childID].Name), childID, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
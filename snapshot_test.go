@@ -0,0 +1,140 @@
+package drivefs_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func TestDriveFS_Snapshot(t *testing.T) {
+	mem, rootID := newWalkTree(t)
+	b := &countingListBackend{Backend: mem}
+	s := drivefs.NewWithBackend(b)
+	writeRemoteFile(t, s, mem.RootID(), "outside.txt", "outside")
+	trashed := writeRemoteFile(t, s, rootID, "trashed.txt", "trashed")
+	if err := s.Remove(trashed.ID, true); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	dup, err := s.Mkdir(findRemote(t, s, rootID, "/dir1").ID, "sub")
+	if err != nil {
+		t.Fatalf("Mkdir() error = %v", err)
+	}
+
+	b.requests = 0
+	snapshot, err := s.Snapshot(rootID)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if b.requests != 1 {
+		t.Fatalf("Snapshot() made %d list requests, want 1", b.requests)
+	}
+	if snapshot.Root().ID != rootID || snapshot.Len() != 34 {
+		t.Fatalf("Snapshot() = root %+v with %d items, want /tree with 34 items", snapshot.Root(), snapshot.Len())
+	}
+
+	type visit struct {
+		path drivefs.Path
+		id   drivefs.FileID
+	}
+	var want, got []visit
+	if err := s.Walk(rootID, func(p drivefs.Path, info drivefs.FileInfo) error {
+		want = append(want, visit{p, info.ID})
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if err := snapshot.Walk(func(p drivefs.Path, info drivefs.FileInfo) error {
+		got = append(got, visit{p, info.ID})
+		return nil
+	}); err != nil {
+		t.Fatalf("Snapshot.Walk() error = %v", err)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Snapshot.Walk() visited %v, want %v", got, want)
+	}
+
+	dir1 := findRemote(t, s, rootID, "/dir1")
+	children, err := snapshot.ReadDir(dir1.ID)
+	if err != nil {
+		t.Fatalf("Snapshot.ReadDir() error = %v", err)
+	}
+	wantChildren, err := s.ReadDir(dir1.ID)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(children) != 3 || !slices.EqualFunc(children, wantChildren, func(a, b drivefs.FileInfo) bool { return a.ID == b.ID }) {
+		t.Fatalf("Snapshot.ReadDir() = %+v, want %+v", children, wantChildren)
+	}
+
+	found, err := snapshot.FindByPath("/dir1/sub")
+	if err != nil || len(found) != 2 || !slices.ContainsFunc(found, func(info drivefs.FileInfo) bool { return info.ID == dup.ID }) {
+		t.Fatalf("Snapshot.FindByPath() = %+v, %v, want both subs", found, err)
+	}
+	if found, err := snapshot.FindByPath("/dir1/missing"); err != nil || len(found) != 0 {
+		t.Fatalf("Snapshot.FindByPath() of a missing path = %+v, %v, want nothing", found, err)
+	}
+	if _, err := snapshot.FindByPath("dir1"); !errors.Is(err, drivefs.ErrInvalidPath) {
+		t.Fatalf("Snapshot.FindByPath() of a relative path error = %v, want ErrInvalidPath", err)
+	}
+
+	file := findRemote(t, s, rootID, "/dir3/sub/file.txt")
+	if p, err := snapshot.ResolvePath(file.ID); err != nil || p != "/dir3/sub/file.txt" {
+		t.Fatalf("Snapshot.ResolvePath() = %q, %v, want /dir3/sub/file.txt", p, err)
+	}
+	if p, err := snapshot.ResolvePath(rootID); err != nil || p != "/" {
+		t.Fatalf("Snapshot.ResolvePath() of the root = %q, %v, want /", p, err)
+	}
+	if info, found := snapshot.Info(file.ID); !found || info.Name != "file.txt" {
+		t.Fatalf("Snapshot.Info() = %+v, %v, want file.txt", info, found)
+	}
+	for _, id := range []drivefs.FileID{trashed.ID, findRemote(t, s, mem.RootID(), "/outside.txt").ID} {
+		if _, err := snapshot.ResolvePath(id); !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("Snapshot.ResolvePath() of an item outside the snapshot error = %v, want ErrNotFound", err)
+		}
+		if _, err := snapshot.ReadDir(id); !errors.Is(err, drivefs.ErrNotFound) {
+			t.Fatalf("Snapshot.ReadDir() of an item outside the snapshot error = %v, want ErrNotFound", err)
+		}
+	}
+
+	if _, err := s.Snapshot("unknown"); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("Snapshot() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDriveFS_Snapshot_SharedDrive(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	team, err := s.CreateSharedDrive("Team", "")
+	if err != nil {
+		t.Fatalf("CreateSharedDrive() error = %v", err)
+	}
+	docs, err := s.MkdirAll(team.RootID, "/docs")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	writeRemoteFile(t, s, docs.ID, "a.txt", "a")
+	writeRemoteFile(t, s, mem.RootID(), "mine.txt", "mine")
+
+	snapshot, err := s.Snapshot(team.RootID)
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if snapshot.Len() != 3 {
+		t.Fatalf("Snapshot() has %d items, want 3", snapshot.Len())
+	}
+	if found, err := snapshot.FindByPath("/docs/a.txt"); err != nil || len(found) != 1 {
+		t.Fatalf("Snapshot.FindByPath() = %+v, %v, want a.txt", found, err)
+	}
+
+	file := findRemote(t, s, team.RootID, "/docs/a.txt")
+	snapshot, err = s.Snapshot(file.ID)
+	if err != nil {
+		t.Fatalf("Snapshot() of a file error = %v", err)
+	}
+	if snapshot.Len() != 1 || snapshot.Root().ID != file.ID {
+		t.Fatalf("Snapshot() of a file = %+v, want only the file", snapshot.Root())
+	}
+}
@@ -0,0 +1,658 @@
+package drivefs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// SyncOptions configures the synchronization performed by SyncUp and SyncDown.
+type SyncOptions struct {
+	// DryRun reports the changes that would be made in SyncResult without making them.
+	DryRun bool
+
+	// Delete removes the files and directories in the destination that do not exist in the source.
+	Delete bool
+
+	// MoveToTrash moves the files and directories removed by Delete from Google Drive to trash
+	// instead of permanently deleting them. Local files are always deleted permanently.
+	MoveToTrash bool
+
+	// Include is a list of glob patterns of the files to be synchronized. Empty means all files.
+	// Patterns are matched by path.Match against the slash-separated path relative to the local directory,
+	// such as "dist/app.js", and patterns without a slash are also matched against the base name, such as "*.js".
+	// Directories are always traversed.
+	Include []string
+
+	// Exclude is a list of glob patterns, in the same syntax as Include, of the files and directories
+	// not to be synchronized. An excluded directory is skipped with all of its contents.
+	// Exclude takes precedence over Include.
+	Exclude []string
+
+	// ExportFormats maps the MIME types of Google Apps files to the MIME types to which SyncDown exports them,
+	// such as "application/vnd.google-apps.document" to "application/pdf".
+	// The local files are named with the extension of the export format appended, such as "Report.pdf".
+	ExportFormats map[string]string
+
+	// Placeholders makes SyncDown write a placeholder file for each Google Apps file that is not exported,
+	// which is named with ".url" appended and contains an Internet shortcut to its WebViewLink.
+	// Otherwise such files are skipped.
+	Placeholders bool
+}
+
+// SyncResult summarizes the changes made by a synchronization.
+// Paths are relative to the synchronized directories, such as "/dist/app.js".
+type SyncResult struct {
+	// Created lists the files and directories created in the destination.
+	Created []Path
+
+	// Updated lists the files whose content has been replaced in the destination.
+	Updated []Path
+
+	// Deleted lists the files and directories removed from the destination because of SyncOptions.Delete.
+	// The contents of a removed directory are not listed.
+	Deleted []Path
+
+	// Skipped lists the files that are already up to date in the destination,
+	// and the files that are not synchronized because they cannot be represented in the destination.
+	Skipped []Path
+
+	// Moved lists the files and directories moved or renamed in the destination by Bisync.
+	Moved []SyncMove
+}
+
+// SyncMove is a move or rename of a file or directory from one path to another.
+type SyncMove struct {
+	From Path
+	To   Path
+}
+
+// SyncUp makes the Google Drive folder with the given remoteFolderID mirror the local directory localDir.
+// Files that do not exist in the folder are uploaded, and files whose size or content differs are overwritten.
+// A remote file is regarded as up to date if it has the same size and either the same modification time
+// or the same MD5 checksum as the local file; uploaded files are given the modification time of the local file.
+// Remote files and directories that do not exist locally are removed only if opts.Delete is true.
+// Symbolic links and other special files in localDir are ignored.
+// Returns a summary of the changes, which is partial if an error occurs.
+// Returns ErrNotFound if the remote folder does not exist.
+func (s *DriveFS) SyncUp(localDir string, remoteFolderID FileID, opts SyncOptions) (result SyncResult, err error) {
+	return s.SyncUpContext(context.Background(), localDir, remoteFolderID, opts)
+}
+
+// SyncUpContext is like SyncUp but uses ctx for all Google Drive API calls.
+func (s *DriveFS) SyncUpContext(ctx context.Context, localDir string, remoteFolderID FileID, opts SyncOptions) (result SyncResult, err error) {
+	if err := validatePatterns(opts); err != nil {
+		return SyncResult{}, err
+	}
+	stat, err := os.Stat(localDir)
+	if err != nil {
+		return SyncResult{}, newIOError("failed to stat local directory", err)
+	}
+	if !stat.IsDir() {
+		return SyncResult{}, newIOError("failed to sync", fmt.Errorf("'%s' is not a directory", localDir))
+	}
+	root, found, err := findByID(ctx, s.backend, string(remoteFolderID))
+	if err != nil {
+		return SyncResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found || root.MimeType != mimeTypeGoogleAppFolder {
+		return SyncResult{}, fmt.Errorf("directory '%s' not found: %w", remoteFolderID, ErrNotFound)
+	}
+
+	u := &syncUp{s: s, opts: opts, result: &result}
+	err = u.syncDir(ctx, localDir, root.Id, nil)
+	return result, err
+}
+
+type syncUp struct {
+	s      *DriveFS
+	opts   SyncOptions
+	result *SyncResult
+}
+
+// syncDir synchronizes the contents of the local directory localPath into the remote folder with the given remoteID.
+// remoteID is empty if the folder does not exist because it has not been created in a dry run.
+func (u *syncUp) syncDir(ctx context.Context, localPath, remoteID string, rel []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(localPath)
+	if err != nil {
+		return newIOError("failed to read local directory", err)
+	}
+	var remoteFiles []*drive.File
+	if remoteID != "" {
+		remoteFiles, err = findAllIn(ctx, u.s.backend, remoteID)
+		if err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+	}
+	remoteByName := map[string][]*drive.File{}
+	for _, f := range remoteFiles {
+		remoteByName[f.Name] = append(remoteByName[f.Name], f)
+	}
+
+	matched := map[string]bool{}
+	for _, entry := range entries {
+		name := entry.Name()
+		entryRel := append(append([]string{}, rel...), name)
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			// The remote counterparts of ignored entries are left as they are.
+			for _, f := range remoteByName[name] {
+				matched[f.Id] = true
+			}
+			continue
+		}
+		if isExcluded(u.opts, entryRel, entry.IsDir()) {
+			continue
+		}
+		var remote *drive.File
+		for _, f := range remoteByName[name] {
+			if isSyncCounterpart(f, entry.IsDir()) {
+				remote = f
+				break
+			}
+		}
+		if remote != nil {
+			matched[remote.Id] = true
+		}
+
+		localEntryPath := filepath.Join(localPath, name)
+		if entry.IsDir() {
+			err = u.syncSubdir(ctx, localEntryPath, remoteID, remote, entryRel)
+		} else {
+			err = u.syncFile(ctx, localEntryPath, remoteID, remote, entryRel)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range remoteFiles {
+		fileRel := append(append([]string{}, rel...), f.Name)
+		if matched[f.Id] || isExcluded(u.opts, fileRel, f.MimeType == mimeTypeGoogleAppFolder) {
+			continue
+		}
+		if err := u.deleteExtra(ctx, f, fileRel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *syncUp) syncSubdir(ctx context.Context, localPath, parentID string, remote *drive.File, rel []string) error {
+	remoteID := ""
+	if remote != nil {
+		remoteID = remote.Id
+	} else {
+		u.result.Created = append(u.result.Created, syncPath(rel))
+		if !u.opts.DryRun {
+			dir, err := createDirIn(ctx, u.s.backend, parentID, rel[len(rel)-1])
+			if err != nil {
+				return err
+			}
+			remoteID = dir.Id
+		}
+	}
+	return u.syncDir(ctx, localPath, remoteID, rel)
+}
+
+func (u *syncUp) syncFile(ctx context.Context, localPath, parentID string, remote *drive.File, rel []string) error {
+	stat, err := os.Stat(localPath)
+	if err != nil {
+		return newIOError("failed to stat local file", err)
+	}
+	if remote == nil {
+		u.result.Created = append(u.result.Created, syncPath(rel))
+	} else {
+		same, err := sameLocalContent(localPath, stat, remote)
+		if err != nil {
+			return err
+		}
+		if same {
+			u.result.Skipped = append(u.result.Skipped, syncPath(rel))
+			return nil
+		}
+		u.result.Updated = append(u.result.Updated, syncPath(rel))
+	}
+	if u.opts.DryRun {
+		return nil
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return newIOError("failed to open local file", err)
+	}
+	defer file.Close()
+	modifiedTime := stat.ModTime().UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+	if remote == nil {
+		_, err = u.s.backend.CreateFile(ctx, CreateFileRequest{
+			File:  &drive.File{Name: rel[len(rel)-1], Parents: []string{parentID}, ModifiedTime: modifiedTime},
+			Media: file,
+		})
+		if err != nil {
+			return newDriveError("failed to upload file", err)
+		}
+		return nil
+	}
+	_, err = u.s.backend.UpdateFile(ctx, UpdateFileRequest{
+		FileID: remote.Id,
+		File:   &drive.File{ModifiedTime: modifiedTime},
+		Media:  file,
+	})
+	if err != nil {
+		return newDriveError("failed to upload file", err)
+	}
+	return nil
+}
+
+func (u *syncUp) deleteExtra(ctx context.Context, f *drive.File, rel []string) error {
+	if !u.opts.Delete {
+		return nil
+	}
+	u.result.Deleted = append(u.result.Deleted, syncPath(rel))
+	if u.opts.DryRun {
+		return nil
+	}
+	return u.s.RemoveAllContext(ctx, FileID(f.Id), u.opts.MoveToTrash)
+}
+
+// isSyncCounterpart reports whether the remote file f can be synchronized with a local directory or regular file.
+func isSyncCounterpart(f *drive.File, dir bool) bool {
+	if dir {
+		return f.MimeType == mimeTypeGoogleAppFolder
+	}
+	return !strings.HasPrefix(f.MimeType, mimeTypePrefixGoogleApp)
+}
+
+// sameLocalContent reports whether the local file at localPath has the same content as the remote file.
+func sameLocalContent(localPath string, stat fs.FileInfo, remote *drive.File) (bool, error) {
+	if stat.Size() != remote.Size {
+		return false, nil
+	}
+	if sameModTime(remote.ModifiedTime, stat.ModTime()) {
+		return true, nil
+	}
+	if remote.Md5Checksum == "" {
+		return false, nil
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return false, newIOError("failed to open local file", err)
+	}
+	defer file.Close()
+	sum, err := md5Checksum(file)
+	if err != nil {
+		return false, newIOError("failed to read local file", err)
+	}
+	return sum == remote.Md5Checksum, nil
+}
+
+// sameModTime reports whether the modification time of a remote file in RFC 3339 format
+// equals the modification time of a local file in the millisecond precision of Google Drive.
+func sameModTime(remote string, local time.Time) bool {
+	modTime, err := time.Parse(time.RFC3339, remote)
+	return err == nil && modTime.Truncate(time.Millisecond).Equal(local.Truncate(time.Millisecond))
+}
+
+func md5Checksum(r io.Reader) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func validatePatterns(opts SyncOptions) error {
+	for _, pattern := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", pattern, ErrInvalidPath)
+		}
+	}
+	return nil
+}
+
+// isExcluded reports whether the file or directory at the relative path rel is out of the scope of the synchronization.
+func isExcluded(opts SyncOptions, rel []string, dir bool) bool {
+	p := strings.Join(rel, "/")
+	if matchesAny(opts.Exclude, p) {
+		return true
+	}
+	return !dir && len(opts.Include) > 0 && !matchesAny(opts.Include, p)
+}
+
+func matchesAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(p)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func syncPath(rel []string) Path {
+	return Path("/" + strings.Join(rel, "/"))
+}
+
+// SyncDown makes the local directory localDir mirror the Google Drive folder with the given remoteFolderID,
+// creating localDir if it does not exist.
+// Files that do not exist locally are downloaded, and files whose size or content differs are overwritten.
+// A local file is regarded as up to date if it has the same size and either the same modification time
+// or the same MD5 checksum as the remote file; downloaded files are given the modification time of the remote file.
+// Google Apps files are exported according to opts.ExportFormats or represented by placeholders if opts.Placeholders is true.
+// Local files and directories that do not exist in the folder are removed only if opts.Delete is true.
+// Shortcuts are ignored.
+//
+// Since a local directory cannot contain duplicate names, siblings with the same name are ordered by their IDs,
+// and all but the first are given a numbered suffix before the extension, such as "notes (1).txt".
+// Returns a summary of the changes, which is partial if an error occurs.
+// Returns ErrNotFound if the remote folder does not exist,
+// and ErrNotExportable if a Google Apps file cannot be exported to the format specified by opts.ExportFormats.
+func (s *DriveFS) SyncDown(remoteFolderID FileID, localDir string, opts SyncOptions) (result SyncResult, err error) {
+	return s.SyncDownContext(context.Background(), remoteFolderID, localDir, opts)
+}
+
+// SyncDownContext is like SyncDown but uses ctx for all Google Drive API calls.
+func (s *DriveFS) SyncDownContext(ctx context.Context, remoteFolderID FileID, localDir string, opts SyncOptions) (result SyncResult, err error) {
+	if err := validatePatterns(opts); err != nil {
+		return SyncResult{}, err
+	}
+	root, found, err := findByID(ctx, s.backend, string(remoteFolderID))
+	if err != nil {
+		return SyncResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found || root.MimeType != mimeTypeGoogleAppFolder {
+		return SyncResult{}, fmt.Errorf("directory '%s' not found: %w", remoteFolderID, ErrNotFound)
+	}
+	if !opts.DryRun {
+		if err := os.MkdirAll(localDir, 0o755); err != nil {
+			return SyncResult{}, newIOError("failed to create local directory", err)
+		}
+	}
+
+	d := &syncDown{s: s, opts: opts, result: &result}
+	err = d.syncDir(ctx, root.Id, localDir, nil)
+	return result, err
+}
+
+type syncDown struct {
+	s      *DriveFS
+	opts   SyncOptions
+	result *SyncResult
+}
+
+// syncDownEntry is a remote file to be synchronized with the local file of the given name.
+type syncDownEntry struct {
+	file *drive.File
+	name string
+	// export is the MIME type to which a Google Apps file is exported, or empty for a placeholder.
+	export string
+}
+
+// syncDir synchronizes the contents of the remote folder with the given remoteID into the local directory localPath.
+// localPath may not exist in a dry run.
+func (d *syncDown) syncDir(ctx context.Context, remoteID, localPath string, rel []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	remoteFiles, err := findAllIn(ctx, d.s.backend, remoteID)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+	localEntries, err := os.ReadDir(localPath)
+	if err != nil && !(d.opts.DryRun && errors.Is(err, fs.ErrNotExist)) {
+		return newIOError("failed to read local directory", err)
+	}
+
+	matched := map[string]bool{}
+	for _, entry := range d.entries(remoteFiles) {
+		entryRel := append(append([]string{}, rel...), entry.name)
+		isDir := entry.file.MimeType == mimeTypeGoogleAppFolder
+		if isExcluded(d.opts, entryRel, isDir) {
+			continue
+		}
+		matched[entry.name] = true
+
+		localEntryPath := filepath.Join(localPath, entry.name)
+		switch {
+		case isDir:
+			err = d.syncSubdir(ctx, entry.file, localEntryPath, entryRel)
+		case !strings.HasPrefix(entry.file.MimeType, mimeTypePrefixGoogleApp):
+			err = d.syncFile(ctx, entry.file, localEntryPath, entryRel)
+		case entry.export != "":
+			err = d.syncExport(ctx, entry.file, entry.export, localEntryPath, entryRel)
+		default:
+			err = d.syncPlaceholder(entry.file, localEntryPath, entryRel)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range localEntries {
+		entryRel := append(append([]string{}, rel...), entry.Name())
+		if matched[entry.Name()] || isExcluded(d.opts, entryRel, entry.IsDir()) || !d.opts.Delete {
+			continue
+		}
+		d.result.Deleted = append(d.result.Deleted, syncPath(entryRel))
+		if !d.opts.DryRun {
+			if err := os.RemoveAll(filepath.Join(localPath, entry.Name())); err != nil {
+				return newIOError("failed to delete local file", err)
+			}
+		}
+	}
+	return nil
+}
+
+// entries returns the remote files to be synchronized with their local names, which are unique in the directory.
+func (d *syncDown) entries(files []*drive.File) []syncDownEntry {
+	files = slices.Clone(files)
+	slices.SortStableFunc(files, func(a, b *drive.File) int { return strings.Compare(a.Id, b.Id) })
+
+	var entries []syncDownEntry
+	used := map[string]bool{}
+	for _, f := range files {
+		entry := syncDownEntry{file: f, name: strings.ReplaceAll(f.Name, "/", "_")}
+		switch {
+		case f.MimeType == mimeTypeGoogleAppFolder:
+		case f.MimeType == mimeTypeGoogleAppShortcut:
+			continue
+		case strings.HasPrefix(f.MimeType, mimeTypePrefixGoogleApp):
+			if format := d.opts.ExportFormats[f.MimeType]; format != "" {
+				entry.export = format
+				entry.name = withExtension(entry.name, exportExtensions[format])
+			} else if d.opts.Placeholders {
+				entry.name += ".url"
+			} else {
+				continue
+			}
+		}
+
+		ext := path.Ext(entry.name)
+		if f.MimeType == mimeTypeGoogleAppFolder || ext == entry.name {
+			ext = ""
+		}
+		base := strings.TrimSuffix(entry.name, ext)
+		for i := 1; used[entry.name]; i++ {
+			entry.name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		used[entry.name] = true
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func withExtension(name, ext string) string {
+	if ext == "" || strings.EqualFold(path.Ext(name), ext) {
+		return name
+	}
+	return name + ext
+}
+
+func (d *syncDown) syncSubdir(ctx context.Context, remote *drive.File, localPath string, rel []string) error {
+	stat, err := os.Lstat(localPath)
+	switch {
+	case err == nil && stat.IsDir():
+	case err == nil:
+		if !d.opts.Delete {
+			d.result.Skipped = append(d.result.Skipped, syncPath(rel))
+			return nil
+		}
+		// The local file is replaced by the directory.
+		d.result.Created = append(d.result.Created, syncPath(rel))
+		if !d.opts.DryRun {
+			if err := os.Remove(localPath); err != nil {
+				return newIOError("failed to delete local file", err)
+			}
+			if err := os.Mkdir(localPath, 0o755); err != nil {
+				return newIOError("failed to create local directory", err)
+			}
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		d.result.Created = append(d.result.Created, syncPath(rel))
+		if !d.opts.DryRun {
+			if err := os.Mkdir(localPath, 0o755); err != nil {
+				return newIOError("failed to create local directory", err)
+			}
+		}
+	default:
+		return newIOError("failed to stat local directory", err)
+	}
+	return d.syncDir(ctx, remote.Id, localPath, rel)
+}
+
+// prepareFile records the synchronization of the local file at localPath and reports whether it has to be written.
+// same reports whether the existing local file is up to date.
+func (d *syncDown) prepareFile(localPath string, rel []string, same func(stat fs.FileInfo) (bool, error)) (write bool, err error) {
+	stat, err := os.Lstat(localPath)
+	switch {
+	case err == nil && stat.Mode().IsRegular():
+		same, err := same(stat)
+		if err != nil {
+			return false, err
+		}
+		if same {
+			d.result.Skipped = append(d.result.Skipped, syncPath(rel))
+			return false, nil
+		}
+		d.result.Updated = append(d.result.Updated, syncPath(rel))
+	case err == nil:
+		if !d.opts.Delete {
+			d.result.Skipped = append(d.result.Skipped, syncPath(rel))
+			return false, nil
+		}
+		// The local directory or special file is replaced by the file.
+		d.result.Updated = append(d.result.Updated, syncPath(rel))
+		if !d.opts.DryRun {
+			if err := os.RemoveAll(localPath); err != nil {
+				return false, newIOError("failed to delete local file", err)
+			}
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		d.result.Created = append(d.result.Created, syncPath(rel))
+	default:
+		return false, newIOError("failed to stat local file", err)
+	}
+	return !d.opts.DryRun, nil
+}
+
+func (d *syncDown) syncFile(ctx context.Context, remote *drive.File, localPath string, rel []string) error {
+	write, err := d.prepareFile(localPath, rel, func(stat fs.FileInfo) (bool, error) {
+		return sameLocalContent(localPath, stat, remote)
+	})
+	if err != nil || !write {
+		return err
+	}
+	body, err := d.s.backend.DownloadFile(ctx, DownloadFileRequest{FileID: remote.Id, Length: -1})
+	if err != nil {
+		return newDriveError("failed to download file", err)
+	}
+	defer body.Close()
+	return writeLocalFile(localPath, body, remote.ModifiedTime)
+}
+
+func (d *syncDown) syncExport(ctx context.Context, remote *drive.File, mimeType, localPath string, rel []string) error {
+	if !slices.Contains(exportFormatsOf(remote.ExportLinks, remote.MimeType), mimeType) {
+		return fmt.Errorf("cannot export '%s' of type '%s' to '%s': %w", remote.Id, remote.MimeType, mimeType, ErrNotExportable)
+	}
+	write, err := d.prepareFile(localPath, rel, func(stat fs.FileInfo) (bool, error) {
+		// Exported files have neither a size nor a checksum to be compared.
+		return sameModTime(remote.ModifiedTime, stat.ModTime()), nil
+	})
+	if err != nil || !write {
+		return err
+	}
+	body, err := d.s.backend.ExportFile(ctx, remote.Id, mimeType)
+	if err != nil {
+		return newDriveError("failed to export file", err)
+	}
+	defer body.Close()
+	return writeLocalFile(localPath, body, remote.ModifiedTime)
+}
+
+func (d *syncDown) syncPlaceholder(remote *drive.File, localPath string, rel []string) error {
+	content := []byte(fmt.Sprintf("[InternetShortcut]\r\nURL=%s\r\n", remote.WebViewLink))
+	write, err := d.prepareFile(localPath, rel, func(stat fs.FileInfo) (bool, error) {
+		data, err := os.ReadFile(localPath)
+		if err != nil {
+			return false, newIOError("failed to read local file", err)
+		}
+		return bytes.Equal(data, content), nil
+	})
+	if err != nil || !write {
+		return err
+	}
+	return writeLocalFile(localPath, bytes.NewReader(content), remote.ModifiedTime)
+}
+
+// writeLocalFile replaces the local file at localPath with the data read from r
+// and sets its modification time to modifiedTime in RFC 3339 format, if valid.
+// The data is written to a temporary file that is renamed to localPath, so the local file is never left incomplete.
+func writeLocalFile(localPath string, r io.Reader, modifiedTime string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), ".drivefs-*")
+	if err != nil {
+		return newIOError("failed to create local file", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return newIOError("failed to write local file", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return newIOError("failed to write local file", err)
+	}
+	if modTime, err := time.Parse(time.RFC3339, modifiedTime); err == nil {
+		if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+			return newIOError("failed to set modification time of local file", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), localPath); err != nil {
+		return newIOError("failed to write local file", err)
+	}
+	return nil
+}
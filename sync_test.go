@@ -0,0 +1,351 @@
+package drivefs_test
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func writeLocalFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+}
+
+func remoteTree(t *testing.T, s *drivefs.DriveFS, rootID drivefs.FileID) map[drivefs.Path]string {
+	t.Helper()
+	tree := map[drivefs.Path]string{}
+	err := s.Walk(rootID, func(p drivefs.Path, info drivefs.FileInfo) error {
+		if p == "/" {
+			return nil
+		}
+		if info.IsFolder() {
+			tree[p+"/"] = ""
+			return nil
+		}
+		data, err := s.ReadFile(info.ID)
+		tree[p] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	return tree
+}
+
+func assertPaths(t *testing.T, name string, got []drivefs.Path, want ...drivefs.Path) {
+	t.Helper()
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestDriveFS_SyncUp(t *testing.T) {
+	t.Run("create update delete", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		local := t.TempDir()
+		writeLocalFiles(t, local, map[string]string{"a.txt": "a", "dir/b.txt": "b", "dir/sub/c.txt": "c"})
+
+		result, err := s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{})
+		if err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created, "/a.txt", "/dir", "/dir/b.txt", "/dir/sub", "/dir/sub/c.txt")
+
+		result, err = s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{})
+		if err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created)
+		assertPaths(t, "Skipped", result.Skipped, "/a.txt", "/dir/b.txt", "/dir/sub/c.txt")
+
+		writeLocalFiles(t, local, map[string]string{"a.txt": "changed"})
+		if err := os.RemoveAll(filepath.Join(local, "dir", "sub")); err != nil {
+			t.Fatalf("RemoveAll() error = %v", err)
+		}
+		result, err = s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{Delete: true})
+		if err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		assertPaths(t, "Updated", result.Updated, "/a.txt")
+		assertPaths(t, "Deleted", result.Deleted, "/dir/sub")
+		assertPaths(t, "Skipped", result.Skipped, "/dir/b.txt")
+
+		want := map[drivefs.Path]string{"/a.txt": "changed", "/dir/": "", "/dir/b.txt": "b"}
+		if got := remoteTree(t, s, mem.RootID()); !maps.Equal(got, want) {
+			t.Fatalf("remote tree = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("same content with different modification time", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		local := t.TempDir()
+		writeLocalFiles(t, local, map[string]string{"a.txt": "a"})
+		if _, err := s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{}); err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(filepath.Join(local, "a.txt"), later, later); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+
+		result, err := s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{})
+		if err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		assertPaths(t, "Skipped", result.Skipped, "/a.txt")
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		if _, err := s.MkdirAll(mem.RootID(), "/extra"); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		local := t.TempDir()
+		writeLocalFiles(t, local, map[string]string{"a.txt": "a", "dir/b.txt": "b"})
+
+		result, err := s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{DryRun: true, Delete: true})
+		if err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created, "/a.txt", "/dir", "/dir/b.txt")
+		assertPaths(t, "Deleted", result.Deleted, "/extra")
+
+		want := map[drivefs.Path]string{"/extra/": ""}
+		if got := remoteTree(t, s, mem.RootID()); !maps.Equal(got, want) {
+			t.Fatalf("remote tree = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("include and exclude", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		if _, err := s.MkdirAll(mem.RootID(), "/node_modules"); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		local := t.TempDir()
+		writeLocalFiles(t, local, map[string]string{
+			"app.js":            "app",
+			"app.js.map":        "map",
+			"lib/util.js":       "util",
+			"lib/test/x.js":     "test",
+			"node_modules/m.js": "m",
+		})
+
+		result, err := s.SyncUp(local, mem.RootID(), drivefs.SyncOptions{
+			Include: []string{"*.js"},
+			Exclude: []string{"node_modules", "lib/test"},
+			Delete:  true,
+		})
+		if err != nil {
+			t.Fatalf("SyncUp() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created, "/app.js", "/lib", "/lib/util.js")
+		assertPaths(t, "Deleted", result.Deleted)
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		if _, err := s.SyncUp(t.TempDir(), mem.RootID(), drivefs.SyncOptions{Include: []string{"["}}); err == nil {
+			t.Fatalf("SyncUp() error = nil, want an error")
+		}
+	})
+}
+
+func localTree(t *testing.T, dir string) map[drivefs.Path]string {
+	t.Helper()
+	tree := map[drivefs.Path]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		key := drivefs.Path("/" + filepath.ToSlash(rel))
+		if d.IsDir() {
+			tree[key+"/"] = ""
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		tree[key] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+	return tree
+}
+
+func TestDriveFS_SyncDown(t *testing.T) {
+	newRemote := func(t *testing.T) (*drivefsmem.Drive, *drivefs.DriveFS) {
+		t.Helper()
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		dir, err := s.MkdirAll(mem.RootID(), "/dir")
+		if err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		for _, f := range []struct {
+			parent  drivefs.FileID
+			name    string
+			content string
+		}{
+			{mem.RootID(), "a.txt", "a"},
+			{dir.ID, "b.txt", "b"},
+			{dir.ID, "b.txt", "duplicate"},
+		} {
+			info, err := s.Create(f.parent, f.name)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			if err := s.WriteFile(info.ID, []byte(f.content)); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+		}
+		return mem, s
+	}
+
+	t.Run("download and skip", func(t *testing.T) {
+		mem, s := newRemote(t)
+		local := filepath.Join(t.TempDir(), "local")
+
+		result, err := s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{})
+		if err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created, "/a.txt", "/dir", "/dir/b.txt", "/dir/b (1).txt")
+		want := map[drivefs.Path]string{"/a.txt": "a", "/dir/": "", "/dir/b.txt": "b", "/dir/b (1).txt": "duplicate"}
+		if got := localTree(t, local); !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+
+		children, err := s.ReadDir(mem.RootID())
+		if err != nil {
+			t.Fatalf("ReadDir() error = %v", err)
+		}
+		for _, child := range children {
+			if child.Name != "a.txt" {
+				continue
+			}
+			stat, err := os.Stat(filepath.Join(local, "a.txt"))
+			if err != nil {
+				t.Fatalf("Stat() error = %v", err)
+			}
+			if !stat.ModTime().Equal(child.ModTime) {
+				t.Fatalf("local ModTime = %v, want %v", stat.ModTime(), child.ModTime)
+			}
+		}
+
+		result, err = s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{})
+		if err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created)
+		assertPaths(t, "Updated", result.Updated)
+		assertPaths(t, "Skipped", result.Skipped, "/a.txt", "/dir/b.txt", "/dir/b (1).txt")
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		mem, s := newRemote(t)
+		local := t.TempDir()
+		if _, err := s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{}); err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		writeLocalFiles(t, local, map[string]string{"a.txt": "modified", "extra/c.txt": "c", "keep.log": "log"})
+
+		result, err := s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{Delete: true, Exclude: []string{"*.log"}})
+		if err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		assertPaths(t, "Updated", result.Updated, "/a.txt")
+		assertPaths(t, "Deleted", result.Deleted, "/extra")
+		want := map[drivefs.Path]string{"/a.txt": "a", "/dir/": "", "/dir/b.txt": "b", "/dir/b (1).txt": "duplicate", "/keep.log": "log"}
+		if got := localTree(t, local); !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		mem, s := newRemote(t)
+		local := filepath.Join(t.TempDir(), "local")
+
+		result, err := s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{DryRun: true})
+		if err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created, "/a.txt", "/dir", "/dir/b.txt", "/dir/b (1).txt")
+		if _, err := os.Stat(local); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("Stat() error = %v, want fs.ErrNotExist", err)
+		}
+	})
+
+	t.Run("Google Apps files", func(t *testing.T) {
+		mem := drivefsmem.New(drivefsmem.Options{})
+		s := drivefs.NewWithBackend(mem)
+		sheet, err := s.Import(mem.RootID(), "table.csv", strings.NewReader("a,b\n"), drivefs.ImportOptions{})
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		doc, err := s.Import(mem.RootID(), "notes.md", strings.NewReader("# notes\n"), drivefs.ImportOptions{})
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		local := t.TempDir()
+
+		result, err := s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{
+			ExportFormats: map[string]string{sheet.Mime: "text/csv"},
+			Placeholders:  true,
+		})
+		if err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		assertPaths(t, "Created", result.Created, "/table.csv", "/notes.md.url")
+		want := map[drivefs.Path]string{
+			"/table.csv":    "a,b\n",
+			"/notes.md.url": "[InternetShortcut]\r\nURL=" + doc.WebViewLink + "\r\n",
+		}
+		if got := localTree(t, local); !maps.Equal(got, want) {
+			t.Fatalf("local tree = %v, want %v", got, want)
+		}
+
+		result, err = s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{
+			ExportFormats: map[string]string{sheet.Mime: "text/csv"},
+			Placeholders:  true,
+		})
+		if err != nil {
+			t.Fatalf("SyncDown() error = %v", err)
+		}
+		assertPaths(t, "Skipped", result.Skipped, "/table.csv", "/notes.md.url")
+
+		_, err = s.SyncDown(mem.RootID(), local, drivefs.SyncOptions{
+			ExportFormats: map[string]string{sheet.Mime: "text/markdown"},
+		})
+		if !errors.Is(err, drivefs.ErrNotExportable) {
+			t.Fatalf("SyncDown() error = %v, want ErrNotExportable", err)
+		}
+	})
+}
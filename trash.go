@@ -0,0 +1,135 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// TrashOptions configures which trashed items are listed by ListTrash.
+type TrashOptions struct {
+	// ParentID, if not empty, restricts the items to those trashed from the directory with the given ID.
+	ParentID FileID
+
+	// DriveID, if not empty, restricts the items to those in the shared drive with the given ID.
+	DriveID string
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	// FallbackParentID, if not empty, is the ID of the directory into which the item is restored
+	// when none of its original parents is available because they have been deleted or are also in the trash.
+	FallbackParentID FileID
+}
+
+// RestoreResult is the result of Restore.
+type RestoreResult struct {
+	// File is the FileInfo of the restored item.
+	File FileInfo
+
+	// OriginalParentExists is true if one of the original parents of the item exists and is not in the trash.
+	OriginalParentExists bool
+
+	// Relocated is true if the item has been restored into RestoreOptions.FallbackParentID.
+	Relocated bool
+}
+
+// ListTrash lists the items in the trash, which are the files and directories that have been moved to the trash
+// explicitly rather than together with a trashed parent.
+// The descendants of trashed directories can be listed with ReadDirWithOptions or WalkWithOptions.
+func (s *DriveFS) ListTrash(opts TrashOptions) (items []FileInfo, err error) {
+	return s.ListTrashContext(context.Background(), opts)
+}
+
+// ListTrashContext is like ListTrash but uses ctx for all Google Drive API calls.
+func (s *DriveFS) ListTrashContext(ctx context.Context, opts TrashOptions) (items []FileInfo, err error) {
+	q := Trashed(true)
+	if opts.ParentID != "" {
+		q = And(InParents(opts.ParentID), q)
+	}
+	var pageToken string
+	for {
+		res, err := s.backend.ListFiles(ctx, ListFilesRequest{Query: q.String(), PageToken: pageToken, DriveID: opts.DriveID})
+		if err != nil {
+			return nil, newDriveError("failed to list trashed files", err)
+		}
+		for _, f := range res.Files {
+			if !f.ExplicitlyTrashed {
+				continue
+			}
+			info, err := newFileInfo(f)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create FileInfo: %w", err)
+			}
+			items = append(items, info)
+		}
+		if res.NextPageToken == "" {
+			return items, nil
+		}
+		pageToken = res.NextPageToken
+	}
+}
+
+// Restore restores the file or directory with the given fileID from the trash, together with its descendants,
+// and reports whether one of its original parents is still available.
+// If none is and opts.FallbackParentID is not empty, the item is restored into that directory instead;
+// otherwise an item in a trashed directory stays in the trash with it, which RestoreResult.File.Trashed reports.
+// Returns ErrNotFound if the file does not exist.
+func (s *DriveFS) Restore(fileID FileID, opts RestoreOptions) (result RestoreResult, err error) {
+	return s.RestoreContext(context.Background(), fileID, opts)
+}
+
+// RestoreContext is like Restore but uses ctx for all Google Drive API calls.
+func (s *DriveFS) RestoreContext(ctx context.Context, fileID FileID, opts RestoreOptions) (result RestoreResult, err error) {
+	file, found, err := findByID(ctx, s.backend, string(fileID))
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return RestoreResult{}, fmt.Errorf("file not found: %s: %w", fileID, ErrNotFound)
+	}
+	for _, parentID := range file.Parents {
+		parent, found, err := findByID(ctx, s.backend, parentID)
+		if err != nil {
+			return RestoreResult{}, fmt.Errorf("failed to find parent: %w", err)
+		}
+		if found && !parent.Trashed {
+			result.OriginalParentExists = true
+			break
+		}
+	}
+
+	req := UpdateFileRequest{
+		FileID: string(fileID),
+		File:   &drive.File{Trashed: false, ForceSendFields: []string{"Trashed"}},
+	}
+	if !result.OriginalParentExists && opts.FallbackParentID != "" {
+		req.AddParents = []string{string(opts.FallbackParentID)}
+		req.RemoveParents = file.Parents
+		result.Relocated = true
+	}
+	file, err = s.backend.UpdateFile(ctx, req)
+	if err != nil {
+		return RestoreResult{}, newDriveError("failed to restore file", err)
+	}
+	result.File, err = newFileInfo(file)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("failed to create FileInfo: %w", err)
+	}
+	return result, nil
+}
+
+// EmptyTrash permanently deletes all items in the trash of My Drive if driveID is empty,
+// or in the trash of the shared drive with the given driveID otherwise.
+func (s *DriveFS) EmptyTrash(driveID string) (err error) {
+	return s.EmptyTrashContext(context.Background(), driveID)
+}
+
+// EmptyTrashContext is like EmptyTrash but uses ctx for all Google Drive API calls.
+func (s *DriveFS) EmptyTrashContext(ctx context.Context, driveID string) (err error) {
+	if err := s.backend.EmptyTrash(ctx, driveID); err != nil {
+		return newDriveError("failed to empty trash", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package drivefs_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+)
+
+func trashNames(t *testing.T, s *drivefs.DriveFS, opts drivefs.TrashOptions) []string {
+	t.Helper()
+	items, err := s.ListTrash(opts)
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	var names []string
+	for _, item := range items {
+		if !item.Trashed {
+			t.Fatalf("ListTrash() returned %+v, want trashed items", item)
+		}
+		names = append(names, item.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestDriveFS_Trash(t *testing.T) {
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	dir, err := s.MkdirAll(mem.RootID(), "/dir/sub")
+	if err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	a := writeRemoteFile(t, s, mem.RootID(), "a.txt", "a")
+	b := writeRemoteFile(t, s, dir.ID, "b.txt", "b")
+	parent := findRemote(t, s, mem.RootID(), "/dir")
+	for _, id := range []drivefs.FileID{a.ID, b.ID, parent.ID} {
+		if err := s.RemoveAll(id, true); err != nil {
+			t.Fatalf("RemoveAll() error = %v", err)
+		}
+	}
+
+	if got, want := trashNames(t, s, drivefs.TrashOptions{}), []string{"a.txt", "b.txt", "dir"}; !slices.Equal(got, want) {
+		t.Fatalf("ListTrash() = %v, want %v", got, want)
+	}
+	if got, want := trashNames(t, s, drivefs.TrashOptions{ParentID: mem.RootID()}), []string{"a.txt", "dir"}; !slices.Equal(got, want) {
+		t.Fatalf("ListTrash() in root = %v, want %v", got, want)
+	}
+	if children, err := s.ReadDirWithOptions(parent.ID, drivefs.ListOptions{IncludeTrashed: true}); err != nil || len(children) != 1 || !children[0].Trashed {
+		t.Fatalf("ReadDirWithOptions() = %+v, %v, want the trashed sub directory", children, err)
+	}
+	var walked []drivefs.Path
+	err = s.WalkWithOptions(parent.ID, drivefs.ListOptions{IncludeTrashed: true}, func(p drivefs.Path, _ drivefs.FileInfo) error {
+		walked = append(walked, p)
+		return nil
+	})
+	if want := []drivefs.Path{"/", "/sub", "/sub/b.txt"}; err != nil || !slices.Equal(walked, want) {
+		t.Fatalf("WalkWithOptions() visited %v, %v, want %v", walked, err, want)
+	}
+
+	// b.txt is in the trashed sub directory, so it is relocated to the fallback directory.
+	result, err := s.Restore(b.ID, drivefs.RestoreOptions{FallbackParentID: mem.RootID()})
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if result.OriginalParentExists || !result.Relocated || result.File.Trashed {
+		t.Fatalf("Restore() = %+v, want the file relocated out of the trash", result)
+	}
+	if found := findRemote(t, s, mem.RootID(), "/b.txt"); found.ID != b.ID {
+		t.Fatalf("restored file = %+v, want %s", found, b.ID)
+	}
+
+	result, err = s.Restore(parent.ID, drivefs.RestoreOptions{FallbackParentID: mem.RootID()})
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !result.OriginalParentExists || result.Relocated || result.File.Trashed {
+		t.Fatalf("Restore() = %+v, want the directory restored in place", result)
+	}
+	findRemote(t, s, mem.RootID(), "/dir/sub")
+
+	if err := s.EmptyTrash(""); err != nil {
+		t.Fatalf("EmptyTrash() error = %v", err)
+	}
+	if got := trashNames(t, s, drivefs.TrashOptions{}); len(got) != 0 {
+		t.Fatalf("ListTrash() after EmptyTrash() = %v, want none", got)
+	}
+	if _, err := s.Info(a.ID); err == nil {
+		t.Fatalf("Info() of a file deleted from the trash succeeded")
+	}
+	if _, err := s.Restore(a.ID, drivefs.RestoreOptions{}); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("Restore() of a deleted file error = %v, want ErrNotFound", err)
+	}
+}
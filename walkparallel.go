@@ -0,0 +1,154 @@
+package drivefs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"slices"
+	"sync"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// WalkOptions configures WalkParallel.
+type WalkOptions struct {
+	// ListOptions selects the files that are visited, as for WalkWithOptions.
+	ListOptions
+
+	// Concurrency is the maximum number of directories listed at the same time. Zero means 4.
+	Concurrency int
+}
+
+// WalkParallel is like WalkWithOptions but lists up to opts.Concurrency directories at the same time.
+//
+// The calls of f are serialized: f is never called concurrently and is called from the goroutine of the caller.
+// A directory is visited before its contents, and the contents of a directory are visited one after another
+// in the order in which they are listed, but the contents of different directories may be visited in any order.
+//
+// If f returns fs.SkipDir for a directory, its contents are not listed nor visited.
+// If f returns fs.SkipDir for a file, the remaining contents of its directory are skipped.
+// If f returns fs.SkipAll, or fs.SkipDir for the root, walking stops and WalkParallel returns nil.
+// If f returns any other error, or listing a directory fails, walking stops,
+// the listings in progress are canceled and the first error is returned.
+func (s *DriveFS) WalkParallel(rootID FileID, opts WalkOptions, f func(Path, FileInfo) error) (err error) {
+	return s.WalkParallelContext(context.Background(), rootID, opts, f)
+}
+
+// WalkParallelContext is like WalkParallel but uses ctx for all Google Drive API calls.
+// Walking stops with the context's error once ctx is done.
+func (s *DriveFS) WalkParallelContext(ctx context.Context, rootID FileID, opts WalkOptions, f func(Path, FileInfo) error) (err error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	root, found, err := findByID(ctx, s.backend, string(rootID))
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("file not found: %s: %w", rootID, ErrNotFound)
+	}
+
+	w := &parallelWalker{s: s, opts: opts, results: make(chan dirListing, opts.Concurrency)}
+	ctx, cancel := context.WithCancel(ctx)
+	defer w.wg.Wait()
+	defer cancel()
+	return w.run(ctx, root, f)
+}
+
+// walkDir is a directory at rel to be listed.
+type walkDir struct {
+	rel []string
+	id  string
+}
+
+// dirListing is the result of listing the directory at rel.
+type dirListing struct {
+	rel      []string
+	children []*drive.File
+	err      error
+}
+
+type parallelWalker struct {
+	s    *DriveFS
+	opts WalkOptions
+	wg   sync.WaitGroup
+	// results receives the listings of the directories, which never blocks
+	// since at most opts.Concurrency directories are listed at the same time.
+	results chan dirListing
+}
+
+// run visits root and its descendants, listing the directories in background goroutines.
+func (w *parallelWalker) run(ctx context.Context, root *drive.File, f func(Path, FileInfo) error) error {
+	// queue holds the directories to be listed.
+	var queue []walkDir
+	enqueue, err := w.visit(root, nil, f)
+	switch {
+	case err == fs.SkipDir || err == fs.SkipAll:
+		return nil
+	case err != nil:
+		return err
+	case enqueue:
+		queue = append(queue, walkDir{rel: nil, id: root.Id})
+	}
+
+	running := 0
+	for len(queue) > 0 || running > 0 {
+		for ; running < w.opts.Concurrency && len(queue) > 0; running++ {
+			dir := queue[0]
+			queue = queue[1:]
+			w.list(ctx, dir)
+		}
+		var l dirListing
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case l = <-w.results:
+			running--
+		}
+		if l.err != nil {
+			return fmt.Errorf("failed to list files: %w", l.err)
+		}
+		for _, child := range l.children {
+			rel := append(slices.Clone(l.rel), child.Name)
+			enqueue, err := w.visit(child, rel, f)
+			if err == fs.SkipDir {
+				if child.MimeType == mimeTypeGoogleAppFolder {
+					continue
+				}
+				break
+			}
+			if err == fs.SkipAll {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if enqueue {
+				queue = append(queue, walkDir{rel: rel, id: child.Id})
+			}
+		}
+	}
+	return nil
+}
+
+// visit calls f with file at rel and reports whether file is a directory whose contents are to be listed.
+func (w *parallelWalker) visit(file *drive.File, rel []string, f func(Path, FileInfo) error) (isDir bool, err error) {
+	info, err := newFileInfo(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to create FileInfo: %w", err)
+	}
+	if err := f(syncPath(rel), info); err != nil {
+		return false, err
+	}
+	return file.MimeType == mimeTypeGoogleAppFolder, nil
+}
+
+// list lists the children of dir in a background goroutine and sends them to w.results.
+func (w *parallelWalker) list(ctx context.Context, dir walkDir) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		children, err := listChildren(ctx, w.s.backend, dir.id, w.opts.ListOptions)
+		w.results <- dirListing{rel: dir.rel, children: children, err: err}
+	}()
+}
@@ -0,0 +1,215 @@
+package drivefs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jumpaku/go-drivefs"
+	"github.com/Jumpaku/go-drivefs/drivefsmem"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// slowListBackend delays listing files, records the largest number of listings in progress,
+// and fails to list the directory with the given failID.
+type slowListBackend struct {
+	drivefs.Backend
+	failID string
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (b *slowListBackend) ListFiles(ctx context.Context, req drivefs.ListFilesRequest) (*drive.FileList, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.maxInFlight = max(b.maxInFlight, b.inFlight)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Millisecond):
+	}
+	if b.failID != "" && strings.Contains(req.Query, b.failID) {
+		return nil, &googleapi.Error{Code: 500, Message: "list failed"}
+	}
+	return b.Backend.ListFiles(ctx, req)
+}
+
+// newWalkTree creates "/tree" containing "dir0" to "dir7", each containing "sub/file.txt" and "file.txt".
+func newWalkTree(t *testing.T) (*drivefsmem.Drive, drivefs.FileID) {
+	t.Helper()
+	mem := drivefsmem.New(drivefsmem.Options{})
+	s := drivefs.NewWithBackend(mem)
+	for i := range 8 {
+		sub, err := s.MkdirAll(mem.RootID(), drivefs.Path(fmt.Sprintf("/tree/dir%d/sub", i)))
+		if err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		writeRemoteFile(t, s, sub.ID, "file.txt", "sub")
+		writeRemoteFile(t, s, findRemote(t, s, mem.RootID(), drivefs.Path(fmt.Sprintf("/tree/dir%d", i))).ID, "file.txt", "dir")
+	}
+	return mem, findRemote(t, s, mem.RootID(), "/tree").ID
+}
+
+func TestDriveFS_WalkParallel(t *testing.T) {
+	mem, rootID := newWalkTree(t)
+	b := &slowListBackend{Backend: mem}
+	s := drivefs.NewWithBackend(b)
+
+	var want []drivefs.Path
+	if err := s.Walk(rootID, func(p drivefs.Path, _ drivefs.FileInfo) error {
+		want = append(want, p)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	b.maxInFlight = 0
+
+	var got []drivefs.Path
+	var calling bool
+	err := s.WalkParallel(rootID, drivefs.WalkOptions{Concurrency: 4}, func(p drivefs.Path, info drivefs.FileInfo) error {
+		if calling {
+			t.Errorf("f called concurrently for %s", p)
+		}
+		calling = true
+		defer func() { calling = false }()
+		if parent := drivefs.Path(path.Dir(string(p))); p != "/" && !slices.Contains(got, parent) {
+			t.Errorf("%s visited before its directory", p)
+		}
+		got = append(got, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkParallel() error = %v", err)
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("WalkParallel() visited %v, want %v", got, want)
+	}
+	if b.maxInFlight < 2 || b.maxInFlight > 4 {
+		t.Fatalf("WalkParallel() listed %d directories at the same time, want 2 to 4", b.maxInFlight)
+	}
+
+	if err := s.WalkParallel("unknown", drivefs.WalkOptions{}, func(drivefs.Path, drivefs.FileInfo) error { return nil }); !errors.Is(err, drivefs.ErrNotFound) {
+		t.Fatalf("WalkParallel() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDriveFS_WalkParallel_Skip(t *testing.T) {
+	mem, rootID := newWalkTree(t)
+	s := drivefs.NewWithBackend(mem)
+
+	t.Run("skip dir", func(t *testing.T) {
+		var got []drivefs.Path
+		err := s.WalkParallel(rootID, drivefs.WalkOptions{}, func(p drivefs.Path, info drivefs.FileInfo) error {
+			got = append(got, p)
+			if info.Name == "sub" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkParallel() error = %v", err)
+		}
+		if len(got) != 25 || slices.Contains(got, "/dir0/sub/file.txt") {
+			t.Fatalf("WalkParallel() visited %v, want the contents of sub skipped", got)
+		}
+	})
+
+	t.Run("skip siblings", func(t *testing.T) {
+		dir0 := findRemote(t, s, rootID, "/dir0")
+		writeRemoteFile(t, s, dir0.ID, "x.txt", "x")
+		writeRemoteFile(t, s, dir0.ID, "y.txt", "y")
+		defer func() {
+			for _, name := range []drivefs.Path{"/dir0/x.txt", "/dir0/y.txt"} {
+				if err := s.Remove(findRemote(t, s, rootID, name).ID, false); err != nil {
+					t.Fatalf("Remove() error = %v", err)
+				}
+			}
+		}()
+		var got []drivefs.Path
+		err := s.WalkParallel(rootID, drivefs.WalkOptions{}, func(p drivefs.Path, _ drivefs.FileInfo) error {
+			got = append(got, p)
+			if p == "/dir0/file.txt" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkParallel() error = %v", err)
+		}
+		if slices.Contains(got, "/dir0/x.txt") || slices.Contains(got, "/dir0/y.txt") || !slices.Contains(got, "/dir0/sub/file.txt") {
+			t.Fatalf("WalkParallel() visited %v, want the files after /dir0/file.txt skipped", got)
+		}
+	})
+
+	t.Run("skip all", func(t *testing.T) {
+		var calls int
+		err := s.WalkParallel(rootID, drivefs.WalkOptions{}, func(p drivefs.Path, _ drivefs.FileInfo) error {
+			calls++
+			if p == "/dir3" {
+				return fs.SkipAll
+			}
+			return nil
+		})
+		if err != nil || calls != 5 {
+			t.Fatalf("WalkParallel() = %v after %d calls, want nil after 5 calls", err, calls)
+		}
+	})
+}
+
+func TestDriveFS_WalkParallel_Error(t *testing.T) {
+	mem, rootID := newWalkTree(t)
+	failing := findRemote(t, drivefs.NewWithBackend(mem), rootID, "/dir2/sub")
+	b := &slowListBackend{Backend: mem, failID: string(failing.ID)}
+	s := drivefs.NewWithBackend(b)
+
+	err := s.WalkParallel(rootID, drivefs.WalkOptions{Concurrency: 8}, func(drivefs.Path, drivefs.FileInfo) error { return nil })
+	if !errors.Is(err, drivefs.ErrDriveError) {
+		t.Fatalf("WalkParallel() error = %v, want ErrDriveError", err)
+	}
+
+	errStop := errors.New("stop")
+	err = s.WalkParallel(rootID, drivefs.WalkOptions{Concurrency: 8}, func(p drivefs.Path, _ drivefs.FileInfo) error {
+		if p == "/dir5" {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("WalkParallel() error = %v, want the error of f", err)
+	}
+	b.mu.Lock()
+	inFlight := b.inFlight
+	b.mu.Unlock()
+	if inFlight != 0 {
+		t.Fatalf("WalkParallel() returned with %d listings in progress", inFlight)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = s.WalkParallelContext(ctx, rootID, drivefs.WalkOptions{}, func(p drivefs.Path, _ drivefs.FileInfo) error {
+		if p == "/dir0" {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WalkParallelContext() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,133 @@
+package drivefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// WriteOptions configures streaming uploads performed by WriteFrom and OpenWriter.
+type WriteOptions struct {
+	// ChunkSize is the number of bytes sent in each request of the resumable upload.
+	// It is rounded up to a multiple of 256 KiB. Zero means 16 MiB.
+	ChunkSize int
+
+	// RetryDeadline bounds how long a chunk is retried after transient failures
+	// before the upload fails. Each retry resumes from the last offset committed by Google Drive.
+	// Zero means 32 seconds.
+	RetryDeadline time.Duration
+
+	// ContentType is the MIME type of the uploaded content.
+	// If empty, it is detected from the content.
+	ContentType string
+
+	// Progress, if not nil, is called with the total number of bytes committed so far
+	// each time a chunk has been uploaded.
+	Progress func(uploaded int64)
+}
+
+// WriteFrom writes the data read from r to the file with the given fileID until EOF,
+// overwriting any existing content.
+// The data is sent with the resumable-upload protocol in chunks of opts.ChunkSize bytes,
+// so only a single chunk is buffered in memory at a time.
+func (s *DriveFS) WriteFrom(fileID FileID, r io.Reader, opts WriteOptions) (err error) {
+	return s.WriteFromContext(context.Background(), fileID, r, opts)
+}
+
+// WriteFromContext is like WriteFrom but uses ctx for all Google Drive API calls.
+func (s *DriveFS) WriteFromContext(ctx context.Context, fileID FileID, r io.Reader, opts WriteOptions) (err error) {
+	return uploadFrom(ctx, s.backend, string(fileID), r, opts)
+}
+
+// FileWriter is a streaming handle that uploads everything written to it to a file in Google Drive.
+// It is returned by OpenWriter and must be closed to complete the upload.
+type FileWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	err  error
+}
+
+var _ io.WriteCloser = (*FileWriter)(nil)
+
+// OpenWriter opens the file with the given fileID for streaming writes.
+// The content of the file is replaced by the data written to the returned FileWriter
+// once it is closed successfully; the upload is performed as in WriteFrom.
+// Returns ErrNotFound if the file does not exist.
+func (s *DriveFS) OpenWriter(fileID FileID, opts WriteOptions) (w *FileWriter, err error) {
+	return s.OpenWriterContext(context.Background(), fileID, opts)
+}
+
+// OpenWriterContext is like OpenWriter but uses ctx for all Google Drive API calls made by the returned FileWriter.
+func (s *DriveFS) OpenWriterContext(ctx context.Context, fileID FileID, opts WriteOptions) (w *FileWriter, err error) {
+	_, found, err := findByID(ctx, s.backend, string(fileID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("file '%s' not found: %w", fileID, ErrNotFound)
+	}
+
+	pr, pw := io.Pipe()
+	w = &FileWriter{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		w.err = uploadFrom(ctx, s.backend, string(fileID), pr, opts)
+		// Unblock pending and later writes if the upload ended before all data was written.
+		_ = pr.CloseWithError(errors.Join(w.err, io.ErrClosedPipe))
+	}()
+	return w, nil
+}
+
+// Write writes p to the upload.
+// It blocks until the data has been consumed by the upload and fails if the upload has failed.
+func (w *FileWriter) Write(p []byte) (n int, err error) {
+	n, err = w.pw.Write(p)
+	if err != nil {
+		return n, newIOError("failed to write to upload", err)
+	}
+	return n, nil
+}
+
+// Close completes the upload and waits until it finishes.
+// It returns the error of the upload, if any.
+func (w *FileWriter) Close() error {
+	return w.CloseWithError(nil)
+}
+
+// CloseWithError aborts the upload with the given error so that the content of the file is left unchanged,
+// and waits until the upload finishes. If err is nil, it is equivalent to Close.
+func (w *FileWriter) CloseWithError(err error) error {
+	_ = w.pw.CloseWithError(err)
+	<-w.done
+	return w.err
+}
+
+func uploadFrom(ctx context.Context, b Backend, fileID string, r io.Reader, opts WriteOptions) (err error) {
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = googleapi.DefaultUploadChunkSize
+	}
+	mediaOptions := []googleapi.MediaOption{googleapi.ChunkSize(chunkSize)}
+	if opts.RetryDeadline > 0 {
+		mediaOptions = append(mediaOptions, googleapi.ChunkRetryDeadline(opts.RetryDeadline))
+	}
+	if opts.ContentType != "" {
+		mediaOptions = append(mediaOptions, googleapi.ContentType(opts.ContentType))
+	}
+	_, err = b.UpdateFile(ctx, UpdateFileRequest{
+		FileID:       fileID,
+		File:         &drive.File{},
+		Media:        r,
+		MediaOptions: mediaOptions,
+		Progress:     opts.Progress,
+	})
+	if err != nil {
+		return newDriveError("failed to upload file", err)
+	}
+	return nil
+}
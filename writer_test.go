@@ -0,0 +1,168 @@
+package drivefs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jumpaku/go-drivefs"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// resumableTestServer emulates the resumable-upload protocol of files.update.
+type resumableTestServer struct {
+	mu        sync.Mutex
+	content   map[string][]byte
+	sessions  map[string][]byte
+	failNext  int
+	failCount int
+}
+
+func (s *resumableTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/files/"):
+		id := strings.TrimPrefix(r.URL.Path, "/files/")
+		fmt.Fprintf(w, `{"id":%q,"name":%q,"mimeType":"text/plain"}`, id, id)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/upload/drive/v3/files/"):
+		if r.URL.Query().Get("uploadType") != "resumable" {
+			http.Error(w, "resumable upload expected", http.StatusBadRequest)
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/upload/drive/v3/files/")
+		s.sessions[id] = nil
+		w.Header().Set("Location", "http://"+r.Host+"/session/"+id)
+	case (r.Method == http.MethodPut || r.Method == http.MethodPost) && strings.HasPrefix(r.URL.Path, "/session/"):
+		id := strings.TrimPrefix(r.URL.Path, "/session/")
+		if s.failNext > 0 {
+			s.failNext--
+			s.failCount++
+			_, _ = io.Copy(io.Discard, r.Body)
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var start, end, total int64 = 0, -1, -1
+		var totalStr string
+		cr := r.Header.Get("Content-Range")
+		if _, err := fmt.Sscanf(cr, "bytes %d-%d/%s", &start, &end, &totalStr); err != nil {
+			if _, err := fmt.Sscanf(cr, "bytes */%s", &totalStr); err != nil {
+				http.Error(w, "bad range: "+cr, http.StatusBadRequest)
+				return
+			}
+		}
+		if totalStr != "*" {
+			_, _ = fmt.Sscan(totalStr, &total)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if end >= 0 {
+			if start != int64(len(s.sessions[id])) {
+				http.Error(w, "unexpected offset", http.StatusBadRequest)
+				return
+			}
+			s.sessions[id] = append(s.sessions[id], body...)
+		}
+		if total >= 0 && int64(len(s.sessions[id])) == total {
+			s.content[id] = s.sessions[id]
+			fmt.Fprintf(w, `{"id":%q}`, id)
+			return
+		}
+		if n := len(s.sessions[id]); n > 0 {
+			w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+		}
+		// The client asks for "X-GUploader-No-308: yes", so incomplete uploads are reported with 200.
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.String(), http.StatusNotFound)
+	}
+}
+
+func newResumableTestServer(t *testing.T) (*resumableTestServer, *drivefs.DriveFS) {
+	t.Helper()
+	ts := &resumableTestServer{content: map[string][]byte{}, sessions: map[string][]byte{}}
+	srv := httptest.NewServer(ts)
+	t.Cleanup(srv.Close)
+	service, err := drive.NewService(context.Background(),
+		option.WithEndpoint(srv.URL+"/"),
+		option.WithHTTPClient(srv.Client()),
+	)
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	return ts, drivefs.New(service)
+}
+
+func TestDriveFS_WriteFrom(t *testing.T) {
+	ts, s := newResumableTestServer(t)
+	ts.failNext = 1
+	data := bytes.Repeat([]byte("0123456789"), 60*1024)
+
+	var progress []int64
+	err := s.WriteFrom("file", bytes.NewReader(data), drivefs.WriteOptions{
+		ChunkSize: 256 * 1024,
+		Progress:  func(uploaded int64) { progress = append(progress, uploaded) },
+	})
+	if err != nil {
+		t.Fatalf("WriteFrom() error = %v", err)
+	}
+	if !bytes.Equal(ts.content["file"], data) {
+		t.Fatalf("uploaded %d bytes, want %d bytes", len(ts.content["file"]), len(data))
+	}
+	if ts.failCount != 1 {
+		t.Fatalf("failed requests = %d, want 1", ts.failCount)
+	}
+	if len(progress) == 0 || progress[len(progress)-1] != int64(len(data)) {
+		t.Fatalf("progress = %v, want last value %d", progress, len(data))
+	}
+}
+
+func TestDriveFS_OpenWriter(t *testing.T) {
+	data := bytes.Repeat([]byte("abcdefghij"), 60*1024)
+
+	t.Run("Close", func(t *testing.T) {
+		ts, s := newResumableTestServer(t)
+		w, err := s.OpenWriter("file", drivefs.WriteOptions{ChunkSize: 256 * 1024})
+		if err != nil {
+			t.Fatalf("OpenWriter() error = %v", err)
+		}
+		for chunk := range slices.Chunk(data, 1000) {
+			if _, err := w.Write(chunk); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if !bytes.Equal(ts.content["file"], data) {
+			t.Fatalf("uploaded %d bytes, want %d bytes", len(ts.content["file"]), len(data))
+		}
+	})
+
+	t.Run("CloseWithError", func(t *testing.T) {
+		ts, s := newResumableTestServer(t)
+		w, err := s.OpenWriter("file", drivefs.WriteOptions{ChunkSize: 256 * 1024})
+		if err != nil {
+			t.Fatalf("OpenWriter() error = %v", err)
+		}
+		if _, err := w.Write(data[:300*1024]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		abort := errors.New("abort")
+		if err := w.CloseWithError(abort); !errors.Is(err, abort) {
+			t.Fatalf("CloseWithError() error = %v, want %v", err, abort)
+		}
+		if _, ok := ts.content["file"]; ok {
+			t.Fatalf("aborted upload was committed")
+		}
+	})
+}